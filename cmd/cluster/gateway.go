@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"sync/atomic"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"test-jaeger/internal/httpclient"
+	"test-jaeger/internal/telemetry"
+)
+
+const instrumentationName = "test-jaeger/cmd/cluster"
+
+// upstreamInstanceKey names the replica a request was balanced to.
+const upstreamInstanceKey = attribute.Key("gateway.upstream.instance")
+
+type upstream struct {
+	instance string
+	url      *url.URL
+}
+
+// upstreamKey holds the upstream chosen for a request in its context, for
+// the rewrite of the proxy.
+type upstreamKey struct{}
+
+func withUpstream(ctx context.Context, up upstream) context.Context {
+	return context.WithValue(ctx, upstreamKey{}, up)
+}
+
+func upstreamFrom(r *http.Request) upstream {
+	return r.Context().Value(upstreamKey{}).(upstream)
+}
+
+// gateway sends each request to the next upstream in turn. Requests are
+// traced as SERVER spans continuing the caller's trace, and forwarded with
+// the CLIENT spans of httpclient, so a trace shows the replica that served
+// it.
+type gateway struct {
+	upstreams []upstream
+	next      atomic.Uint64
+	proxy     *httputil.ReverseProxy
+	tracer    trace.Tracer
+	requests  metric.Int64Counter
+}
+
+func newGateway(upstreams []upstream) *gateway {
+	g := &gateway{
+		upstreams: upstreams,
+		tracer:    otel.Tracer(instrumentationName),
+	}
+	g.proxy = &httputil.ReverseProxy{
+		Rewrite: func(r *httputil.ProxyRequest) {
+			r.SetURL(upstreamFrom(r.In).url)
+			r.SetXForwarded()
+		},
+		Transport: httpclient.New().Transport,
+	}
+	var err error
+	if g.requests, err = otel.Meter(instrumentationName).Int64Counter("gateway.requests",
+		metric.WithDescription("Number of requests balanced to each replica")); err != nil {
+		log.Printf("failed to create gateway.requests counter: %v", err)
+	}
+	return g
+}
+
+func (g *gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	up := g.upstreams[(g.next.Add(1)-1)%uint64(len(g.upstreams))]
+
+	ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+	ctx, span := g.tracer.Start(ctx, telemetry.ServerSpanName(r.Method, ""),
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			semconv.HTTPRequestMethodKey.String(r.Method),
+			semconv.URLPath(r.URL.Path),
+			upstreamInstanceKey.String(up.instance),
+		))
+	defer span.End()
+	if r.URL.RawQuery != "" {
+		span.SetAttributes(semconv.URLQuery(r.URL.RawQuery))
+	}
+	if g.requests != nil {
+		g.requests.Add(ctx, 1, metric.WithAttributes(upstreamInstanceKey.String(up.instance)))
+	}
+
+	rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+	g.proxy.ServeHTTP(rec, r.WithContext(withUpstream(ctx, up)))
+	span.SetAttributes(semconv.HTTPResponseStatusCodeKey.Int(rec.status))
+	telemetry.SetStatus(span, rec.status, nil)
+}
+
+// statusRecorder keeps the status code written by the proxy.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// Unwrap lets the proxy flush streamed responses.
+func (r *statusRecorder) Unwrap() http.ResponseWriter { return r.ResponseWriter }
@@ -0,0 +1,109 @@
+// Command cluster runs several replicas of a service behind a round-robin
+// gateway, so load balancing shows in the traces and metrics: each replica
+// listens on its own port and reports its own service.instance.id, and the
+// gateway traces every request with the replica it was sent to, e.g.
+//
+//	go build -o /tmp/svcb ./golang2
+//	go run ./cmd/cluster -n 3 -listen :5001 -- /tmp/svcb -config config.yaml
+//
+// The service is started with -port added to its arguments, so it must accept
+// the flags of internal/config.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"syscall"
+
+	"test-jaeger/internal/telemetry"
+)
+
+func main() {
+	replicas := flag.Int("n", 3, "number of replicas")
+	basePort := flag.Int("base-port", 5101, "port of the first replica, the next ones use the following ports")
+	listen := flag.String("listen", ":5001", "address of the gateway")
+	collector := flag.String("collector", telemetry.DefaultEndpoint, "OTLP endpoint the gateway spans are exported to")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s [flags] -- <service binary> [service flags]\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 || *replicas < 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	provider, err := telemetry.NewTracerProvider(ctx, telemetry.Config{
+		ServiceName: "Gateway",
+		Exporter:    telemetry.Exporter{Endpoint: *collector},
+	})
+	if err != nil {
+		log.Fatalf("failed to initialize tracing: %v", err)
+	}
+	defer telemetry.Shutdown(provider)
+
+	var wg sync.WaitGroup
+	var upstreams []upstream
+	name := filepath.Base(flag.Arg(0))
+	for i := 0; i < *replicas; i++ {
+		port := *basePort + i
+		instance := fmt.Sprintf("%s-%d", name, i+1)
+		cmd := replica(ctx, flag.Args(), port, instance)
+		if err := cmd.Start(); err != nil {
+			log.Fatalf("failed to start %s: %v", instance, err)
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := cmd.Wait(); err != nil && ctx.Err() == nil {
+				log.Printf("%s exited: %v", instance, err)
+			}
+		}()
+		upstreams = append(upstreams, upstream{
+			instance: instance,
+			url:      &url.URL{Scheme: "http", Host: "localhost:" + strconv.Itoa(port)},
+		})
+	}
+
+	srv := &http.Server{Addr: *listen, Handler: newGateway(upstreams)}
+	go func() {
+		<-ctx.Done()
+		srv.Shutdown(context.Background())
+	}()
+	log.Printf("gateway listening on %s, balancing %d replicas from port %d", *listen, *replicas, *basePort)
+	if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		log.Printf("gateway stopped: %v", err)
+		stop()
+	}
+	wg.Wait()
+}
+
+// replica prepares the command of one replica: the service listens on port
+// and reports instance as its service.instance.id, on top of the resource
+// attributes already in the environment. It is interrupted when ctx is done.
+func replica(ctx context.Context, args []string, port int, instance string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, args[0], append(args[1:], "-port", strconv.Itoa(port))...)
+	cmd.Cancel = func() error { return cmd.Process.Signal(os.Interrupt) }
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	attrs := "service.instance.id=" + instance
+	if v := os.Getenv("OTEL_RESOURCE_ATTRIBUTES"); v != "" {
+		attrs = v + "," + attrs
+	}
+	cmd.Env = append(os.Environ(), "OTEL_RESOURCE_ATTRIBUTES="+attrs)
+	return cmd
+}
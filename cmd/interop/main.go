@@ -0,0 +1,78 @@
+// Command interop calls an interop peer (see internal/interop) with a W3C
+// traceparent and verifies that the peer continued the trace. It exits
+// non-zero when the propagated context does not line up.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	"test-jaeger/internal/httpclient"
+	"test-jaeger/internal/interop"
+	"test-jaeger/internal/telemetry"
+)
+
+func main() {
+	endpoint := flag.String("endpoint", "http://localhost:5001"+interop.Path, "URL of the interop peer")
+	collector := flag.String("collector", telemetry.DefaultEndpoint, "OTLP endpoint the client span is exported to")
+	flag.Parse()
+
+	provider, err := telemetry.NewTracerProvider(context.Background(), telemetry.Config{
+		ServiceName: "InteropClient",
+		Exporter:    telemetry.Exporter{Endpoint: *collector},
+	})
+	if err != nil {
+		log.Fatalf("failed to initialize tracing: %v", err)
+	}
+
+	err = run(*endpoint)
+	telemetry.Shutdown(provider)
+	if err != nil {
+		log.Printf("interop check failed: %v", err)
+		os.Exit(1)
+	}
+}
+
+func run(endpoint string) error {
+	ctx, span := otel.Tracer("interop").Start(context.Background(), "InteropClient",
+		trace.WithSpanKind(trace.SpanKindClient))
+	defer span.End()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	resp, err := httpclient.NewUntraced().Do(req)
+	if err != nil {
+		span.RecordError(err)
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("peer returned %s", resp.Status)
+	}
+
+	var body interop.Response
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return fmt.Errorf("decode peer response: %w", err)
+	}
+	if err := interop.Verify(span.SpanContext(), body); err != nil {
+		span.RecordError(err)
+		return err
+	}
+
+	fmt.Printf("trace %s continued by %s (%s sdk) as span %s\n",
+		body.TraceID, body.Service, body.SDK, body.SpanID)
+	return nil
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/trace"
+
+	"test-jaeger/internal/collectortest"
+	"test-jaeger/internal/interop"
+	"test-jaeger/internal/telemetry"
+)
+
+func TestRun(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		name string
+		// strip drops the trace context before the peer sees it
+		strip   bool
+		wantErr bool
+	}{
+		{"propagated", false, false},
+		{"context lost", true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			collector := collectortest.Start(t)
+			provider, err := telemetry.NewTracerProvider(context.Background(), telemetry.Config{
+				ServiceName: "InteropClient",
+				Exporter:    telemetry.Exporter{Endpoint: collector.Endpoint},
+				SyncExport:  true,
+			})
+			if err != nil {
+				t.Fatalf("NewTracerProvider: %v", err)
+			}
+			defer telemetry.Shutdown(provider)
+
+			router := gin.New()
+			if tt.strip {
+				router.Use(func(c *gin.Context) { c.Request.Header.Del("traceparent") })
+			}
+			router.Use(telemetry.ExtractContext(), telemetry.TraceRequests())
+			router.GET(interop.Path, interop.Handler("ServiceB"))
+			peer := httptest.NewServer(router)
+			defer peer.Close()
+
+			err = run(peer.URL + interop.Path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("run = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestRunExportsTrace checks that the client span of run and the span of the
+// peer reach the collector as one well formed trace.
+func TestRunExportsTrace(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	collector := collectortest.Start(t)
+	provider, err := telemetry.NewTracerProvider(context.Background(), telemetry.Config{
+		ServiceName: "InteropClient",
+		Exporter:    telemetry.Exporter{Endpoint: collector.Endpoint},
+		SyncExport:  true,
+	})
+	if err != nil {
+		t.Fatalf("NewTracerProvider: %v", err)
+	}
+
+	var received http.Header
+	router := gin.New()
+	router.Use(func(c *gin.Context) { received = c.Request.Header.Clone() })
+	router.Use(telemetry.ExtractContext(), telemetry.TraceRequests())
+	router.GET(interop.Path, interop.Handler("ServiceB"))
+	peer := httptest.NewServer(router)
+	defer peer.Close()
+
+	if err := run(peer.URL + interop.Path); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	telemetry.Shutdown(provider)
+
+	parts := strings.Split(received.Get("traceparent"), "-")
+	if len(parts) != 4 {
+		t.Fatalf("peer received traceparent %q", received.Get("traceparent"))
+	}
+	traceID, err := trace.TraceIDFromHex(parts[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	spans := collector.Trace(traceID)
+	if len(spans) != 2 {
+		t.Fatalf("collector received %d spans of the trace, want the client and the peer span", len(spans))
+	}
+	if err := collectortest.WellFormed(spans); err != nil {
+		t.Fatalf("trace is not well formed: %v", err)
+	}
+}
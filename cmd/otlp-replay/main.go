@@ -0,0 +1,160 @@
+//go:build !notelemetry && !wasm
+
+// Command otlp-replay resends the spans written by the file backend (see
+// telemetry.FileConfig) to an OTLP gRPC endpoint, e.g. to backfill a backend
+// with traces captured in an air-gapped environment or to reproduce an issue.
+// With -shift-time, the timestamps are moved so the latest span ends now,
+// keeping the spans' relative timing, for backends that drop old data.
+//
+//	go run ./cmd/otlp-replay -endpoint http://localhost:4317 traces*.jsonl
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"time"
+
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+
+	"test-jaeger/internal/telemetry"
+)
+
+func main() {
+	endpoint := flag.String("endpoint", telemetry.DefaultEndpoint, "OTLP gRPC endpoint URL the spans are sent to")
+	shift := flag.Bool("shift-time", false, "move the timestamps so the latest span ends now")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s [flags] file...\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	var offset time.Duration
+	if *shift {
+		var latest uint64
+		err := eachRequest(flag.Args(), func(req *coltracepb.ExportTraceServiceRequest) error {
+			latest = max(latest, latestEnd(req))
+			return nil
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+		if latest > 0 {
+			offset = time.Since(time.Unix(0, int64(latest)))
+		}
+	}
+
+	ctx := context.Background()
+	client := otlptracegrpc.NewClient(otlptracegrpc.WithEndpointURL(*endpoint))
+	if err := client.Start(ctx); err != nil {
+		log.Fatalf("failed to connect to %s: %v", *endpoint, err)
+	}
+	var requests, spans int
+	err := eachRequest(flag.Args(), func(req *coltracepb.ExportTraceServiceRequest) error {
+		if offset != 0 {
+			shiftTimes(req, offset)
+		}
+		if err := client.UploadTraces(ctx, req.ResourceSpans); err != nil {
+			return err
+		}
+		requests++
+		spans += countSpans(req)
+		return nil
+	})
+	if stopErr := client.Stop(ctx); err == nil {
+		err = stopErr
+	}
+	fmt.Printf("%d spans in %d requests sent to %s\n", spans, requests, *endpoint)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// eachRequest calls fn with every request of the files, in order.
+func eachRequest(files []string, fn func(*coltracepb.ExportTraceServiceRequest) error) error {
+	for _, name := range files {
+		if err := readFile(name, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func readFile(name string, fn func(*coltracepb.ExportTraceServiceRequest) error) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	// Lines hold whole batches, too long for a bufio.Scanner
+	r := bufio.NewReader(f)
+	for n := 1; ; n++ {
+		line, err := r.ReadBytes('\n')
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			req, uerr := telemetry.UnmarshalOTLPJSON(line)
+			if uerr != nil {
+				return fmt.Errorf("%s:%d: %w", name, n, uerr)
+			}
+			if ferr := fn(req); ferr != nil {
+				return fmt.Errorf("%s:%d: %w", name, n, ferr)
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func latestEnd(req *coltracepb.ExportTraceServiceRequest) uint64 {
+	var latest uint64
+	for _, rs := range req.ResourceSpans {
+		for _, ss := range rs.ScopeSpans {
+			for _, s := range ss.Spans {
+				latest = max(latest, s.EndTimeUnixNano)
+			}
+		}
+	}
+	return latest
+}
+
+// shiftTimes moves the timestamps of the spans and their events forward by
+// offset.
+func shiftTimes(req *coltracepb.ExportTraceServiceRequest, offset time.Duration) {
+	d := uint64(offset)
+	for _, rs := range req.ResourceSpans {
+		for _, ss := range rs.ScopeSpans {
+			for _, s := range ss.Spans {
+				s.StartTimeUnixNano += d
+				s.EndTimeUnixNano += d
+				for _, e := range s.Events {
+					e.TimeUnixNano += d
+				}
+			}
+		}
+	}
+}
+
+func countSpans(req *coltracepb.ExportTraceServiceRequest) int {
+	var n int
+	for _, rs := range req.ResourceSpans {
+		for _, ss := range rs.ScopeSpans {
+			n += len(ss.Spans)
+		}
+	}
+	return n
+}
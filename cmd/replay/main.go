@@ -0,0 +1,106 @@
+// Command replay re-issues the requests recorded by a service (see the record
+// setting and internal/replay) against another build of it, and compares the
+// status codes and latencies. Each replayed request is traced with the
+// original trace ID as replay.original_trace_id, so both traces can be put
+// side by side. It exits non-zero when a status code differs.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"test-jaeger/internal/httpclient"
+	"test-jaeger/internal/replay"
+	"test-jaeger/internal/telemetry"
+)
+
+func main() {
+	file := flag.String("file", "", "recording to replay")
+	target := flag.String("target", "http://localhost:5001", "base URL of the service build to replay against")
+	collector := flag.String("collector", telemetry.DefaultEndpoint, "OTLP endpoint the replay spans are exported to")
+	flag.Parse()
+	if *file == "" {
+		log.Fatal("-file is required")
+	}
+
+	exchanges, err := replay.ReadFile(*file)
+	if err != nil {
+		log.Fatalf("failed to read recording: %v", err)
+	}
+
+	provider, err := telemetry.NewTracerProvider(context.Background(), telemetry.Config{
+		ServiceName: "Replay",
+		Exporter:    telemetry.Exporter{Endpoint: *collector},
+	})
+	if err != nil {
+		log.Fatalf("failed to initialize tracing: %v", err)
+	}
+
+	mismatches := run(exchanges, *target)
+	telemetry.Shutdown(provider)
+	if mismatches > 0 {
+		os.Exit(1)
+	}
+}
+
+// run replays the exchanges in order and returns how many got a different
+// status code, failed requests included.
+func run(exchanges []replay.Exchange, target string) int {
+	client := httpclient.New()
+	tracer := otel.Tracer("replay")
+
+	var mismatches int
+	var recorded, replayed float64
+	for _, ex := range exchanges {
+		ctx, span := tracer.Start(context.Background(), "Replay "+ex.Method,
+			trace.WithAttributes(attribute.String("replay.original_trace_id", ex.TraceID)))
+		start := time.Now()
+		status, err := send(ctx, client, ex, target)
+		elapsed := float64(time.Since(start)) / float64(time.Millisecond)
+		telemetry.SetStatus(span, 0, err)
+		span.End()
+
+		recorded += ex.DurationMs
+		replayed += elapsed
+		switch {
+		case err != nil:
+			mismatches++
+			fmt.Printf("FAIL %s %s: %v\n", ex.Method, ex.URL, err)
+		case status != ex.Status:
+			mismatches++
+			fmt.Printf("DIFF %s %s: status %d, recorded %d\n", ex.Method, ex.URL, status, ex.Status)
+		default:
+			fmt.Printf("OK   %s %s: %.1fms, recorded %.1fms\n", ex.Method, ex.URL, elapsed, ex.DurationMs)
+		}
+	}
+
+	if n := float64(len(exchanges)); n > 0 {
+		fmt.Printf("%d requests, %d mismatches, mean latency %.1fms, recorded %.1fms\n",
+			len(exchanges), mismatches, replayed/n, recorded/n)
+	}
+	return mismatches
+}
+
+func send(ctx context.Context, client *http.Client, ex replay.Exchange, target string) (int, error) {
+	req, err := ex.Request(ctx, target)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,63 @@
+// Command seed fills the store of the demo services with fake users, so load
+// tests and search demos have data to work on. The job is traced, see
+// store.Seed, and exported like a service would:
+//
+//	go run ./cmd/seed -store postgres://localhost/demo -users 5000 -seed 42
+//
+// The memory store lives in the service, seed it with POST /admin/seed on
+// the admin address of ServiceB instead, see config.Config.AdminListen.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+
+	"test-jaeger/internal/store"
+	"test-jaeger/internal/telemetry"
+	"test-jaeger/internal/telemetry/metrics"
+)
+
+func main() {
+	dsn := flag.String("store", "", "DSN of the store to seed, e.g. postgres://localhost/demo")
+	users := flag.Int("users", 1000, "number of users to create")
+	seed := flag.Uint64("seed", 1, "seed of the generated data, the same seed gives the same users")
+	collector := flag.String("collector", telemetry.DefaultEndpoint, "OTLP endpoint the seeding telemetry is exported to")
+	flag.Parse()
+	if u, err := url.Parse(*dsn); err != nil || u.Scheme == "" || u.Scheme == "memory" {
+		log.Fatal("-store is required and cannot be the memory store, use POST /admin/seed on the admin address of the service")
+	}
+
+	ctx := context.Background()
+	tcfg := telemetry.Config{
+		ServiceName: "Seed",
+		Exporter:    telemetry.Exporter{Endpoint: *collector},
+	}
+	tracer, err := telemetry.NewTracerProvider(ctx, tcfg)
+	if err != nil {
+		log.Fatalf("failed to initialize tracing: %v", err)
+	}
+	meters, err := metrics.NewMeterProvider(ctx, tcfg)
+	if err != nil {
+		log.Fatalf("failed to initialize metrics: %v", err)
+	}
+
+	s, err := store.Open(ctx, *dsn)
+	if err != nil {
+		log.Fatal(err)
+	}
+	created, err := store.Seed(ctx, s, store.SeedOptions{Users: *users, Seed: *seed})
+	fmt.Printf("created %d users\n", created)
+	if cerr := s.Close(ctx); cerr != nil {
+		log.Printf("failed to close store: %v", cerr)
+	}
+	metrics.Shutdown(meters)
+	telemetry.Shutdown(tracer)
+	if err != nil {
+		log.Print(err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,276 @@
+//go:build !notelemetry && !wasm
+
+// Command smoketest runs the canonical user flow against the stack and checks
+// that it left a complete trace, for local runs and deployment gates alike. It
+// calls ServiceA, which calls ServiceB, under a client span of its own, checks
+// the response, then looks the trace up in Jaeger, or in the file of the file
+// backend, until every expected service reported its spans, all linked to the
+// client span. It exits non-zero on the first mismatch.
+//
+// Against a running stack:
+//
+//	go run ./cmd/smoketest -url http://localhost:5000/hello -jaeger http://localhost:16686
+//
+// Starting the services itself, without a collector:
+//
+//	go build -o /tmp/svca ./golang && go build -o /tmp/svcb ./golang2
+//	go run ./cmd/smoketest -a /tmp/svca -b /tmp/svcb -file /tmp/smoke.jsonl
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	"test-jaeger/internal/httpclient"
+	"test-jaeger/internal/telemetry"
+)
+
+// client makes the calls of the smoke test, which traces and propagates its
+// own user flow, and polls the services and Jaeger.
+var client = httpclient.NewUntraced()
+
+func main() {
+	serviceA := flag.String("a", "", "ServiceA binary to start, empty to use a running one")
+	serviceB := flag.String("b", "", "ServiceB binary to start, empty to use a running one")
+	target := flag.String("url", "http://localhost:5000/hello", "URL of the user flow on ServiceA")
+	jaeger := flag.String("jaeger", "http://localhost:16686", "base URL of the Jaeger query API the trace is looked up in")
+	file := flag.String("file", "", "OTLP JSON file of the file backend to look the trace up in instead of Jaeger")
+	collector := flag.String("collector", telemetry.DefaultEndpoint, "OTLP endpoint the spans are exported to")
+	services := flag.String("services", "ServiceA,ServiceB", "comma separated services the trace must hold spans of")
+	timeout := flag.Duration("timeout", 30*time.Second, "how long to wait for the services and for the trace")
+	flag.Parse()
+
+	s := &smoketest{
+		target:   *target,
+		timeout:  *timeout,
+		services: strings.Split(*services, ","),
+		exporter: telemetry.Exporter{Endpoint: *collector},
+		lookup:   func(traceID string) ([]span, error) { return jaegerSpans(*jaeger, traceID) },
+	}
+	if *file != "" {
+		path, err := filepath.Abs(*file)
+		if err != nil {
+			log.Fatalf("invalid file %q: %v", *file, err)
+		}
+		s.file = path
+		s.exporter = telemetry.Exporter{Backend: telemetry.File, File: telemetry.FileConfig{Path: path}}
+		s.lookup = func(traceID string) ([]span, error) { return fileSpans(path, traceID) }
+	}
+
+	if err := s.run(*serviceA, *serviceB, *collector); err != nil {
+		log.Printf("smoke test failed: %v", err)
+		os.Exit(1)
+	}
+}
+
+type smoketest struct {
+	target   string
+	timeout  time.Duration
+	services []string
+	exporter telemetry.Exporter
+	file     string
+	lookup   func(traceID string) ([]span, error)
+}
+
+func (s *smoketest) run(binaryA, binaryB, collector string) error {
+	provider, err := telemetry.NewTracerProvider(context.Background(), telemetry.Config{
+		ServiceName: "SmokeTest",
+		Exporter:    s.exporter,
+	})
+	if err != nil {
+		return fmt.Errorf("initialize tracing: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	stop := func() {
+		cancel()
+		wg.Wait()
+	}
+	defer stop()
+	args, err := s.serviceArgs(collector)
+	if err != nil {
+		telemetry.Shutdown(provider)
+		return err
+	}
+	// ServiceB first, ServiceA calls it
+	for _, bin := range []string{binaryB, binaryA} {
+		if bin == "" {
+			continue
+		}
+		if err := start(ctx, &wg, bin, args); err != nil {
+			telemetry.Shutdown(provider)
+			return err
+		}
+	}
+	if err := waitReady(s.target, s.timeout); err != nil {
+		telemetry.Shutdown(provider)
+		return err
+	}
+
+	traceID, err := s.userFlow()
+	// The client span and the spans of the started services are only all
+	// exported once they are shut down.
+	telemetry.Shutdown(provider)
+	stop()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("user flow ok, trace %s\n", traceID)
+	return s.verify(traceID)
+}
+
+// serviceArgs returns the flags of the services started by the smoke test:
+// they export to the collector, or to the file, along with the smoke test.
+func (s *smoketest) serviceArgs(collector string) ([]string, error) {
+	if s.file == "" {
+		return []string{"-otlp-endpoint", collector}, nil
+	}
+	// Every field of a config file is optional, the services keep their own
+	// defaults for the rest.
+	cfg := filepath.Join(filepath.Dir(s.file), "smoketest.yaml")
+	data := fmt.Sprintf("exporter:\n  type: file\n  file:\n    path: %q\n", s.file)
+	return []string{"-config", cfg}, os.WriteFile(cfg, []byte(data), 0o600)
+}
+
+// start runs a service until ctx is done, then interrupts it so it flushes
+// its telemetry.
+func start(ctx context.Context, wg *sync.WaitGroup, bin string, args []string) error {
+	cmd := exec.CommandContext(ctx, bin, args...)
+	cmd.Cancel = func() error { return cmd.Process.Signal(os.Interrupt) }
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("start %s: %w", bin, err)
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if err := cmd.Wait(); err != nil && ctx.Err() == nil {
+			log.Printf("%s exited: %v", bin, err)
+		}
+	}()
+	return nil
+}
+
+// waitReady polls url until it answers 200 OK.
+func waitReady(url string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		resp, err := client.Get(url)
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode == http.StatusOK {
+				return nil
+			}
+			err = fmt.Errorf("%s returned %s", url, resp.Status)
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("stack not ready after %s: %w", timeout, err)
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+}
+
+// userFlow calls the target under a client span and checks the response.
+func (s *smoketest) userFlow() (string, error) {
+	ctx, span := otel.Tracer("smoketest").Start(context.Background(), "smoketest",
+		trace.WithSpanKind(trace.SpanKindClient))
+	defer span.End()
+	traceID := span.SpanContext().TraceID().String()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.target, nil)
+	if err != nil {
+		return traceID, err
+	}
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+	resp, err := client.Do(req)
+	if err != nil {
+		span.RecordError(err)
+		return traceID, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return traceID, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return traceID, fmt.Errorf("%s returned %s: %s", s.target, resp.Status, body)
+	}
+	if len(body) == 0 {
+		return traceID, errors.New(s.target + " returned an empty body")
+	}
+	return traceID, nil
+}
+
+// verify waits for the trace to hold spans of every expected service, all
+// descending from the client span.
+func (s *smoketest) verify(traceID string) error {
+	deadline := time.Now().Add(s.timeout)
+	for {
+		spans, err := s.lookup(traceID)
+		if err == nil {
+			err = check(spans, s.services)
+		}
+		if err == nil {
+			fmt.Printf("trace %s complete: %d spans from %s\n", traceID, len(spans), strings.Join(s.services, ", "))
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("trace %s: %w", traceID, err)
+		}
+		time.Sleep(time.Second)
+	}
+}
+
+// span is what the check needs to know of a span of the trace.
+type span struct {
+	service string
+	id      string
+	parent  string
+}
+
+// check reports the expected services missing from spans, and the spans
+// whose parent is not in the trace, the client span aside.
+func check(spans []span, services []string) error {
+	ids := make(map[string]bool, len(spans))
+	seen := make(map[string]bool)
+	for _, s := range spans {
+		ids[s.id] = true
+		seen[s.service] = true
+	}
+	var errs []error
+	for _, name := range services {
+		if !seen[name] {
+			errs = append(errs, fmt.Errorf("no span of %s", name))
+		}
+	}
+	var roots []string
+	for _, s := range spans {
+		if s.parent == "" {
+			roots = append(roots, s.service)
+		} else if !ids[s.parent] {
+			errs = append(errs, fmt.Errorf("span %s of %s has parent %s outside the trace", s.id, s.service, s.parent))
+		}
+	}
+	if len(roots) != 1 || !slices.Contains(roots, "SmokeTest") {
+		errs = append(errs, fmt.Errorf("want the SmokeTest client span as the only root, got %v", roots))
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,121 @@
+//go:build !notelemetry && !wasm
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"test-jaeger/internal/telemetry"
+)
+
+// jaegerResponse is the body of the Jaeger query API /api/traces/{id}.
+type jaegerResponse struct {
+	Data []struct {
+		Spans []struct {
+			SpanID     string `json:"spanID"`
+			ProcessID  string `json:"processID"`
+			References []struct {
+				RefType string `json:"refType"`
+				SpanID  string `json:"spanID"`
+			} `json:"references"`
+		} `json:"spans"`
+		Processes map[string]struct {
+			ServiceName string `json:"serviceName"`
+		} `json:"processes"`
+	} `json:"data"`
+}
+
+// jaegerSpans reads the spans of a trace from the Jaeger query API at base,
+// e.g. http://localhost:16686.
+func jaegerSpans(base, traceID string) ([]span, error) {
+	resp, err := client.Get(strings.TrimSuffix(base, "/") + "/api/traces/" + traceID)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("jaeger returned %s: %s", resp.Status, body)
+	}
+	var body jaegerResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("decode trace: %w", err)
+	}
+	var spans []span
+	for _, t := range body.Data {
+		for _, s := range t.Spans {
+			sp := span{service: t.Processes[s.ProcessID].ServiceName, id: s.SpanID}
+			for _, ref := range s.References {
+				if ref.RefType == "CHILD_OF" {
+					sp.parent = ref.SpanID
+				}
+			}
+			spans = append(spans, sp)
+		}
+	}
+	if len(spans) == 0 {
+		return nil, errors.New("trace not found")
+	}
+	return spans, nil
+}
+
+// fileSpans reads the spans of a trace from the OTLP JSON file of the file
+// backend.
+func fileSpans(path, traceID string) ([]span, error) {
+	id, err := hex.DecodeString(traceID)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var spans []span
+	// Lines hold whole batches, too long for a bufio.Scanner
+	r := bufio.NewReader(f)
+	for {
+		line, err := r.ReadBytes('\n')
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			req, uerr := telemetry.UnmarshalOTLPJSON(line)
+			if uerr != nil {
+				return nil, fmt.Errorf("%s: %w", path, uerr)
+			}
+			for _, rs := range req.ResourceSpans {
+				var service string
+				for _, kv := range rs.GetResource().GetAttributes() {
+					if kv.Key == "service.name" {
+						service = kv.GetValue().GetStringValue()
+					}
+				}
+				for _, ss := range rs.ScopeSpans {
+					for _, s := range ss.Spans {
+						if bytes.Equal(s.TraceId, id) {
+							spans = append(spans, span{service: service, id: hex.EncodeToString(s.SpanId), parent: hex.EncodeToString(s.ParentSpanId)})
+						}
+					}
+				}
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(spans) == 0 {
+		return nil, errors.New("trace not found")
+	}
+	return spans, nil
+}
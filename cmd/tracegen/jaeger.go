@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+
+	"test-jaeger/internal/httpclient"
+)
+
+// jaegerResponse is the body of the Jaeger query API /api/traces/{id}, also
+// what the Jaeger UI downloads as trace JSON.
+type jaegerResponse struct {
+	Data []jaegerTrace `json:"data"`
+}
+
+type jaegerTrace struct {
+	TraceID   string                   `json:"traceID"`
+	Spans     []jaegerSpan             `json:"spans"`
+	Processes map[string]jaegerProcess `json:"processes"`
+}
+
+type jaegerSpan struct {
+	SpanID        string            `json:"spanID"`
+	OperationName string            `json:"operationName"`
+	References    []jaegerReference `json:"references"`
+	ProcessID     string            `json:"processID"`
+}
+
+type jaegerReference struct {
+	RefType string `json:"refType"`
+	SpanID  string `json:"spanID"`
+}
+
+type jaegerProcess struct {
+	ServiceName string `json:"serviceName"`
+}
+
+// fetchTrace reads a trace from the Jaeger query API at base, e.g.
+// http://localhost:16686.
+func fetchTrace(base, traceID string) (jaegerTrace, error) {
+	resp, err := httpclient.NewUntraced().Get(strings.TrimSuffix(base, "/") + "/api/traces/" + traceID)
+	if err != nil {
+		return jaegerTrace{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return jaegerTrace{}, fmt.Errorf("jaeger returned %s: %s", resp.Status, body)
+	}
+	return decodeTrace(resp.Body)
+}
+
+// readTrace reads a trace JSON file downloaded from the Jaeger UI.
+func readTrace(path string) (jaegerTrace, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return jaegerTrace{}, err
+	}
+	defer f.Close()
+	return decodeTrace(f)
+}
+
+func decodeTrace(r io.Reader) (jaegerTrace, error) {
+	var body jaegerResponse
+	if err := json.NewDecoder(r).Decode(&body); err != nil {
+		return jaegerTrace{}, fmt.Errorf("decode trace: %w", err)
+	}
+	if len(body.Data) == 0 {
+		return jaegerTrace{}, fmt.Errorf("trace not found")
+	}
+	return body.Data[0], nil
+}
+
+// spanPaths describes the structure of the trace as one path per span, from
+// its topmost ancestor in service down to it, e.g. "GET /hello > GET localhost:5001",
+// sorted. Spans of other services are left out; when service is empty every
+// span is kept.
+func spanPaths(t jaegerTrace, service string) []string {
+	byID := make(map[string]jaegerSpan, len(t.Spans))
+	for _, s := range t.Spans {
+		byID[s.SpanID] = s
+	}
+	keep := func(s jaegerSpan) bool {
+		return service == "" || t.Processes[s.ProcessID].ServiceName == service
+	}
+
+	var paths []string
+	for _, s := range t.Spans {
+		if !keep(s) {
+			continue
+		}
+		path := []string{s.OperationName}
+		for parent, ok := parentOf(s, byID); ok && keep(parent); parent, ok = parentOf(parent, byID) {
+			path = append([]string{parent.OperationName}, path...)
+		}
+		paths = append(paths, strings.Join(path, " > "))
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+func parentOf(s jaegerSpan, byID map[string]jaegerSpan) (jaegerSpan, bool) {
+	for _, ref := range s.References {
+		if ref.RefType == "CHILD_OF" {
+			parent, ok := byID[ref.SpanID]
+			return parent, ok
+		}
+	}
+	return jaegerSpan{}, false
+}
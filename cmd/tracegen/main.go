@@ -0,0 +1,168 @@
+// Command tracegen turns a captured trace into a Go test skeleton that
+// asserts the same span structure, so a trace seen in production can become
+// a regression test. The trace is read from the Jaeger query API or from a
+// trace JSON file downloaded from the Jaeger UI:
+//
+//	go run ./cmd/tracegen -trace 0af7651916cd43dd8448eb211c80319c -service ServiceB -package main -out golang2/hello_trace_test.go
+//
+// The generated test records spans with an in-memory exporter; the code that
+// produced the trace has to be exercised where the TODO says so.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"log"
+	"os"
+	"strings"
+	"text/template"
+	"unicode"
+)
+
+func main() {
+	jaeger := flag.String("jaeger", "http://localhost:16686", "base URL of the Jaeger query API")
+	traceID := flag.String("trace", "", "ID of the trace to fetch from Jaeger")
+	file := flag.String("file", "", "trace JSON file to read instead of fetching the trace")
+	service := flag.String("service", "", "only keep the spans of this service, empty keeps every span")
+	pkg := flag.String("package", "main", "package of the generated test")
+	name := flag.String("name", "", "name of the test function, derived from the root span by default")
+	out := flag.String("out", "", "file to write the test to, stdout by default")
+	flag.Parse()
+
+	var (
+		t   jaegerTrace
+		err error
+	)
+	switch {
+	case *file != "":
+		t, err = readTrace(*file)
+	case *traceID != "":
+		t, err = fetchTrace(*jaeger, *traceID)
+	default:
+		log.Fatal("one of -trace or -file is required")
+	}
+	if err != nil {
+		log.Fatalf("failed to read trace: %v", err)
+	}
+
+	paths := spanPaths(t, *service)
+	if len(paths) == 0 {
+		log.Fatalf("trace %s has no spans for service %q", t.TraceID, *service)
+	}
+	if *name == "" {
+		*name = testName(paths[0])
+	}
+
+	src, err := generate(testFile{Package: *pkg, Name: *name, TraceID: t.TraceID, Service: *service, Paths: paths})
+	if err != nil {
+		log.Fatalf("failed to generate test: %v", err)
+	}
+	if *out == "" {
+		os.Stdout.Write(src)
+		return
+	}
+	if err := os.WriteFile(*out, src, 0o644); err != nil {
+		log.Fatalf("failed to write test: %v", err)
+	}
+}
+
+// testName derives a test function name from a span name, e.g.
+// "GET /users" becomes TestGETUsersTrace.
+func testName(path string) string {
+	root, _, _ := strings.Cut(path, " > ")
+	var b strings.Builder
+	b.WriteString("Test")
+	upper := true
+	for _, r := range root {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+	b.WriteString("Trace")
+	return b.String()
+}
+
+type testFile struct {
+	Package string
+	Name    string
+	TraceID string
+	Service string
+	Paths   []string
+}
+
+var testTemplate = template.Must(template.New("test").Parse(`// Code generated by cmd/tracegen from trace {{.TraceID}}{{if .Service}} ({{.Service}} spans){{end}}.
+// Complete the TODO, then edit freely.
+
+package {{.Package}}
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func {{.Name}}(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	defer otel.SetTracerProvider(previous)
+
+	// TODO: exercise the code path that produced trace {{.TraceID}}.
+
+	want := []string{
+{{- range .Paths}}
+		{{printf "%q" .}},
+{{- end}}
+	}
+	if got := spanPathsOf{{.Name}}(recorder.Ended()); !reflect.DeepEqual(got, want) {
+		t.Errorf("span structure changed\ngot:\n\t%s\nwant:\n\t%s",
+			strings.Join(got, "\n\t"), strings.Join(want, "\n\t"))
+	}
+}
+
+// spanPathsOf{{.Name}} lists every span as the names of its recorded
+// ancestors and its own, e.g. "GET /hello > GET localhost:5001", sorted.
+// Each generated test has its own, so that several can share a package.
+func spanPathsOf{{.Name}}(spans []sdktrace.ReadOnlySpan) []string {
+	byID := map[string]sdktrace.ReadOnlySpan{}
+	for _, s := range spans {
+		byID[s.SpanContext().SpanID().String()] = s
+	}
+	var paths []string
+	for _, s := range spans {
+		path := []string{s.Name()}
+		for p, ok := byID[s.Parent().SpanID().String()]; ok; p, ok = byID[p.Parent().SpanID().String()] {
+			path = append([]string{p.Name()}, path...)
+		}
+		paths = append(paths, strings.Join(path, " > "))
+	}
+	sort.Strings(paths)
+	return paths
+}
+`))
+
+func generate(f testFile) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := testTemplate.Execute(&buf, f); err != nil {
+		return nil, err
+	}
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("format generated test: %w", err)
+	}
+	return src, nil
+}
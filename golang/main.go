@@ -1,72 +1,106 @@
 package main
 
 import (
-	"context"
-	"fmt"
 	"log"
+	"log/slog"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
-	"go.opentelemetry.io/otel"
-	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
-	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
-	"go.opentelemetry.io/otel/sdk/resource"
-	sdktrace "go.opentelemetry.io/otel/sdk/trace"
-	semconv "go.opentelemetry.io/otel/semconv/v1.10.0"
 	"go.opentelemetry.io/otel/trace"
+
+	"test-jaeger/internal/canary"
+	"test-jaeger/internal/config"
+	"test-jaeger/internal/httpclient"
+	"test-jaeger/internal/service"
 )
 
-func initExporter() *otlptrace.Exporter {
-	// Create and configure the OTLP exporter to send traces to the collector
-	exporter, err := otlptracegrpc.New(context.Background(), otlptracegrpc.WithEndpointURL("http://localhost:4317/api/traces"))
-	if err != nil {
-		log.Fatalf("failed to create OTLP exporter: %v", err)
-		return nil
-	}
-	// Create a new trace provider with the exporter
-	provider := sdktrace.NewTracerProvider(
-		sdktrace.WithBatcher(exporter),
-		sdktrace.WithResource(resource.NewWithAttributes("", semconv.ServiceNameKey.String("ServiceA"))))
-	otel.SetTracerProvider(provider)
+// client is used for all calls to downstream services
+var client *http.Client
 
-	return exporter
-}
+// downstreamURL is the ServiceB endpoint called by HelloHandler
+var downstreamURL string
+
+// mirror copies a share of the downstream calls to a shadow instance when
+// configured
+var mirror *httpclient.Mirror
 
 // HelloHandler is the handler for the /hello route
 func HelloHandler(c *gin.Context) {
-	// Get the tracer from the global provider
-	// Start a span
-	span := trace.SpanFromContext(c)
-	ctx := trace.ContextWithSpan(c, span)
-	defer span.End()
-	span.AddEvent("handling the request")
-	req, _ := http.NewRequestWithContext(ctx, "GET", "http://localhost:5001/", nil)
-	resp, err := http.DefaultClient.Do(req)
+	// The span of the request continues the incoming trace, the downstream
+	// call is cancelled along with the request
+	ctx := c.Request.Context()
+	trace.SpanFromContext(ctx).AddEvent("handling the request")
+	req, _ := http.NewRequestWithContext(ctx, "GET", downstreamURL, nil)
+	mirror.Send(req)
+	resp, err := client.Do(req)
 	if err != nil {
-		span.RecordError(err)
+		c.Error(err)
+		if ctx.Err() != nil {
+			slog.InfoContext(ctx, "client went away before Service B answered", "error", ctx.Err())
+			c.Status(service.StatusClientClosedRequest)
+			return
+		}
+		slog.ErrorContext(ctx, "failed to call Service B", "error", err)
 		c.String(http.StatusInternalServerError, "Error calling Service A: %v", err)
 		return
 	}
 	defer resp.Body.Close()
-	log.Printf("Service B response: %v", resp.Status)
+	slog.InfoContext(ctx, "Service B response", "status", resp.Status)
 
 	// Respond with "Hello, World!"
 	c.String(http.StatusOK, "Hello, World!")
 }
 func main() {
-	// Create and configure the OTLP exporter to send traces to the collector
-	expoter := initExporter()
-	defer expoter.Shutdown(context.Background())
+	svc, err := service.New(config.Config{
+		ServiceName:       "ServiceA",
+		Listen:            ":5000",
+		DownstreamURL:     "http://localhost:5001/hello",
+		DownstreamService: "ServiceB",
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer svc.Close()
+	cfg := svc.Config
 
-	// Create a new Gin router
-	r := gin.Default()
+	downstreamURL = cfg.DownstreamURL
+
+	opts := []httpclient.Option{httpclient.WithPeerService(cfg.DownstreamService)}
+	if len(cfg.LoadBalancer.Instances) > 0 {
+		balancer, err := httpclient.NewBalancer(downstreamURL, cfg.LoadBalancer.Instances,
+			httpclient.Strategy(cfg.LoadBalancer.Strategy))
+		if err != nil {
+			log.Fatalf("invalid load balancer configuration: %v", err)
+		}
+		opts = append(opts, httpclient.WithBalancer(balancer))
+	}
+	client = httpclient.New(opts...)
+
+	if cfg.Shadow.URL != "" {
+		if mirror, err = httpclient.NewMirror(client, cfg.Shadow.URL, cfg.Shadow.Percent); err != nil {
+			log.Fatalf("invalid shadow configuration: %v", err)
+		}
+	}
 
 	// Define route handlers
-	r.GET("/hello", HelloHandler)
+	svc.Router.GET("/hello", HelloHandler)
+
+	// Compare the downstream versions when a canary is being rolled out
+	if cfg.Canary.Baseline != "" {
+		analyzer := canary.NewAnalyzer(canary.Config{
+			Baseline:          cfg.Canary.Baseline,
+			Canary:            cfg.Canary.Canary,
+			MinSamples:        cfg.Canary.MinSamples,
+			MaxErrorRateDelta: cfg.Canary.MaxErrorRateDelta,
+			MaxLatencyRatio:   cfg.Canary.MaxLatencyRatio,
+		})
+		svc.Tracer.RegisterSpanProcessor(analyzer)
+		svc.Router.GET("/canary/verdict", analyzer.Handler)
+	}
 
-	// Start HTTP server
-	fmt.Println("Server started on :5000")
-	if err := http.ListenAndServe(":5000", r); err != nil {
+	// Serve until SIGINT/SIGTERM, buffered spans are flushed by the deferred
+	// Close
+	if err := svc.Run(); err != nil {
 		log.Fatalf("failed to start server: %v", err)
 	}
 }
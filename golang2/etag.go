@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// etagWriter buffers the response so the ETag can be computed from the body
+// before anything is sent to the client.
+type etagWriter struct {
+	gin.ResponseWriter
+	body   bytes.Buffer
+	status int
+}
+
+func (w *etagWriter) WriteHeader(code int) { w.status = code }
+func (w *etagWriter) WriteHeaderNow()      {}
+func (w *etagWriter) Status() int          { return w.status }
+func (w *etagWriter) Size() int            { return w.body.Len() }
+func (w *etagWriter) Written() bool        { return w.body.Len() > 0 }
+
+func (w *etagWriter) Write(b []byte) (int, error) { return w.body.Write(b) }
+
+func (w *etagWriter) WriteString(s string) (int, error) { return w.body.WriteString(s) }
+
+// etagMatches reports whether the If-None-Match header contains the given ETag.
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// ETagMiddleware adds a strong ETag to successful GET responses and answers
+// conditional requests with 304 Not Modified. Every validation is recorded on
+// the span as http.cache.validated and 304s are counted.
+func ETagMiddleware() gin.HandlerFunc {
+	notModified, err := otel.Meter("serviceB").Int64Counter("http.server.not_modified",
+		metric.WithDescription("Number of requests answered with 304 Not Modified"))
+	if err != nil {
+		log.Printf("failed to create not_modified counter: %v", err)
+	}
+
+	return func(c *gin.Context) {
+		if c.Request.Method != http.MethodGet {
+			c.Next()
+			return
+		}
+
+		ctx := c.Request.Context()
+		span := trace.SpanFromContext(ctx)
+
+		original := c.Writer
+		w := &etagWriter{ResponseWriter: original, status: http.StatusOK}
+		c.Writer = w
+		// Restored on a panic too, so the 500 of telemetry.Recover reaches
+		// the client rather than the buffer
+		defer func() { c.Writer = original }()
+		c.Next()
+
+		if w.status != http.StatusOK {
+			original.WriteHeader(w.status)
+			original.Write(w.body.Bytes())
+			return
+		}
+
+		sum := sha256.Sum256(w.body.Bytes())
+		etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+		original.Header().Set("ETag", etag)
+		span.SetAttributes(attribute.String("http.response.etag", etag))
+
+		if inm := c.GetHeader("If-None-Match"); inm != "" {
+			validated := etagMatches(inm, etag)
+			span.SetAttributes(attribute.Bool("http.cache.validated", validated))
+			if validated {
+				span.AddEvent("returning 304 Not Modified")
+				if notModified != nil {
+					notModified.Add(ctx, 1, metric.WithAttributes(semconv.HTTPRouteKey.String(c.FullPath())))
+				}
+				original.WriteHeader(http.StatusNotModified)
+				original.WriteHeaderNow()
+				return
+			}
+		}
+
+		original.WriteHeader(w.status)
+		original.Write(w.body.Bytes())
+	}
+}
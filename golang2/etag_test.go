@@ -0,0 +1,51 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"test-jaeger/internal/telemetry"
+)
+
+func newETagRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(telemetry.Recover())
+	etag := ETagMiddleware()
+	router.GET("/users", etag, func(c *gin.Context) { c.JSON(http.StatusOK, []string{"ada"}) })
+	router.GET("/panic", etag, func(*gin.Context) { panic("boom") })
+	return router
+}
+
+func TestETagMiddleware(t *testing.T) {
+	router := newETagRouter()
+
+	first := httptest.NewRecorder()
+	router.ServeHTTP(first, httptest.NewRequest(http.MethodGet, "/users", nil))
+	etag := first.Header().Get("ETag")
+	if first.Code != http.StatusOK || etag == "" || first.Body.String() != `["ada"]` {
+		t.Fatalf("first response = %d %q with ETag %q", first.Code, first.Body.String(), etag)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/users", nil)
+	req.Header.Set("If-None-Match", etag)
+	second := httptest.NewRecorder()
+	router.ServeHTTP(second, req)
+	if second.Code != http.StatusNotModified || second.Body.Len() != 0 {
+		t.Errorf("conditional response = %d %q, want 304 without a body", second.Code, second.Body.String())
+	}
+}
+
+func TestETagMiddlewarePanic(t *testing.T) {
+	w := httptest.NewRecorder()
+	newETagRouter().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/panic", nil))
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d, want 500", w.Code)
+	}
+	if w.Header().Get("ETag") != "" {
+		t.Errorf("failed response has ETag %q", w.Header().Get("ETag"))
+	}
+}
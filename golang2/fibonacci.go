@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// maxFibonacciN is the largest n whose Fibonacci number fits in an int64.
+const maxFibonacciN = 92
+
+// FibonacciHandler serves /fibonacci?n=. Every call is counted as
+// fibonacci.invocations with fibonacci.valid.n telling whether n was
+// accepted; invalid input is recorded on the span and answered with 400.
+func FibonacciHandler() gin.HandlerFunc {
+	invocations, err := otel.Meter("serviceB").Int64Counter("fibonacci.invocations",
+		metric.WithDescription("Number of calls to the fibonacci endpoint"))
+	if err != nil {
+		log.Printf("failed to create fibonacci.invocations counter: %v", err)
+	}
+
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		n, err := parseFibonacciN(c.Query("n"))
+		if invocations != nil {
+			invocations.Add(ctx, 1, metric.WithAttributes(attribute.Bool("fibonacci.valid.n", err == nil)))
+		}
+		if err != nil {
+			slog.WarnContext(ctx, "invalid fibonacci input", "error", err)
+			trace.SpanFromContext(ctx).RecordError(err)
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"n": n, "result": fibonacci(ctx, n)})
+	}
+}
+
+func parseFibonacciN(raw string) (int, error) {
+	if raw == "" {
+		return 0, fmt.Errorf("missing query parameter n")
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil {
+		return 0, fmt.Errorf("n must be an integer, got %q", raw)
+	}
+	if n < 0 || n > maxFibonacciN {
+		return 0, fmt.Errorf("n must be between 0 and %d, got %d", maxFibonacciN, n)
+	}
+	return n, nil
+}
+
+// fibonacci computes the n-th Fibonacci number iteratively in its own span.
+func fibonacci(ctx context.Context, n int) int64 {
+	_, span := otel.GetTracerProvider().Tracer("serviceB").Start(ctx, "fibonacci")
+	defer span.End()
+
+	var a, b int64 = 0, 1
+	for i := 0; i < n; i++ {
+		a, b = b, a+b
+	}
+	span.SetAttributes(attribute.Int("fibonacci.n", n), attribute.Int64("fibonacci.result", a))
+	return a
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"test-jaeger/internal/store"
+	"test-jaeger/pkg/models"
+)
+
+// usersHandler serves the users of the configured store.
+type usersHandler struct {
+	store store.Store
+}
+
+func (h usersHandler) list(c *gin.Context) {
+	users, err := h.store.GetUsers(c.Request.Context())
+	if err != nil {
+		slog.ErrorContext(c.Request.Context(), "failed to list users", "error", err)
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list users"})
+		return
+	}
+	c.JSON(http.StatusOK, users)
+}
+
+func (h usersHandler) get(c *gin.Context) {
+	u, err := h.store.GetUser(c.Request.Context(), c.Param("id"))
+	if errors.Is(err, store.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(c.Request.Context(), "failed to get user", "error", err)
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get user"})
+		return
+	}
+	c.JSON(http.StatusOK, u)
+}
+
+func (h usersHandler) create(c *gin.Context) {
+	var u models.User
+	if err := c.ShouldBindJSON(&u); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	u, err := h.store.CreateUser(c.Request.Context(), u)
+	if err != nil {
+		slog.ErrorContext(c.Request.Context(), "failed to create user", "error", err)
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create user"})
+		return
+	}
+	c.JSON(http.StatusCreated, u)
+}
+
+// seed fills the store with fake users, POST /admin/seed?users=1000&seed=42,
+// and answers with the number created. It is the way to seed the memory
+// store, which lives in the service, see cmd/seed for the other backends.
+func (h usersHandler) seed(c *gin.Context) {
+	var opts store.SeedOptions
+	var err error
+	if opts.Users, err = strconv.Atoi(c.DefaultQuery("users", "100")); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "users must be a number"})
+		return
+	}
+	if opts.Seed, err = strconv.ParseUint(c.DefaultQuery("seed", "1"), 10, 64); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "seed must be a positive number"})
+		return
+	}
+	if opts.Users < 0 || opts.Users > store.MaxSeedUsers {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("users must be between 0 and %d", store.MaxSeedUsers)})
+		return
+	}
+	created, err := store.Seed(c.Request.Context(), h.store, opts)
+	if err != nil {
+		slog.ErrorContext(c.Request.Context(), "failed to seed users", "error", err, "created", created)
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to seed users", "created": created})
+		return
+	}
+	slog.InfoContext(c.Request.Context(), "seeded users", "created", created, "seed", opts.Seed)
+	c.JSON(http.StatusOK, gin.H{"created": created})
+}
@@ -0,0 +1,176 @@
+// Package canary compares the telemetry of two versions of a downstream
+// service and turns it into a pass/fail verdict, demoing telemetry driven
+// canary gates. Observations come from the client spans of the calling
+// service, grouped by the peer.service.version the callee reported.
+package canary
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+
+	"test-jaeger/internal/telemetry"
+)
+
+// Config selects the cohorts and the tolerances of the analysis.
+type Config struct {
+	Baseline string
+	Canary   string
+	// MinSamples per cohort before a verdict other than inconclusive is given.
+	MinSamples int
+	// MaxErrorRateDelta is how much higher the canary error rate may be, e.g. 0.01.
+	MaxErrorRateDelta float64
+	// MaxLatencyRatio bounds canary p95 / baseline p95, e.g. 1.2.
+	MaxLatencyRatio float64
+}
+
+// window is the number of most recent observations kept per cohort.
+const window = 1000
+
+type observation struct {
+	latency time.Duration
+	failed  bool
+}
+
+type cohort struct {
+	obs  []observation
+	next int
+}
+
+func (c *cohort) add(o observation) {
+	if len(c.obs) < window {
+		c.obs = append(c.obs, o)
+		return
+	}
+	c.obs[c.next] = o
+	c.next = (c.next + 1) % window
+}
+
+// CohortStats summarizes one version.
+type CohortStats struct {
+	Version   string  `json:"version"`
+	Samples   int     `json:"samples"`
+	ErrorRate float64 `json:"error_rate"`
+	P95Millis float64 `json:"p95_ms"`
+}
+
+func (c *cohort) stats(version string) CohortStats {
+	s := CohortStats{Version: version, Samples: len(c.obs)}
+	if len(c.obs) == 0 {
+		return s
+	}
+	latencies := make([]time.Duration, 0, len(c.obs))
+	failed := 0
+	for _, o := range c.obs {
+		latencies = append(latencies, o.latency)
+		if o.failed {
+			failed++
+		}
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	s.ErrorRate = float64(failed) / float64(len(c.obs))
+	s.P95Millis = float64(latencies[(len(latencies)*95-1)/100]) / float64(time.Millisecond)
+	return s
+}
+
+// Verdict is the result of an analysis.
+type Verdict struct {
+	Result   string      `json:"result"` // pass, fail or inconclusive
+	Reasons  []string    `json:"reasons,omitempty"`
+	Baseline CohortStats `json:"baseline"`
+	Canary   CohortStats `json:"canary"`
+}
+
+// Analyzer is a span processor collecting the observations of both cohorts.
+type Analyzer struct {
+	cfg Config
+
+	mu      sync.Mutex
+	cohorts map[string]*cohort
+}
+
+var _ sdktrace.SpanProcessor = (*Analyzer)(nil)
+
+// NewAnalyzer returns an analyzer for cfg, filling unset tolerances with 20
+// samples, +1% errors and +20% p95 latency. Register it on the tracer provider.
+func NewAnalyzer(cfg Config) *Analyzer {
+	if cfg.MinSamples == 0 {
+		cfg.MinSamples = 20
+	}
+	if cfg.MaxErrorRateDelta == 0 {
+		cfg.MaxErrorRateDelta = 0.01
+	}
+	if cfg.MaxLatencyRatio == 0 {
+		cfg.MaxLatencyRatio = 1.2
+	}
+	return &Analyzer{
+		cfg:     cfg,
+		cohorts: map[string]*cohort{cfg.Baseline: {}, cfg.Canary: {}},
+	}
+}
+
+// OnEnd records finished client spans that belong to one of the cohorts.
+func (a *Analyzer) OnEnd(s sdktrace.ReadOnlySpan) {
+	if s.SpanKind() != trace.SpanKindClient {
+		return
+	}
+	var version string
+	for _, kv := range s.Attributes() {
+		if kv.Key == telemetry.PeerVersionKey {
+			version = kv.Value.AsString()
+		}
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if c, ok := a.cohorts[version]; ok {
+		c.add(observation{latency: s.EndTime().Sub(s.StartTime()), failed: s.Status().Code == codes.Error})
+	}
+}
+
+func (a *Analyzer) OnStart(context.Context, sdktrace.ReadWriteSpan) {}
+func (a *Analyzer) Shutdown(context.Context) error                  { return nil }
+func (a *Analyzer) ForceFlush(context.Context) error                { return nil }
+
+// Verdict compares the cohorts against the configured tolerances.
+func (a *Analyzer) Verdict() Verdict {
+	a.mu.Lock()
+	v := Verdict{
+		Baseline: a.cohorts[a.cfg.Baseline].stats(a.cfg.Baseline),
+		Canary:   a.cohorts[a.cfg.Canary].stats(a.cfg.Canary),
+	}
+	a.mu.Unlock()
+
+	if v.Baseline.Samples < a.cfg.MinSamples || v.Canary.Samples < a.cfg.MinSamples {
+		v.Result = "inconclusive"
+		v.Reasons = append(v.Reasons, "not enough samples")
+		return v
+	}
+	v.Result = "pass"
+	if v.Canary.ErrorRate > v.Baseline.ErrorRate+a.cfg.MaxErrorRateDelta {
+		v.Result = "fail"
+		v.Reasons = append(v.Reasons, "canary error rate exceeds baseline")
+	}
+	if v.Baseline.P95Millis > 0 && v.Canary.P95Millis > v.Baseline.P95Millis*a.cfg.MaxLatencyRatio {
+		v.Result = "fail"
+		v.Reasons = append(v.Reasons, "canary p95 latency exceeds baseline")
+	}
+	return v
+}
+
+// Handler serves the verdict. Failing canaries answer 412 so that deployment
+// gates can use the status code alone.
+func (a *Analyzer) Handler(c *gin.Context) {
+	v := a.Verdict()
+	status := http.StatusOK
+	if v.Result == "fail" {
+		status = http.StatusPreconditionFailed
+	}
+	c.JSON(status, v)
+}
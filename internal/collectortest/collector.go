@@ -0,0 +1,123 @@
+// Package collectortest is a mock OTLP collector for tests: it accepts trace
+// exports over gRPC, as Jaeger or the OpenTelemetry Collector would, and
+// keeps the spans in memory so tests can check the traces the services and
+// their peers left, whatever SDK exported them.
+package collectortest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/grpc"
+)
+
+// Span is a span received by the collector with the service.name of the
+// resource it was exported with.
+type Span struct {
+	Service string
+	*tracepb.Span
+}
+
+// Collector is a running mock collector.
+type Collector struct {
+	coltracepb.UnimplementedTraceServiceServer
+
+	// Endpoint is the OTLP endpoint of the collector, e.g. to set as
+	// telemetry.Exporter.Endpoint.
+	Endpoint string
+
+	mu    sync.Mutex
+	spans []Span
+}
+
+// Start starts a collector on a free local port, stopped when the test
+// ends.
+func Start(t testing.TB) *Collector {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	c := &Collector{Endpoint: "http://" + lis.Addr().String()}
+	srv := grpc.NewServer()
+	coltracepb.RegisterTraceServiceServer(srv, c)
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+	return c
+}
+
+// Export implements the OTLP trace service.
+func (c *Collector) Export(_ context.Context, req *coltracepb.ExportTraceServiceRequest) (*coltracepb.ExportTraceServiceResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, rs := range req.GetResourceSpans() {
+		var service string
+		for _, kv := range rs.GetResource().GetAttributes() {
+			if kv.GetKey() == "service.name" {
+				service = kv.GetValue().GetStringValue()
+			}
+		}
+		for _, ss := range rs.GetScopeSpans() {
+			for _, s := range ss.GetSpans() {
+				c.spans = append(c.spans, Span{Service: service, Span: s})
+			}
+		}
+	}
+	return &coltracepb.ExportTraceServiceResponse{}, nil
+}
+
+// Trace returns the spans received for the trace with the given 16 byte id.
+func (c *Collector) Trace(traceID [16]byte) []Span {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var spans []Span
+	for _, s := range c.spans {
+		if bytes.Equal(s.GetTraceId(), traceID[:]) {
+			spans = append(spans, s)
+		}
+	}
+	return spans
+}
+
+// WellFormed checks that spans form a single trace: they share a trace id,
+// every span has an id of its own, exactly one of them is the root and every
+// other span's parent is in the trace.
+func WellFormed(spans []Span) error {
+	if len(spans) == 0 {
+		return errors.New("no spans")
+	}
+	ids := make(map[string]bool, len(spans))
+	for _, s := range spans {
+		if len(s.GetSpanId()) != 8 || bytes.Equal(s.GetSpanId(), make([]byte, 8)) {
+			return fmt.Errorf("span %q of %s has invalid id %x", s.GetName(), s.Service, s.GetSpanId())
+		}
+		if !bytes.Equal(s.GetTraceId(), spans[0].GetTraceId()) {
+			return fmt.Errorf("span %q of %s has trace id %x, want %x", s.GetName(), s.Service, s.GetTraceId(), spans[0].GetTraceId())
+		}
+		id := string(s.GetSpanId())
+		if ids[id] {
+			return fmt.Errorf("span id %x is used twice", s.GetSpanId())
+		}
+		ids[id] = true
+	}
+	var roots int
+	for _, s := range spans {
+		switch parent := s.GetParentSpanId(); {
+		case len(parent) == 0:
+			roots++
+		case !ids[string(parent)]:
+			return fmt.Errorf("span %q of %s has parent %x missing from the trace", s.GetName(), s.Service, parent)
+		}
+	}
+	if roots != 1 {
+		return fmt.Errorf("trace has %d root spans, want 1", roots)
+	}
+	return nil
+}
@@ -0,0 +1,670 @@
+// Package config loads the service configuration from a YAML or JSON file on
+// top of per-service defaults and validates it, so misconfiguration is
+// reported as an error at startup instead of a panic.
+package config
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+
+	"test-jaeger/internal/httpclient"
+	"test-jaeger/internal/masking"
+	"test-jaeger/internal/store"
+	"test-jaeger/internal/telemetry"
+	"test-jaeger/internal/telemetry/profiling"
+)
+
+// Config is the configuration shared by every service.
+type Config struct {
+	ServiceName    string   `yaml:"service_name" json:"service_name"`
+	ServiceVersion string   `yaml:"service_version" json:"service_version"`
+	Listen         string   `yaml:"listen" json:"listen"`
+	DownstreamURL  string   `yaml:"downstream_url" json:"downstream_url"`
+	Exporter       Exporter `yaml:"exporter" json:"exporter"`
+	Sampler        Sampler  `yaml:"sampler" json:"sampler"`
+	Propagators    []string `yaml:"propagators" json:"propagators"`
+	Metrics        Metrics  `yaml:"metrics" json:"metrics"`
+	Shadow         Shadow   `yaml:"shadow" json:"shadow"`
+	Canary         Canary   `yaml:"canary" json:"canary"`
+	// Store is the DSN of the data store, see store.Open.
+	Store string `yaml:"store" json:"store"`
+	// Profiling pushes continuous profiles, see Profiling.
+	Profiling Profiling `yaml:"profiling" json:"profiling"`
+	// HeartbeatInterval, e.g. "30s", enables a periodic heartbeat span and
+	// metric so that a silent service can be told from an idle one.
+	HeartbeatInterval string `yaml:"heartbeat_interval" json:"heartbeat_interval"`
+	// Record is the path of a file the handled requests are recorded to,
+	// for cmd/replay. Recording is off while it is empty.
+	Record string `yaml:"record" json:"record"`
+	// LoadBalancer spreads the calls to downstream_url over its instances.
+	LoadBalancer LoadBalancer `yaml:"load_balancer" json:"load_balancer"`
+	// DownstreamService names the service at downstream_url, recorded as
+	// peer.service on the calls to it.
+	DownstreamService string `yaml:"downstream_service" json:"downstream_service"`
+	// Exporters are more backends the spans are exported to, next to
+	// Exporter, e.g. to compare them side by side. Metrics and logs only go
+	// to Exporter.
+	Exporters []Exporter `yaml:"exporters" json:"exporters"`
+	// EgressAllowlist restricts the outbound HTTP calls to these hosts, see
+	// httpclient.SetEgressAllowlist. Empty allows any host.
+	EgressAllowlist []string `yaml:"egress_allowlist" json:"egress_allowlist"`
+	// Masking hides emails, tokens and the like in recordings, debug
+	// endpoints and exported spans.
+	Masking Masking `yaml:"masking" json:"masking"`
+	// GRPCListen is the address of the gRPC server, serving the
+	// grpc.health.v1 health checks. The server is off while it is empty.
+	GRPCListen string `yaml:"grpc_listen" json:"grpc_listen"`
+	// AdminListen is the address of the admin server, serving the
+	// net/http/pprof profiles under /debug/pprof/ and the routes changing
+	// the state of the service under /admin/. It should stay private, e.g.
+	// on localhost. The server and the routes are off while it is empty.
+	AdminListen string `yaml:"admin_listen" json:"admin_listen"`
+	// GRPCReflection registers the gRPC server reflection service, so
+	// grpcurl can list and call the services without their protos.
+	GRPCReflection bool `yaml:"grpc_reflection" json:"grpc_reflection"`
+	// AWSXRay switches to X-Ray compatible trace IDs and propagation, see
+	// telemetry.Config.XRay.
+	AWSXRay bool `yaml:"aws_xray" json:"aws_xray"`
+	// DropSpanTargets keeps the spans of these paths and gRPC methods, e.g.
+	// probes, from being exported, see telemetry.Config.DropSpanTargets.
+	DropSpanTargets []string `yaml:"drop_span_targets" json:"drop_span_targets"`
+	// SanitizeURLs strips query strings and IDs from the URLs of the
+	// exported spans, see telemetry.Config.SanitizeURLs.
+	SanitizeURLs bool `yaml:"sanitize_urls" json:"sanitize_urls"`
+	// SyncExport exports each span as it ends instead of batching them, see
+	// telemetry.Config.SyncExport.
+	SyncExport bool `yaml:"sync_export" json:"sync_export"`
+	// Batch tunes the batching of the exported spans.
+	Batch Batch `yaml:"batch" json:"batch"`
+	// DebugSpans is the number of the last spans kept in memory for
+	// /debug/traces and /debug/correlate, see telemetry.SpanCapture.
+	DebugSpans int `yaml:"debug_spans" json:"debug_spans"`
+	// TraceURL is the template of the links to the traces, see
+	// telemetry.TraceLink. Defaults to the Jaeger UI for Jaeger.
+	TraceURL string `yaml:"trace_url" json:"trace_url"`
+
+	source *source
+}
+
+// source remembers how a configuration was built, so that it can be built
+// again from the file on disk, see Reload.
+type source struct {
+	path      string
+	defaults  Config
+	overrides func(*Config)
+}
+
+// LoadBalancer lists the instances of the downstream service. Balancing is
+// off while Instances is empty.
+type LoadBalancer struct {
+	// Strategy is round_robin (the default), least_loaded or
+	// consistent_hash, see httpclient.Strategy.
+	Strategy  string   `yaml:"strategy" json:"strategy"`
+	Instances []string `yaml:"instances" json:"instances"`
+}
+
+// Masking adds rules to masking.DefaultRules, or replaces them when
+// NoDefaults is set.
+type Masking struct {
+	Rules      []masking.Rule `yaml:"rules" json:"rules"`
+	NoDefaults bool           `yaml:"no_defaults" json:"no_defaults"`
+}
+
+// Exporter selects the tracing backend and its OTLP endpoint.
+type Exporter struct {
+	Type     string `yaml:"type" json:"type"`
+	Endpoint string `yaml:"endpoint" json:"endpoint"`
+	// LicenseKey is the New Relic license key, see telemetry.Config.
+	LicenseKey string `yaml:"license_key" json:"license_key"`
+	// OpsRampConfig holds the OAuth2 client credentials and IDs of OpsRamp exports.
+	OpsRamp OpsRamp `yaml:"opsramp" json:"opsramp"`
+	// File configures the file backend.
+	File File `yaml:"file" json:"file"`
+	// Keepalive pings idle exporter connections, see
+	// telemetry.KeepaliveConfig.
+	Keepalive Keepalive `yaml:"keepalive" json:"keepalive"`
+	// TLS adds a client certificate and a CA bundle to an https endpoint,
+	// see telemetry.TLSConfig.
+	TLS TLS `yaml:"tls" json:"tls"`
+	// Retry is how failed exports are retried, see telemetry.RetryPolicy.
+	Retry Retry `yaml:"retry" json:"retry"`
+	// FailoverEndpoints take over the traces while the endpoint keeps
+	// failing, see telemetry.Exporter.FailoverEndpoints.
+	FailoverEndpoints []string `yaml:"failover_endpoints" json:"failover_endpoints"`
+	// Spool keeps the spans on disk while the endpoint is down, see
+	// telemetry.SpoolConfig.
+	Spool Spool `yaml:"spool" json:"spool"`
+	// Compression is gzip or none, see telemetry.Exporter.Compression.
+	Compression string `yaml:"compression" json:"compression"`
+}
+
+// Retry configures the retries of the failed exports. The intervals are
+// durations, e.g. "5s"; empty ones keep the defaults.
+type Retry struct {
+	Disabled        bool   `yaml:"disabled" json:"disabled"`
+	InitialInterval string `yaml:"initial_interval" json:"initial_interval"`
+	MaxInterval     string `yaml:"max_interval" json:"max_interval"`
+	MaxElapsedTime  string `yaml:"max_elapsed_time" json:"max_elapsed_time"`
+}
+
+// Spool configures the disk spool of the failed exports, off while Dir is
+// empty.
+type Spool struct {
+	Dir       string `yaml:"dir" json:"dir"`
+	MaxSizeMB int64  `yaml:"max_size_mb" json:"max_size_mb"`
+}
+
+// TLS names the PEM files securing the exporter connections.
+type TLS struct {
+	CertFile string `yaml:"cert_file" json:"cert_file"`
+	KeyFile  string `yaml:"key_file" json:"key_file"`
+	CAFile   string `yaml:"ca_file" json:"ca_file"`
+}
+
+// Batch tunes the span batching, see telemetry.BatchConfig. ScheduleDelay
+// and ExportTimeout are durations, e.g. "1s"; zero values keep the defaults.
+type Batch struct {
+	MaxExportBatchSize int    `yaml:"max_export_batch_size" json:"max_export_batch_size"`
+	MaxQueueSize       int    `yaml:"max_queue_size" json:"max_queue_size"`
+	ScheduleDelay      string `yaml:"schedule_delay" json:"schedule_delay"`
+	ExportTimeout      string `yaml:"export_timeout" json:"export_timeout"`
+}
+
+// Keepalive configures the keepalive pings of the exporter connections.
+// Time and Timeout are durations, e.g. "30s"; an empty Time disables them.
+type Keepalive struct {
+	Time                string `yaml:"time" json:"time"`
+	Timeout             string `yaml:"timeout" json:"timeout"`
+	PermitWithoutStream bool   `yaml:"permit_without_stream" json:"permit_without_stream"`
+}
+
+// File configures the rotating OTLP JSON file of the file backend, see
+// telemetry.FileConfig.
+type File struct {
+	Path      string `yaml:"path" json:"path"`
+	MaxSizeMB int64  `yaml:"max_size_mb" json:"max_size_mb"`
+}
+
+// OpsRamp configures the OAuth2 client credentials exchanged for the bearer
+// tokens of the OpsRamp exports, and the tenant and resource the telemetry
+// belongs to, see telemetry.OpsRampConfig.
+type OpsRamp struct {
+	TokenURL     string `yaml:"token_url" json:"token_url"`
+	ClientID     string `yaml:"client_id" json:"client_id"`
+	ClientSecret string `yaml:"client_secret" json:"client_secret"`
+	TenantID     string `yaml:"tenant_id" json:"tenant_id"`
+	ResourceUUID string `yaml:"resource_uuid" json:"resource_uuid"`
+}
+
+// Sampler selects the sampling strategy, see telemetry.Config.
+type Sampler struct {
+	Type string `yaml:"type" json:"type"`
+	// Arg is the ratio or the spans per second of the sampler, nil when
+	// unset to use telemetry.DefaultSamplerArg.
+	Arg *float64 `yaml:"arg" json:"arg"`
+	// ReportInterval, e.g. "1m", enables the periodic sampling report.
+	ReportInterval string `yaml:"report_interval" json:"report_interval"`
+}
+
+// Metrics configures the metric pipeline.
+type Metrics struct {
+	// ExemplarFilter is trace_based, always_on or always_off, see
+	// telemetry.Config.
+	ExemplarFilter string `yaml:"exemplar_filter" json:"exemplar_filter"`
+}
+
+// Profiling pushes the profiles of the service to a Pyroscope server,
+// linked to the traces, see package profiling. It is off while
+// ServerAddress is empty.
+type Profiling struct {
+	ServerAddress  string `yaml:"server_address" json:"server_address"`
+	TenantID       string `yaml:"tenant_id" json:"tenant_id"`
+	UploadInterval string `yaml:"upload_interval" json:"upload_interval"`
+}
+
+// Shadow mirrors a percentage of downstream traffic to a shadow instance.
+// Mirroring is off while URL is empty.
+type Shadow struct {
+	URL     string  `yaml:"url" json:"url"`
+	Percent float64 `yaml:"percent" json:"percent"`
+}
+
+// Canary compares two versions of the downstream service. The analysis is
+// off while Baseline is empty; zero tolerances use the canary package defaults.
+type Canary struct {
+	Baseline          string  `yaml:"baseline" json:"baseline"`
+	Canary            string  `yaml:"canary" json:"canary"`
+	MinSamples        int     `yaml:"min_samples" json:"min_samples"`
+	MaxErrorRateDelta float64 `yaml:"max_error_rate_delta" json:"max_error_rate_delta"`
+	MaxLatencyRatio   float64 `yaml:"max_latency_ratio" json:"max_latency_ratio"`
+}
+
+// Load returns defaults overlaid with the file at path. An empty path just
+// validates the defaults. Files ending in .json are decoded as JSON, anything
+// else as YAML; unknown fields are rejected in both.
+func Load(path string, defaults Config) (Config, error) {
+	return load(&source{path: path, defaults: defaults})
+}
+
+func load(src *source) (Config, error) {
+	cfg, err := loadFile(src.path, src.defaults)
+	if err != nil {
+		return Config{}, err
+	}
+	if src.overrides != nil {
+		src.overrides(&cfg)
+	}
+	if err := cfg.Validate(); err != nil {
+		return Config{}, err
+	}
+	cfg.source = src
+	return cfg, nil
+}
+
+// Path is the file the configuration was loaded from, empty if none.
+func (c Config) Path() string {
+	if c.source == nil {
+		return ""
+	}
+	return c.source.path
+}
+
+// Reload builds the configuration again from the current content of its
+// file, with the same defaults and command line flags. Comparing the Hash of
+// the result to that of c tells whether the file changed since c was loaded.
+func (c Config) Reload() (Config, error) {
+	if c.source == nil {
+		return c, nil
+	}
+	return load(c.source)
+}
+
+// Redacted returns c with the exporter secrets and the passwords of the URLs
+// it holds masked, so it can be displayed.
+func (c Config) Redacted() Config {
+	c.Exporters = slices.Clone(c.Exporters)
+	urls := []*string{&c.DownstreamURL, &c.Store, &c.Shadow.URL, &c.Canary.Baseline, &c.Canary.Canary, &c.Profiling.ServerAddress}
+	exporters := []*Exporter{&c.Exporter}
+	for i := range c.Exporters {
+		exporters = append(exporters, &c.Exporters[i])
+	}
+	for _, e := range exporters {
+		for _, s := range []*string{&e.LicenseKey, &e.OpsRamp.ClientSecret} {
+			if *s != "" {
+				*s = "xxxxx"
+			}
+		}
+		urls = append(urls, &e.Endpoint)
+	}
+	for _, s := range urls {
+		if u, err := url.Parse(*s); err == nil && u.User != nil {
+			*s = u.Redacted()
+		}
+	}
+	return c
+}
+
+func loadFile(path string, defaults Config) (Config, error) {
+	cfg := defaults
+	if path == "" {
+		return cfg, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, fmt.Errorf("read config: %w", err)
+	}
+	if err := decode(path, data, &cfg); err != nil {
+		return Config{}, fmt.Errorf("parse config %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+func decode(path string, data []byte, cfg *Config) error {
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		return dec.Decode(cfg)
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	return dec.Decode(cfg)
+}
+
+// Validate reports every invalid setting at once.
+func (c Config) Validate() error {
+	var errs []error
+	if c.ServiceName == "" {
+		errs = append(errs, errors.New("service_name must be set"))
+	}
+	if _, port, err := net.SplitHostPort(c.Listen); err != nil {
+		errs = append(errs, fmt.Errorf("listen %q: %w", c.Listen, err))
+	} else if n, err := strconv.Atoi(port); err != nil || n < 0 || n > 65535 {
+		errs = append(errs, fmt.Errorf("listen %q: invalid port", c.Listen))
+	}
+	if c.GRPCListen != "" {
+		if _, port, err := net.SplitHostPort(c.GRPCListen); err != nil {
+			errs = append(errs, fmt.Errorf("grpc_listen %q: %w", c.GRPCListen, err))
+		} else if n, err := strconv.Atoi(port); err != nil || n < 0 || n > 65535 {
+			errs = append(errs, fmt.Errorf("grpc_listen %q: invalid port", c.GRPCListen))
+		} else if c.GRPCListen == c.Listen {
+			errs = append(errs, fmt.Errorf("grpc_listen %q is also listen", c.GRPCListen))
+		}
+	}
+	if c.AdminListen != "" {
+		if _, port, err := net.SplitHostPort(c.AdminListen); err != nil {
+			errs = append(errs, fmt.Errorf("admin_listen %q: %w", c.AdminListen, err))
+		} else if n, err := strconv.Atoi(port); err != nil || n < 0 || n > 65535 {
+			errs = append(errs, fmt.Errorf("admin_listen %q: invalid port", c.AdminListen))
+		} else if c.AdminListen == c.Listen || c.AdminListen == c.GRPCListen {
+			errs = append(errs, fmt.Errorf("admin_listen %q is also listen or grpc_listen", c.AdminListen))
+		}
+	}
+	errs = append(errs, c.Exporter.validate("exporter")...)
+	spools := map[string]bool{c.Exporter.Spool.Dir: true}
+	for i, e := range c.Exporters {
+		errs = append(errs, e.validate(fmt.Sprintf("exporters[%d]", i))...)
+		if e.Spool.Dir != "" && spools[e.Spool.Dir] {
+			errs = append(errs, fmt.Errorf("exporters[%d].spool.dir %s is used by another exporter", i, e.Spool.Dir))
+		}
+		spools[e.Spool.Dir] = true
+	}
+	if c.DownstreamURL != "" {
+		if u, err := url.Parse(c.DownstreamURL); err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+			errs = append(errs, fmt.Errorf("downstream_url %q is not an absolute http or https URL", c.DownstreamURL))
+		}
+	} else if c.DownstreamService != "" {
+		errs = append(errs, fmt.Errorf("downstream_service %q is set without downstream_url", c.DownstreamService))
+	}
+	if strings.ContainsFunc(c.DownstreamService, unicode.IsSpace) {
+		errs = append(errs, fmt.Errorf("downstream_service %q contains spaces", c.DownstreamService))
+	}
+	if c.LoadBalancer.Strategy != "" && !slices.Contains(httpclient.Strategies, httpclient.Strategy(c.LoadBalancer.Strategy)) {
+		errs = append(errs, fmt.Errorf("load_balancer.strategy %q is not one of %v", c.LoadBalancer.Strategy, httpclient.Strategies))
+	}
+	for _, instance := range c.LoadBalancer.Instances {
+		if u, err := url.Parse(instance); err != nil || u.Host == "" {
+			errs = append(errs, fmt.Errorf("load_balancer.instances: %q is not an absolute URL", instance))
+		}
+	}
+	for _, host := range c.EgressAllowlist {
+		if host == "" || strings.Contains(host, "/") {
+			errs = append(errs, fmt.Errorf("egress_allowlist: %q is not a host, host:port or *.domain", host))
+		}
+	}
+	if _, err := masking.New(c.Masking.Rules, c.Masking.NoDefaults); err != nil {
+		errs = append(errs, fmt.Errorf("masking: %w", err))
+	}
+	if c.Profiling.ServerAddress != "" {
+		if u, err := url.Parse(c.Profiling.ServerAddress); err != nil || u.Host == "" {
+			errs = append(errs, fmt.Errorf("profiling.server_address %q is not an absolute URL", c.Profiling.ServerAddress))
+		}
+	}
+	if v := c.Profiling.UploadInterval; v != "" {
+		if d, err := time.ParseDuration(v); err != nil || d <= 0 {
+			errs = append(errs, fmt.Errorf("profiling.upload_interval %q is not a positive duration", v))
+		}
+	}
+	if c.Shadow.URL != "" {
+		if u, err := url.Parse(c.Shadow.URL); err != nil || u.Host == "" {
+			errs = append(errs, fmt.Errorf("shadow.url %q is not an absolute URL", c.Shadow.URL))
+		}
+	}
+	if c.Shadow.Percent < 0 || c.Shadow.Percent > 100 {
+		errs = append(errs, fmt.Errorf("shadow.percent must be between 0 and 100, got %v", c.Shadow.Percent))
+	}
+	if (c.Canary.Baseline == "") != (c.Canary.Canary == "") {
+		errs = append(errs, errors.New("canary.baseline and canary.canary must be set together"))
+	} else if c.Canary.Baseline != "" && c.Canary.Baseline == c.Canary.Canary {
+		errs = append(errs, errors.New("canary.baseline and canary.canary must differ"))
+	}
+	arg := telemetry.DefaultSamplerArg(c.Sampler.Type)
+	if c.Sampler.Arg != nil {
+		arg = *c.Sampler.Arg
+	}
+	if err := telemetry.ValidateSampler(c.Sampler.Type, arg); err != nil {
+		errs = append(errs, fmt.Errorf("sampler: %w", err))
+	}
+	if c.Sampler.ReportInterval != "" {
+		if d, err := time.ParseDuration(c.Sampler.ReportInterval); err != nil || d <= 0 {
+			errs = append(errs, fmt.Errorf("sampler.report_interval %q is not a positive duration", c.Sampler.ReportInterval))
+		}
+	}
+	if c.TraceURL != "" {
+		if err := telemetry.ValidateTraceURL(c.TraceURL); err != nil {
+			errs = append(errs, fmt.Errorf("trace_url: %w", err))
+		}
+	}
+	if err := telemetry.ValidateSpanFilter(c.DropSpanTargets); err != nil {
+		errs = append(errs, fmt.Errorf("drop_span_targets: %w", err))
+	}
+	errs = append(errs, c.Batch.validate()...)
+	if c.DebugSpans < 0 {
+		errs = append(errs, fmt.Errorf("debug_spans %d is negative", c.DebugSpans))
+	}
+	if c.HeartbeatInterval != "" {
+		if d, err := time.ParseDuration(c.HeartbeatInterval); err != nil || d <= 0 {
+			errs = append(errs, fmt.Errorf("heartbeat_interval %q is not a positive duration", c.HeartbeatInterval))
+		}
+	}
+	switch c.Metrics.ExemplarFilter {
+	case "", "trace_based", "always_on", "always_off":
+	default:
+		errs = append(errs, fmt.Errorf("metrics.exemplar_filter %q is not one of trace_based, always_on, always_off", c.Metrics.ExemplarFilter))
+	}
+	if c.Store != "" {
+		if u, err := url.Parse(c.Store); err != nil || !slices.Contains(store.Schemes, u.Scheme) {
+			errs = append(errs, fmt.Errorf("store %q is not a DSN starting with one of %v", c.Store, store.Schemes))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// validate reports the invalid settings of e, named after field.
+func (e Exporter) validate(field string) []error {
+	var errs []error
+	switch telemetry.Backend(e.Type) {
+	case "", telemetry.Jaeger, telemetry.NewRelic, telemetry.OpsRamp, telemetry.Stdout, telemetry.File:
+	default:
+		errs = append(errs, fmt.Errorf("%s.type %q is not one of jaeger, newrelic, opsramp, stdout, file", field, e.Type))
+	}
+	if e.Endpoint != "" {
+		if u, err := url.Parse(e.Endpoint); err != nil || u.Host == "" {
+			errs = append(errs, fmt.Errorf("%s.endpoint %q is not an absolute URL", field, e.Endpoint))
+		}
+	}
+	for i, endpoint := range e.FailoverEndpoints {
+		if u, err := url.Parse(endpoint); err != nil || u.Host == "" {
+			errs = append(errs, fmt.Errorf("%s.failover_endpoints[%d] %q is not an absolute URL", field, i, endpoint))
+		} else if e.TLS != (TLS{}) && u.Scheme == "http" {
+			errs = append(errs, fmt.Errorf("%s.tls needs https failover endpoints, got %s", field, endpoint))
+		}
+	}
+	if e.LicenseKey != "" {
+		if err := telemetry.ValidateLicenseKey(e.LicenseKey); err != nil {
+			errs = append(errs, fmt.Errorf("%s.license_key: %w", field, err))
+		}
+	}
+	if e.OpsRamp.TokenURL != "" {
+		if u, err := url.Parse(e.OpsRamp.TokenURL); err != nil || u.Host == "" {
+			errs = append(errs, fmt.Errorf("%s.opsramp.token_url %q is not an absolute URL", field, e.OpsRamp.TokenURL))
+		}
+	}
+	for name, v := range map[string]string{"time": e.Keepalive.Time, "timeout": e.Keepalive.Timeout} {
+		if v == "" {
+			continue
+		}
+		if d, err := time.ParseDuration(v); err != nil || d <= 0 {
+			errs = append(errs, fmt.Errorf("%s.keepalive.%s %q is not a positive duration", field, name, v))
+		}
+	}
+	for name, v := range map[string]string{
+		"initial_interval": e.Retry.InitialInterval,
+		"max_interval":     e.Retry.MaxInterval,
+		"max_elapsed_time": e.Retry.MaxElapsedTime,
+	} {
+		if v == "" {
+			continue
+		}
+		if d, err := time.ParseDuration(v); err != nil || d <= 0 {
+			errs = append(errs, fmt.Errorf("%s.retry.%s %q is not a positive duration", field, name, v))
+		}
+	}
+	if !slices.Contains(telemetry.Compressions, e.Compression) {
+		errs = append(errs, fmt.Errorf("%s.compression %q is not one of gzip, none", field, e.Compression))
+	}
+	if (e.TLS.CertFile == "") != (e.TLS.KeyFile == "") {
+		errs = append(errs, fmt.Errorf("%s.tls.cert_file and %s.tls.key_file must be set together", field, field))
+	}
+	if e.TLS != (TLS{}) && strings.HasPrefix(e.Endpoint, "http://") {
+		errs = append(errs, fmt.Errorf("%s.tls needs an https endpoint, got %s", field, e.Endpoint))
+	}
+	if e.Spool.MaxSizeMB < 0 {
+		errs = append(errs, fmt.Errorf("%s.spool.max_size_mb %d is negative", field, e.Spool.MaxSizeMB))
+	}
+	if e.File.MaxSizeMB < 0 {
+		errs = append(errs, fmt.Errorf("%s.file.max_size_mb %d is negative", field, e.File.MaxSizeMB))
+	}
+	if e.OpsRamp.ResourceUUID != "" {
+		if err := telemetry.ValidateUUID(e.OpsRamp.ResourceUUID); err != nil {
+			errs = append(errs, fmt.Errorf("%s.opsramp.resource_uuid: %w", field, err))
+		}
+	}
+	return errs
+}
+
+func (b Batch) validate() []error {
+	var errs []error
+	if b.MaxExportBatchSize < 0 {
+		errs = append(errs, fmt.Errorf("batch.max_export_batch_size %d is negative", b.MaxExportBatchSize))
+	}
+	if b.MaxQueueSize < 0 {
+		errs = append(errs, fmt.Errorf("batch.max_queue_size %d is negative", b.MaxQueueSize))
+	}
+	if b.MaxExportBatchSize > 0 && b.MaxQueueSize > 0 && b.MaxExportBatchSize > b.MaxQueueSize {
+		errs = append(errs, fmt.Errorf("batch.max_export_batch_size %d exceeds batch.max_queue_size %d", b.MaxExportBatchSize, b.MaxQueueSize))
+	}
+	for name, v := range map[string]string{"schedule_delay": b.ScheduleDelay, "export_timeout": b.ExportTimeout} {
+		if v == "" {
+			continue
+		}
+		if d, err := time.ParseDuration(v); err != nil || d <= 0 {
+			errs = append(errs, fmt.Errorf("batch.%s %q is not a positive duration", name, v))
+		}
+	}
+	return errs
+}
+
+// Hash identifies the configuration, e.g. to tell instances running with
+// different settings apart. Equal configurations have equal hashes.
+func (c Config) Hash() string {
+	data, _ := json.Marshal(c)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8])
+}
+
+// Masker compiles the masking rules. c must be valid.
+func (c Config) Masker() *masking.Masker {
+	m, _ := masking.New(c.Masking.Rules, c.Masking.NoDefaults)
+	return m
+}
+
+// Profiler returns the profiler configuration. c must be valid.
+func (c Config) Profiler() profiling.Config {
+	interval, _ := time.ParseDuration(c.Profiling.UploadInterval)
+	return profiling.Config{
+		ServerAddress:  c.Profiling.ServerAddress,
+		TenantID:       c.Profiling.TenantID,
+		UploadInterval: interval,
+	}
+}
+
+// Telemetry returns the tracer provider configuration. c must be valid.
+func (c Config) Telemetry() telemetry.Config {
+	var reportInterval time.Duration
+	if c.Sampler.ReportInterval != "" {
+		reportInterval, _ = time.ParseDuration(c.Sampler.ReportInterval)
+	}
+	var exporters []telemetry.Exporter
+	for _, e := range c.Exporters {
+		exporters = append(exporters, e.telemetry())
+	}
+	return telemetry.Config{
+		ServiceName:    c.ServiceName,
+		ServiceVersion: c.ServiceVersion,
+		Exporter:       c.Exporter.telemetry(),
+		Exporters:      exporters,
+		Sampler:        c.Sampler.Type,
+		SamplerArg:     c.Sampler.Arg,
+		Propagators:    c.Propagators,
+		ExemplarFilter: c.Metrics.ExemplarFilter,
+		XRay:           c.AWSXRay,
+
+		SamplingReportInterval: reportInterval,
+		DropSpanTargets:        c.DropSpanTargets,
+		SanitizeURLs:           c.SanitizeURLs,
+		TraceURL:               c.TraceURL,
+		Batch:                  c.Batch.telemetry(),
+		SyncExport:             c.SyncExport,
+	}
+}
+
+func (e Exporter) telemetry() telemetry.Exporter {
+	return telemetry.Exporter{
+		Backend:    telemetry.Backend(e.Type),
+		Endpoint:   e.Endpoint,
+		LicenseKey: e.LicenseKey,
+		OpsRamp:    telemetry.OpsRampConfig(e.OpsRamp),
+		File:       telemetry.FileConfig{Path: e.File.Path, MaxSize: e.File.MaxSizeMB << 20},
+		Keepalive:  e.Keepalive.telemetry(),
+		TLS:        telemetry.TLSConfig{CertFile: e.TLS.CertFile, KeyFile: e.TLS.KeyFile, CAFile: e.TLS.CAFile},
+
+		Retry:             e.Retry.telemetry(),
+		FailoverEndpoints: e.FailoverEndpoints,
+		Spool:             telemetry.SpoolConfig{Dir: e.Spool.Dir, MaxSize: e.Spool.MaxSizeMB << 20},
+		Compression:       e.Compression,
+	}
+}
+
+func (r Retry) telemetry() telemetry.RetryPolicy {
+	initial, _ := time.ParseDuration(r.InitialInterval)
+	maxInterval, _ := time.ParseDuration(r.MaxInterval)
+	maxElapsed, _ := time.ParseDuration(r.MaxElapsedTime)
+	return telemetry.RetryPolicy{
+		Disabled:        r.Disabled,
+		InitialInterval: initial,
+		MaxInterval:     maxInterval,
+		MaxElapsedTime:  maxElapsed,
+	}
+}
+
+func (b Batch) telemetry() telemetry.BatchConfig {
+	delay, _ := time.ParseDuration(b.ScheduleDelay)
+	timeout, _ := time.ParseDuration(b.ExportTimeout)
+	return telemetry.BatchConfig{
+		MaxExportBatchSize: b.MaxExportBatchSize,
+		MaxQueueSize:       b.MaxQueueSize,
+		ScheduleDelay:      delay,
+		ExportTimeout:      timeout,
+	}
+}
+
+func (k Keepalive) telemetry() telemetry.KeepaliveConfig {
+	t, _ := time.ParseDuration(k.Time)
+	timeout, _ := time.ParseDuration(k.Timeout)
+	return telemetry.KeepaliveConfig{Time: t, Timeout: timeout, PermitWithoutStream: k.PermitWithoutStream}
+}
@@ -0,0 +1,68 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+)
+
+// Parse builds the configuration of a service from the command line: the file
+// named by -config is loaded on top of defaults, then the individual flags
+// are applied on top of that and the result is validated. -help prints the
+// usage of every flag.
+func Parse(defaults Config) (Config, error) {
+	return parse(flag.CommandLine, os.Args[1:], defaults)
+}
+
+func parse(fs *flag.FlagSet, args []string, defaults Config) (Config, error) {
+	path := fs.String("config", "", "path to a YAML or JSON config file")
+	provider := fs.String("provider", "", "tracing backend: jaeger, newrelic, opsramp, stdout or file")
+	endpoint := fs.String("otlp-endpoint", "", "OTLP endpoint URL, e.g. http://localhost:4317")
+	listen := fs.String("listen", "", "address to listen on, e.g. 127.0.0.1:5001, :0 for any free port")
+	port := fs.Int("port", 0, "port to listen on, replacing the one of -listen")
+	downstream := fs.String("downstream-url", "", "URL of the downstream service")
+	downstreamService := fs.String("downstream-service", "", "name of the downstream service, recorded as peer.service")
+	syncExport := fs.Bool("sync-export", false, "export each span as it ends, for local debugging")
+	adminListen := fs.String("admin-listen", "", "address serving the pprof profiles and the /admin routes, e.g. localhost:6060")
+	if err := fs.Parse(args); err != nil {
+		return Config{}, err
+	}
+	if fs.NArg() > 0 {
+		return Config{}, fmt.Errorf("unexpected arguments %q, see -help", fs.Args())
+	}
+
+	// DOWNSTREAM_URL and DOWNSTREAM_SERVICE override the file, and only
+	// flags given explicitly override both.
+	overrides := func(cfg *Config) {
+		if v := os.Getenv("DOWNSTREAM_URL"); v != "" {
+			cfg.DownstreamURL = v
+		}
+		if v := os.Getenv("DOWNSTREAM_SERVICE"); v != "" {
+			cfg.DownstreamService = v
+		}
+		fs.Visit(func(f *flag.Flag) {
+			switch f.Name {
+			case "provider":
+				cfg.Exporter.Type = *provider
+			case "otlp-endpoint":
+				cfg.Exporter.Endpoint = *endpoint
+			case "listen":
+				cfg.Listen = *listen
+			case "port":
+				host, _, _ := net.SplitHostPort(cfg.Listen)
+				cfg.Listen = net.JoinHostPort(host, strconv.Itoa(*port))
+			case "downstream-url":
+				cfg.DownstreamURL = *downstream
+			case "downstream-service":
+				cfg.DownstreamService = *downstreamService
+			case "sync-export":
+				cfg.SyncExport = *syncExport
+			case "admin-listen":
+				cfg.AdminListen = *adminListen
+			}
+		})
+	}
+	return load(&source{path: *path, defaults: defaults, overrides: overrides})
+}
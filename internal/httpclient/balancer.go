@@ -0,0 +1,184 @@
+package httpclient
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"sort"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Strategy names how a Balancer picks the instance of a request.
+type Strategy string
+
+const (
+	// RoundRobin picks the instances in turn.
+	RoundRobin Strategy = "round_robin"
+	// LeastLoaded picks the instance with the fewest requests in flight.
+	LeastLoaded Strategy = "least_loaded"
+	// ConsistentHash sends the requests of a user to the same instance, as
+	// long as the instances do not change, see WithHashKey. Requests without
+	// a key are balanced round-robin.
+	ConsistentHash Strategy = "consistent_hash"
+)
+
+// Strategies lists the valid strategies.
+var Strategies = []Strategy{RoundRobin, LeastLoaded, ConsistentHash}
+
+// Attributes recorded on the client spans of balanced requests.
+const (
+	LBInstanceKey = attribute.Key("lb.instance")
+	LBStrategyKey = attribute.Key("lb.strategy")
+)
+
+// ringReplicas is the number of points of an instance on the hash ring, to
+// spread the keys evenly.
+const ringReplicas = 100
+
+// Balancer spreads the requests to one logical downstream over its
+// instances, e.g. the replicas started by cmd/cluster. Requests to other
+// hosts are left alone.
+type Balancer struct {
+	host      string
+	strategy  Strategy
+	instances []*instance
+	ring      []ringPoint
+	next      atomic.Uint64
+	latency   metric.Float64Histogram
+}
+
+type instance struct {
+	url      *url.URL
+	inflight atomic.Int64
+}
+
+type ringPoint struct {
+	hash     uint32
+	instance *instance
+}
+
+// NewBalancer returns a Balancer for the requests to the host of target,
+// sent to the scheme and host of instances instead.
+func NewBalancer(target string, instances []string, strategy Strategy) (*Balancer, error) {
+	u, err := url.Parse(target)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("balanced target %q is not an absolute URL", target)
+	}
+	if strategy == "" {
+		strategy = RoundRobin
+	}
+	if !slices.Contains(Strategies, strategy) {
+		return nil, fmt.Errorf("unknown load balancing strategy %q", strategy)
+	}
+	if len(instances) == 0 {
+		return nil, fmt.Errorf("no instances to balance %s over", u.Host)
+	}
+
+	b := &Balancer{host: u.Host, strategy: strategy}
+	for _, raw := range instances {
+		iu, err := url.Parse(raw)
+		if err != nil || iu.Host == "" {
+			return nil, fmt.Errorf("instance %q is not an absolute URL", raw)
+		}
+		inst := &instance{url: iu}
+		b.instances = append(b.instances, inst)
+		for i := 0; i < ringReplicas; i++ {
+			b.ring = append(b.ring, ringPoint{hash: hash(iu.Host + "#" + strconv.Itoa(i)), instance: inst})
+		}
+	}
+	sort.Slice(b.ring, func(i, j int) bool { return b.ring[i].hash < b.ring[j].hash })
+
+	if b.latency, err = otel.Meter(instrumentationName).Float64Histogram("http.client.instance.duration",
+		metric.WithDescription("Duration of the balanced requests per instance"),
+		metric.WithUnit("ms")); err != nil {
+		log.Printf("failed to create http.client.instance.duration histogram: %v", err)
+	}
+	return b, nil
+}
+
+// WithBalancer balances the requests of the client with b.
+func WithBalancer(b *Balancer) Option {
+	return func(t *transport) { t.balancer = b }
+}
+
+type hashKey struct{}
+
+// WithHashKey sets the key, e.g. a user ID, the requests made with ctx are
+// balanced by under ConsistentHash. Without one, the user.id baggage member
+// is used when present.
+func WithHashKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, hashKey{}, key)
+}
+
+func hashKeyFrom(ctx context.Context) string {
+	if key, ok := ctx.Value(hashKey{}).(string); ok {
+		return key
+	}
+	return baggage.FromContext(ctx).Member("user.id").Value()
+}
+
+// roundTrip sends req to the instance picked for it, recording the instance
+// on span and the latency per instance. req is a copy owned by the transport.
+// A nil Balancer sends req as is.
+func (b *Balancer) roundTrip(base http.RoundTripper, req *http.Request, span trace.Span) (*http.Response, error) {
+	if b == nil || req.URL.Host != b.host {
+		return base.RoundTrip(req)
+	}
+	inst := b.pick(req.Context())
+	req.URL.Scheme = inst.url.Scheme
+	req.URL.Host = inst.url.Host
+	req.Host = ""
+	attrs := []attribute.KeyValue{LBInstanceKey.String(inst.url.Host), LBStrategyKey.String(string(b.strategy))}
+	span.SetAttributes(attrs...)
+
+	inst.inflight.Add(1)
+	defer inst.inflight.Add(-1)
+	start := time.Now()
+	resp, err := base.RoundTrip(req)
+	if b.latency != nil {
+		b.latency.Record(req.Context(), float64(time.Since(start))/float64(time.Millisecond),
+			metric.WithAttributes(append(attrs, attribute.Bool("error", err != nil))...))
+	}
+	return resp, err
+}
+
+func (b *Balancer) pick(ctx context.Context) *instance {
+	switch b.strategy {
+	case LeastLoaded:
+		// Ties go round-robin, so idle instances all get traffic
+		start := int(b.next.Add(1) % uint64(len(b.instances)))
+		best := b.instances[start]
+		for i := 1; i < len(b.instances); i++ {
+			inst := b.instances[(start+i)%len(b.instances)]
+			if inst.inflight.Load() < best.inflight.Load() {
+				best = inst
+			}
+		}
+		return best
+	case ConsistentHash:
+		if key := hashKeyFrom(ctx); key != "" {
+			h := hash(key)
+			i := sort.Search(len(b.ring), func(i int) bool { return b.ring[i].hash >= h })
+			return b.ring[i%len(b.ring)].instance
+		}
+	}
+	return b.instances[(b.next.Add(1)-1)%uint64(len(b.instances))]
+}
+
+func hash(s string) uint32 {
+	h := fnv.New32a()
+	h.Write([]byte(s))
+	return h.Sum32()
+}
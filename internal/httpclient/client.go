@@ -0,0 +1,112 @@
+// Package httpclient provides the HTTP client the services use for their
+// downstream calls.
+package httpclient
+
+import (
+	"net"
+	"net/http"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"test-jaeger/internal/telemetry"
+)
+
+// Timeouts and pooling of the clients. Timeout bounds a whole call, reading
+// the body included; a call whose context is cancelled ends sooner. The
+// default transport keeps only 2 idle connections per host, too few for a
+// service calling the same downstream under load.
+const (
+	DialTimeout           = 5 * time.Second
+	TLSHandshakeTimeout   = 5 * time.Second
+	ResponseHeaderTimeout = 10 * time.Second
+	Timeout               = 30 * time.Second
+	MaxIdleConnsPerHost   = 32
+	IdleConnTimeout       = 90 * time.Second
+)
+
+// New returns a client whose transport starts a CLIENT span for every request,
+// injects its context with the global propagator and records the response
+// status. Network timings are recorded on that span, hosts are resolved
+// through an in-process DNS cache and connection pool metrics, connection
+// reuse included, are exported. Requests are subject to the egress
+// allowlist, see SetEgressAllowlist, and to the timeouts above.
+func New(opts ...Option) *http.Client {
+	base := newBaseTransport()
+	base.DialContext = newCachingResolver(DNSCacheTTL).dialer(base.DialContext)
+	t := &transport{
+		base:   newEgressTransport(base),
+		pool:   newPoolStats(base),
+		tracer: otel.Tracer(instrumentationName),
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return &http.Client{Transport: t, Timeout: Timeout}
+}
+
+// NewUntraced returns a client with the timeouts and pooling of New but no
+// spans, metrics or propagation, for tools that trace their calls
+// themselves or must not, e.g. querying the tracing backend.
+func NewUntraced() *http.Client {
+	return &http.Client{Transport: newBaseTransport(), Timeout: Timeout}
+}
+
+func newBaseTransport() *http.Transport {
+	base := http.DefaultTransport.(*http.Transport).Clone()
+	base.DialContext = (&net.Dialer{Timeout: DialTimeout, KeepAlive: 30 * time.Second}).DialContext
+	base.TLSHandshakeTimeout = TLSHandshakeTimeout
+	base.ResponseHeaderTimeout = ResponseHeaderTimeout
+	base.MaxIdleConnsPerHost = MaxIdleConnsPerHost
+	base.IdleConnTimeout = IdleConnTimeout
+	return base
+}
+
+// Option configures the client returned by New.
+type Option func(*transport)
+
+type transport struct {
+	base        http.RoundTripper
+	pool        *poolStats
+	tracer      trace.Tracer
+	balancer    *Balancer
+	peerService string
+}
+
+// WithPeerService records name as the peer.service of the calls, the
+// service the client talks to. An empty name records none.
+func WithPeerService(name string) Option {
+	return func(t *transport) { t.peerService = name }
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	attrs := telemetry.HTTPClientAttributes(req)
+	if t.peerService != "" {
+		attrs = append(attrs, semconv.PeerService(t.peerService))
+	}
+	ctx, span := t.tracer.Start(req.Context(), telemetry.ClientSpanName(req.Method, req.URL.Host),
+		trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+	defer span.End()
+
+	// RoundTrip must not modify the caller's request, so inject into a copy.
+	req = req.Clone(ctx)
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	req, l := t.pool.track(req)
+	resp, err := l.release(t.balancer.roundTrip(t.base, withNetworkTrace(req, t.tracer), span))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+	span.SetAttributes(semconv.HTTPResponseStatusCode(resp.StatusCode))
+	if version := resp.Header.Get(telemetry.VersionHeader); version != "" {
+		span.SetAttributes(telemetry.PeerVersionKey.String(version))
+	}
+	span.SetStatus(telemetry.HTTPSpanStatus(resp.StatusCode, trace.SpanKindClient))
+	return resp, nil
+}
@@ -0,0 +1,94 @@
+package httpclient
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+	"sync/atomic"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ErrEgressBlocked is returned for the requests to hosts outside of the
+// egress allowlist.
+var ErrEgressBlocked = errors.New("egress blocked")
+
+// EgressHostKey names the host of a blocked request.
+const EgressHostKey = attribute.Key("egress.host")
+
+// allowlist is the egress policy of every client, nil to allow any host.
+var allowlist atomic.Pointer[[]string]
+
+// SetEgressAllowlist restricts the requests of every client to hosts. An
+// entry is a host, matching any port, a host:port, or a domain wildcard like
+// *.example.com. An empty list lifts the restriction.
+func SetEgressAllowlist(hosts []string) {
+	if len(hosts) == 0 {
+		allowlist.Store(nil)
+		return
+	}
+	allowlist.Store(&hosts)
+}
+
+// egressAllowed reports whether the policy lets a request go to hostport.
+func egressAllowed(hostport string) bool {
+	hosts := allowlist.Load()
+	if hosts == nil {
+		return true
+	}
+	host := hostport
+	if h, _, err := net.SplitHostPort(hostport); err == nil {
+		host = h
+	}
+	for _, entry := range *hosts {
+		switch {
+		case strings.HasPrefix(entry, "*."):
+			if strings.HasSuffix(host, entry[1:]) {
+				return true
+			}
+		case entry == host, entry == hostport:
+			return true
+		}
+	}
+	return false
+}
+
+// egressTransport enforces the egress allowlist on the requests actually
+// sent, i.e. after load balancing. Blocked requests are logged, counted as
+// http.client.egress.blocked and recorded as an egress.blocked event of the
+// client span.
+type egressTransport struct {
+	next    http.RoundTripper
+	blocked metric.Int64Counter
+}
+
+func newEgressTransport(next http.RoundTripper) *egressTransport {
+	t := &egressTransport{next: next}
+	var err error
+	if t.blocked, err = otel.Meter(instrumentationName).Int64Counter("http.client.egress.blocked",
+		metric.WithDescription("Number of requests blocked by the egress allowlist")); err != nil {
+		log.Printf("failed to create http.client.egress.blocked counter: %v", err)
+	}
+	return t
+}
+
+func (t *egressTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if egressAllowed(req.URL.Host) {
+		return t.next.RoundTrip(req)
+	}
+	ctx := req.Context()
+	host := EgressHostKey.String(req.URL.Host)
+	trace.SpanFromContext(ctx).AddEvent("egress.blocked", trace.WithAttributes(host))
+	if t.blocked != nil {
+		t.blocked.Add(ctx, 1, metric.WithAttributes(host))
+	}
+	slog.WarnContext(ctx, "blocked request outside of the egress allowlist", "method", req.Method, "host", req.URL.Host)
+	return nil, fmt.Errorf("%w: %s is not allowed", ErrEgressBlocked, req.URL.Host)
+}
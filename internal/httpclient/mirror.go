@@ -0,0 +1,83 @@
+package httpclient
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"test-jaeger/internal/telemetry"
+)
+
+// Mirror copies a share of outbound requests to a shadow instance, e.g. a new
+// version under test. Shadow responses are discarded and the shadow requests
+// are marked as shadow traffic, so their spans carry traffic.shadow=true in
+// both services.
+type Mirror struct {
+	client  *http.Client
+	target  *url.URL
+	percent float64
+	tracer  trace.Tracer
+}
+
+// NewMirror returns a Mirror sending percent (0-100) of requests to the
+// scheme and host of target through client.
+func NewMirror(client *http.Client, target string, percent float64) (*Mirror, error) {
+	u, err := url.Parse(target)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("shadow target %q is not an absolute URL", target)
+	}
+	if percent < 0 || percent > 100 {
+		return nil, fmt.Errorf("shadow percent must be between 0 and 100, got %v", percent)
+	}
+	return &Mirror{client: client, target: u, percent: percent, tracer: otel.Tracer(instrumentationName)}, nil
+}
+
+// Send mirrors req in the background when it is picked for shadowing. It must
+// be called before req is sent, and only requests whose body can be replayed
+// are mirrored. A nil Mirror does nothing.
+func (m *Mirror) Send(req *http.Request) {
+	if m == nil || rand.Float64()*100 >= m.percent {
+		return
+	}
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return
+	}
+
+	// The shadow call stays in the caller's trace but must not be cancelled
+	// together with the primary request.
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.SpanContextFromContext(req.Context()))
+	ctx = telemetry.WithShadow(ctx)
+	ctx, span := m.tracer.Start(ctx, "shadow "+req.Method)
+
+	shadow := req.Clone(ctx)
+	shadow.URL.Scheme = m.target.Scheme
+	shadow.URL.Host = m.target.Host
+	shadow.Host = ""
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			span.End()
+			return
+		}
+		shadow.Body = body
+	}
+
+	go func() {
+		defer span.End()
+		resp, err := m.client.Do(shadow)
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			return
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}()
+}
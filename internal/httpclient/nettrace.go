@@ -0,0 +1,162 @@
+package httpclient
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+	"net/http/httptrace"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"test-jaeger/internal/telemetry"
+)
+
+// Attributes describing where the time of an outbound request went. Durations
+// are in milliseconds, measured from the start of the phase.
+const (
+	DNSDurationKey     = attribute.Key("http.client.dns.duration_ms")
+	ConnectDurationKey = attribute.Key("http.client.connect.duration_ms")
+	TLSDurationKey     = attribute.Key("http.client.tls.duration_ms")
+	TTFBKey            = attribute.Key("http.client.ttfb_ms")
+	// ServerWaitKey is the time from the request written to the first byte
+	// of the response, the time the server took, network latency included.
+	ServerWaitKey = attribute.Key("http.client.server_wait_ms")
+	ConnReusedKey = attribute.Key("http.client.connection.reused")
+)
+
+// withNetworkTrace attaches httptrace hooks to req that record DNS lookup, TCP
+// connect, TLS handshake, request written and time to first byte on the span
+// in its context. The connects and TLS handshakes are child spans of their
+// own, named connect and tls.handshake.
+func withNetworkTrace(req *http.Request, tracer trace.Tracer) *http.Request {
+	ctx := req.Context()
+	span := trace.SpanFromContext(ctx)
+	if !span.IsRecording() {
+		return req
+	}
+	nt := &netTrace{ctx: ctx, tracer: tracer, span: span, start: time.Now(), connects: map[string]phase{}}
+	return req.WithContext(httptrace.WithClientTrace(ctx, nt.clientTrace()))
+}
+
+// phase is a connect or TLS handshake in progress.
+type phase struct {
+	start time.Time
+	span  trace.Span
+}
+
+// netTrace collects the timings of one request. Dials may run concurrently
+// (happy eyeballs), so all state is guarded by mu.
+type netTrace struct {
+	ctx    context.Context
+	tracer trace.Tracer
+	span   trace.Span
+	start  time.Time
+
+	mu       sync.Mutex
+	dnsStart time.Time
+	tls      phase
+	wrote    time.Time
+	connects map[string]phase
+}
+
+func sinceMillis(t time.Time) float64 {
+	return float64(time.Since(t)) / float64(time.Millisecond)
+}
+
+func (nt *netTrace) clientTrace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			nt.span.SetAttributes(ConnReusedKey.Bool(info.Reused))
+		},
+		DNSStart: func(info httptrace.DNSStartInfo) {
+			nt.mu.Lock()
+			nt.dnsStart = time.Now()
+			nt.mu.Unlock()
+			nt.span.AddEvent("dns.start", trace.WithAttributes(semconv.ServerAddress(info.Host)))
+		},
+		DNSDone: func(info httptrace.DNSDoneInfo) {
+			nt.mu.Lock()
+			d := sinceMillis(nt.dnsStart)
+			nt.mu.Unlock()
+			addrs := make([]string, 0, len(info.Addrs))
+			for _, a := range info.Addrs {
+				addrs = append(addrs, a.String())
+			}
+			nt.span.SetAttributes(DNSDurationKey.Float64(d))
+			nt.span.AddEvent("dns.done", trace.WithAttributes(attribute.StringSlice("net.dns.addresses", addrs)))
+			if info.Err != nil {
+				nt.span.RecordError(info.Err)
+			}
+		},
+		ConnectStart: func(network, addr string) {
+			_, span := nt.tracer.Start(nt.ctx, "connect", trace.WithAttributes(append(telemetry.PeerAttributes(addr),
+				semconv.NetworkTransportKey.String(strings.TrimRight(network, "46")))...))
+			nt.mu.Lock()
+			nt.connects[addr] = phase{start: time.Now(), span: span}
+			nt.mu.Unlock()
+		},
+		ConnectDone: func(network, addr string, err error) {
+			nt.mu.Lock()
+			p := nt.connects[addr]
+			delete(nt.connects, addr)
+			nt.mu.Unlock()
+			if p.span == nil {
+				return
+			}
+			if err != nil {
+				p.span.RecordError(err)
+				p.span.SetStatus(codes.Error, err.Error())
+			} else {
+				nt.span.SetAttributes(ConnectDurationKey.Float64(sinceMillis(p.start)))
+			}
+			p.span.End()
+		},
+		TLSHandshakeStart: func() {
+			_, span := nt.tracer.Start(nt.ctx, "tls.handshake")
+			nt.mu.Lock()
+			nt.tls = phase{start: time.Now(), span: span}
+			nt.mu.Unlock()
+		},
+		TLSHandshakeDone: func(state tls.ConnectionState, err error) {
+			nt.mu.Lock()
+			p := nt.tls
+			nt.mu.Unlock()
+			if p.span == nil {
+				return
+			}
+			if err != nil {
+				p.span.RecordError(err)
+				p.span.SetStatus(codes.Error, err.Error())
+			} else {
+				p.span.SetAttributes(attribute.String("tls.version", tls.VersionName(state.Version)))
+				nt.span.SetAttributes(TLSDurationKey.Float64(sinceMillis(p.start)))
+			}
+			p.span.End()
+		},
+		WroteRequest: func(info httptrace.WroteRequestInfo) {
+			nt.mu.Lock()
+			nt.wrote = time.Now()
+			nt.mu.Unlock()
+			if info.Err != nil {
+				nt.span.RecordError(info.Err)
+			}
+			nt.span.AddEvent("request.written")
+		},
+		GotFirstResponseByte: func() {
+			nt.mu.Lock()
+			wrote := nt.wrote
+			nt.mu.Unlock()
+			nt.span.SetAttributes(TTFBKey.Float64(sinceMillis(nt.start)))
+			if !wrote.IsZero() {
+				nt.span.SetAttributes(ServerWaitKey.Float64(sinceMillis(wrote)))
+			}
+			nt.span.AddEvent("first_response_byte")
+		},
+	}
+}
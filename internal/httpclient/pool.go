@@ -0,0 +1,170 @@
+package httpclient
+
+import (
+	"context"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"sync/atomic"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/metric"
+
+	"test-jaeger/internal/telemetry"
+)
+
+const instrumentationName = "test-jaeger/internal/httpclient"
+
+// poolStats tracks the connections of one transport. A connection is open from
+// a successful dial until it is closed, and active while a request holds it
+// (from GotConn until the response body is closed). Idle is reported as open
+// minus active.
+type poolStats struct {
+	open     atomic.Int64
+	active   atomic.Int64
+	acquired atomic.Int64
+	reused   atomic.Int64
+
+	acquiredCounter metric.Int64Counter
+	dialFailures    metric.Int64Counter
+}
+
+// newPoolStats wraps the dialer of t and registers the pool instruments.
+func newPoolStats(t *http.Transport) *poolStats {
+	p := &poolStats{}
+	meter := otel.Meter(instrumentationName)
+
+	var err error
+	if p.acquiredCounter, err = meter.Int64Counter("http.client.connection.acquired",
+		metric.WithDescription("Connections handed to requests, by whether they were reused"),
+		metric.WithUnit("{connection}")); err != nil {
+		log.Printf("failed to create connection.acquired counter: %v", err)
+	}
+	if p.dialFailures, err = meter.Int64Counter("http.client.connection.dial_failures",
+		metric.WithDescription("Failed attempts to dial a downstream connection"),
+		metric.WithUnit("{connection}")); err != nil {
+		log.Printf("failed to create connection.dial_failures counter: %v", err)
+	}
+	if _, err = meter.Int64ObservableGauge("http.client.connection.active",
+		metric.WithDescription("Connections currently serving a request"),
+		metric.WithUnit("{connection}"),
+		metric.WithInt64Callback(func(_ context.Context, o metric.Int64Observer) error {
+			o.Observe(p.active.Load())
+			return nil
+		})); err != nil {
+		log.Printf("failed to create connection.active gauge: %v", err)
+	}
+	if _, err = meter.Int64ObservableGauge("http.client.connection.idle",
+		metric.WithDescription("Open connections waiting in the idle pool"),
+		metric.WithUnit("{connection}"),
+		metric.WithInt64Callback(func(_ context.Context, o metric.Int64Observer) error {
+			o.Observe(max(p.open.Load()-p.active.Load(), 0))
+			return nil
+		})); err != nil {
+		log.Printf("failed to create connection.idle gauge: %v", err)
+	}
+	if _, err = meter.Float64ObservableGauge("http.client.connection.reuse_ratio",
+		metric.WithDescription("Share of acquired connections that were reused from the pool"),
+		metric.WithFloat64Callback(func(_ context.Context, o metric.Float64Observer) error {
+			if acquired := p.acquired.Load(); acquired > 0 {
+				o.Observe(float64(p.reused.Load()) / float64(acquired))
+			}
+			return nil
+		})); err != nil {
+		log.Printf("failed to create connection.reuse_ratio gauge: %v", err)
+	}
+
+	dial := t.DialContext
+	if dial == nil {
+		dial = (&net.Dialer{}).DialContext
+	}
+	t.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := dial(ctx, network, addr)
+		if err != nil {
+			if p.dialFailures != nil {
+				p.dialFailures.Add(ctx, 1, metric.WithAttributes(telemetry.PeerAttributes(addr)...))
+			}
+			return nil, err
+		}
+		p.open.Add(1)
+		return &countedConn{Conn: conn, pool: p}, nil
+	}
+	return p
+}
+
+// track returns req with a trace hook that marks the connection it gets as
+// active. The returned lease must be released once the response is done.
+func (p *poolStats) track(req *http.Request) (*http.Request, *lease) {
+	l := &lease{pool: p}
+	ct := &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			l.held.Store(true)
+			p.active.Add(1)
+			p.acquired.Add(1)
+			if info.Reused {
+				p.reused.Add(1)
+			}
+			if p.acquiredCounter != nil {
+				p.acquiredCounter.Add(req.Context(), 1, metric.WithAttributes(ConnReusedKey.Bool(info.Reused)))
+			}
+		},
+	}
+	return req.WithContext(httptrace.WithClientTrace(req.Context(), ct)), l
+}
+
+// lease is a request's hold on a pooled connection.
+type lease struct {
+	pool *poolStats
+	held atomic.Bool
+	once sync.Once
+}
+
+func (l *lease) done() {
+	if l.held.Load() {
+		l.once.Do(func() { l.pool.active.Add(-1) })
+	}
+}
+
+// release ends the lease when the round trip failed, or defers it until the
+// response body is closed or fully read.
+func (l *lease) release(resp *http.Response, err error) (*http.Response, error) {
+	if err != nil || resp.StatusCode == http.StatusSwitchingProtocols {
+		l.done()
+		return resp, err
+	}
+	resp.Body = &leaseBody{ReadCloser: resp.Body, lease: l}
+	return resp, nil
+}
+
+type leaseBody struct {
+	io.ReadCloser
+	lease *lease
+}
+
+func (b *leaseBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err == io.EOF {
+		b.lease.done()
+	}
+	return n, err
+}
+
+func (b *leaseBody) Close() error {
+	b.lease.done()
+	return b.ReadCloser.Close()
+}
+
+// countedConn keeps the open connection count up to date.
+type countedConn struct {
+	net.Conn
+	pool *poolStats
+	once sync.Once
+}
+
+func (c *countedConn) Close() error {
+	c.once.Do(func() { c.pool.open.Add(-1) })
+	return c.Conn.Close()
+}
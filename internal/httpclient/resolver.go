@@ -0,0 +1,145 @@
+package httpclient
+
+import (
+	"context"
+	"log"
+	"net"
+	"net/http/httptrace"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// DNSCacheTTL is how long resolved addresses are reused. The docker-compose
+// service names rarely change, so a short TTL saves most lookups while still
+// picking up restarted containers.
+const DNSCacheTTL = 30 * time.Second
+
+type dnsEntry struct {
+	addrs   []string
+	expires time.Time
+}
+
+// cachingResolver caches host lookups in process. Cold lookups are traced and
+// timed; hits and misses are counted.
+type cachingResolver struct {
+	resolver *net.Resolver
+	ttl      time.Duration
+
+	mu      sync.Mutex
+	entries map[string]dnsEntry
+
+	tracer  trace.Tracer
+	hits    metric.Int64Counter
+	misses  metric.Int64Counter
+	latency metric.Float64Histogram
+}
+
+func newCachingResolver(ttl time.Duration) *cachingResolver {
+	r := &cachingResolver{
+		resolver: net.DefaultResolver,
+		ttl:      ttl,
+		entries:  map[string]dnsEntry{},
+		tracer:   otel.Tracer(instrumentationName),
+	}
+	meter := otel.Meter(instrumentationName)
+
+	var err error
+	if r.hits, err = meter.Int64Counter("dns.cache.hits",
+		metric.WithDescription("Host lookups answered from the DNS cache")); err != nil {
+		log.Printf("failed to create dns.cache.hits counter: %v", err)
+	}
+	if r.misses, err = meter.Int64Counter("dns.cache.misses",
+		metric.WithDescription("Host lookups that had to query DNS")); err != nil {
+		log.Printf("failed to create dns.cache.misses counter: %v", err)
+	}
+	if r.latency, err = meter.Float64Histogram("dns.lookup.duration",
+		metric.WithDescription("Duration of DNS lookups on cache misses"),
+		metric.WithUnit("ms")); err != nil {
+		log.Printf("failed to create dns.lookup.duration histogram: %v", err)
+	}
+	return r
+}
+
+// lookup returns the addresses of host, from the cache when possible.
+func (r *cachingResolver) lookup(ctx context.Context, host string) ([]string, error) {
+	attrs := metric.WithAttributes(semconv.ServerAddress(host))
+
+	r.mu.Lock()
+	entry, ok := r.entries[host]
+	r.mu.Unlock()
+	if ok && time.Now().Before(entry.expires) {
+		if r.hits != nil {
+			r.hits.Add(ctx, 1, attrs)
+		}
+		return entry.addrs, nil
+	}
+	if r.misses != nil {
+		r.misses.Add(ctx, 1, attrs)
+	}
+
+	ctx, span := r.tracer.Start(ctx, "dns.lookup",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(semconv.ServerAddress(host)))
+	defer span.End()
+
+	start := time.Now()
+	addrs, err := r.resolver.LookupHost(ctx, host)
+	if r.latency != nil {
+		r.latency.Record(ctx, float64(time.Since(start))/float64(time.Millisecond), attrs)
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "lookup failed")
+		return nil, err
+	}
+	span.SetAttributes(attribute.StringSlice("net.dns.addresses", addrs))
+
+	r.mu.Lock()
+	r.entries[host] = dnsEntry{addrs: addrs, expires: time.Now().Add(r.ttl)}
+	r.mu.Unlock()
+	return addrs, nil
+}
+
+// dialer returns a dial function that resolves host names through the cache
+// and dials the resulting addresses in order with dial.
+func (r *cachingResolver) dialer(dial func(ctx context.Context, network, addr string) (net.Conn, error)) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil || net.ParseIP(host) != nil {
+			return dial(ctx, network, addr)
+		}
+
+		// The dialer only sees IP addresses from here on, so report the
+		// lookup to any httptrace hooks ourselves.
+		ct := httptrace.ContextClientTrace(ctx)
+		if ct != nil && ct.DNSStart != nil {
+			ct.DNSStart(httptrace.DNSStartInfo{Host: host})
+		}
+		addrs, err := r.lookup(ctx, host)
+		if ct != nil && ct.DNSDone != nil {
+			info := httptrace.DNSDoneInfo{Err: err}
+			for _, a := range addrs {
+				info.Addrs = append(info.Addrs, net.IPAddr{IP: net.ParseIP(a)})
+			}
+			ct.DNSDone(info)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		for _, a := range addrs {
+			var conn net.Conn
+			if conn, err = dial(ctx, network, net.JoinHostPort(a, port)); err == nil {
+				return conn, nil
+			}
+		}
+		return nil, err
+	}
+}
@@ -0,0 +1,88 @@
+// Package interop defines the contract used to check that trace context
+// propagates between the Go services and peers built with other SDKs
+// (Python, Java, ...). A peer only has to expose Path and answer with a
+// Response describing the traceparent it received and the span it created.
+package interop
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	"test-jaeger/internal/telemetry"
+)
+
+// Path is the route every interop peer exposes.
+const Path = "/interop"
+
+// Response is the JSON document returned by an interop peer.
+type Response struct {
+	// Service and SDK identify the peer, e.g. "orders" and "python".
+	Service string `json:"service"`
+	SDK     string `json:"sdk"`
+	// Traceparent is the header exactly as the peer received it.
+	Traceparent string `json:"traceparent"`
+	// TraceID and SpanID belong to the server span the peer started.
+	TraceID string `json:"trace_id"`
+	SpanID  string `json:"span_id"`
+}
+
+var traceparentRE = regexp.MustCompile(`^00-([0-9a-f]{32})-([0-9a-f]{16})-[0-9a-f]{2}$`)
+
+// Handler is the Go reference implementation of the peer side of the contract.
+func Handler(service string) gin.HandlerFunc {
+	tracer := otel.GetTracerProvider().Tracer("interop")
+
+	return func(c *gin.Context) {
+		// Behind telemetry.TraceRequests the request already has its server span
+		span := trace.SpanFromContext(c.Request.Context())
+		if sc := span.SpanContext(); !sc.IsValid() || sc.IsRemote() {
+			ctx := otel.GetTextMapPropagator().Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+			c.Request = c.Request.WithContext(ctx)
+			_, span = telemetry.StartServerSpan(c, tracer)
+			defer telemetry.FinishSpan(c, span)
+		}
+
+		sc := span.SpanContext()
+		c.JSON(http.StatusOK, Response{
+			Service:     service,
+			SDK:         "go",
+			Traceparent: c.GetHeader("traceparent"),
+			TraceID:     sc.TraceID().String(),
+			SpanID:      sc.SpanID().String(),
+		})
+	}
+}
+
+// Verify checks that the peer continued the trace of the client span: the
+// traceparent it received is well formed and points at the client span, and
+// the peer's own span belongs to the same trace.
+func Verify(client trace.SpanContext, resp Response) error {
+	m := traceparentRE.FindStringSubmatch(resp.Traceparent)
+	if m == nil {
+		return fmt.Errorf("peer received malformed traceparent %q", resp.Traceparent)
+	}
+	if m[1] != client.TraceID().String() {
+		return fmt.Errorf("peer received trace id %s, want %s", m[1], client.TraceID())
+	}
+	if m[2] != client.SpanID().String() {
+		return fmt.Errorf("peer received parent span id %s, want %s", m[2], client.SpanID())
+	}
+	if resp.TraceID != client.TraceID().String() {
+		return fmt.Errorf("peer span has trace id %s, want %s", resp.TraceID, client.TraceID())
+	}
+	spanID, err := trace.SpanIDFromHex(resp.SpanID)
+	if err != nil {
+		return fmt.Errorf("peer span id %q: %w", resp.SpanID, err)
+	}
+	if spanID == client.SpanID() {
+		return errors.New("peer reused the client span id instead of starting a child span")
+	}
+	return nil
+}
@@ -0,0 +1,214 @@
+package interop_test
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+
+	"test-jaeger/internal/collectortest"
+	"test-jaeger/internal/interop"
+	"test-jaeger/internal/telemetry"
+)
+
+// foreignPeer stands for a peer built with another SDK: it reads the
+// traceparent by hand, answers the contract and exports its server span
+// straight to the collector as a Python service would.
+func foreignPeer(t *testing.T, collector *collectortest.Collector) http.Handler {
+	conn, err := grpc.NewClient(strings.TrimPrefix(collector.Endpoint, "http://"),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("dial collector: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	exporter := coltracepb.NewTraceServiceClient(conn)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent := r.Header.Get("traceparent")
+		parts := strings.Split(traceparent, "-")
+		if len(parts) != 4 {
+			http.Error(w, "missing traceparent", http.StatusBadRequest)
+			return
+		}
+		traceID, _ := hex.DecodeString(parts[1])
+		parentID, _ := hex.DecodeString(parts[2])
+		spanID := make([]byte, 8)
+		rand.Read(spanID)
+
+		_, err := exporter.Export(r.Context(), &coltracepb.ExportTraceServiceRequest{
+			ResourceSpans: []*tracepb.ResourceSpans{{
+				Resource: &resourcepb.Resource{Attributes: []*commonpb.KeyValue{
+					{Key: "service.name", Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: "orders"}}},
+					{Key: "telemetry.sdk.language", Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: "python"}}},
+				}},
+				ScopeSpans: []*tracepb.ScopeSpans{{Spans: []*tracepb.Span{{
+					TraceId:      traceID,
+					SpanId:       spanID,
+					ParentSpanId: parentID,
+					Name:         "GET " + interop.Path,
+					Kind:         tracepb.Span_SPAN_KIND_SERVER,
+				}}}},
+			}},
+		})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(interop.Response{
+			Service:     "orders",
+			SDK:         "python",
+			Traceparent: traceparent,
+			TraceID:     parts[1],
+			SpanID:      hex.EncodeToString(spanID),
+		})
+	})
+}
+
+// goPeer serves the reference Handler behind the middlewares of the
+// services.
+func goPeer() http.Handler {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(telemetry.ExtractContext(), telemetry.TraceRequests())
+	router.GET(interop.Path, interop.Handler("ServiceB"))
+	return router
+}
+
+func TestCrossSDKTrace(t *testing.T) {
+	tests := []struct {
+		name string
+		peer func(*testing.T, *collectortest.Collector) http.Handler
+		// services are the services the trace must hold spans of
+		services []string
+	}{
+		{"python", foreignPeer, []string{"InteropClient", "orders"}},
+		{"go", func(*testing.T, *collectortest.Collector) http.Handler { return goPeer() }, []string{"InteropClient"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			collector := collectortest.Start(t)
+			provider, err := telemetry.NewTracerProvider(context.Background(), telemetry.Config{
+				ServiceName: "InteropClient",
+				Exporter:    telemetry.Exporter{Endpoint: collector.Endpoint},
+				SyncExport:  true,
+			})
+			if err != nil {
+				t.Fatalf("NewTracerProvider: %v", err)
+			}
+			peer := httptest.NewServer(tt.peer(t, collector))
+			defer peer.Close()
+
+			client, resp := call(t, peer.URL+interop.Path)
+			telemetry.Shutdown(provider)
+			if err := interop.Verify(client, resp); err != nil {
+				t.Fatalf("Verify: %v", err)
+			}
+
+			spans := collector.Trace(client.TraceID())
+			if err := collectortest.WellFormed(spans); err != nil {
+				t.Fatalf("trace is not well formed: %v", err)
+			}
+			services := map[string]bool{}
+			var server *collectortest.Span
+			for i, s := range spans {
+				services[s.Service] = true
+				if hex.EncodeToString(s.GetSpanId()) == resp.SpanID {
+					server = &spans[i]
+				}
+			}
+			for _, service := range tt.services {
+				if !services[service] {
+					t.Errorf("trace has no span of %s", service)
+				}
+			}
+			if server == nil {
+				t.Fatalf("collector did not receive the peer span %s", resp.SpanID)
+			}
+			if server.GetKind() != tracepb.Span_SPAN_KIND_SERVER {
+				t.Errorf("peer span kind = %v, want server", server.GetKind())
+			}
+			if parent := hex.EncodeToString(server.GetParentSpanId()); parent != client.SpanID().String() {
+				t.Errorf("peer span parent = %s, want the client span %s", parent, client.SpanID())
+			}
+		})
+	}
+}
+
+// call does what cmd/interop does: calls the peer under a client span and
+// decodes its answer.
+func call(t *testing.T, url string) (trace.SpanContext, interop.Response) {
+	t.Helper()
+	ctx, span := otel.Tracer("interop").Start(context.Background(), "InteropClient",
+		trace.WithSpanKind(trace.SpanKindClient))
+	defer span.End()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("call peer: %v", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("peer returned %s", res.Status)
+	}
+	var resp interop.Response
+	if err := json.NewDecoder(res.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode peer response: %v", err)
+	}
+	return span.SpanContext(), resp
+}
+
+func TestVerify(t *testing.T) {
+	client := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16},
+		SpanID:     trace.SpanID{1, 2, 3, 4, 5, 6, 7, 8},
+		TraceFlags: trace.FlagsSampled,
+	})
+	traceID, spanID := client.TraceID().String(), client.SpanID().String()
+	traceparent := "00-" + traceID + "-" + spanID + "-01"
+	otherTrace := strings.Repeat("ab", 16)
+
+	tests := []struct {
+		name    string
+		resp    interop.Response
+		wantErr string
+	}{
+		{"continued", interop.Response{Traceparent: traceparent, TraceID: traceID, SpanID: "0000000000000009"}, ""},
+		{"malformed traceparent", interop.Response{Traceparent: "00-" + traceID, TraceID: traceID, SpanID: "0000000000000009"}, "malformed traceparent"},
+		{"other trace received", interop.Response{Traceparent: "00-" + otherTrace + "-" + spanID + "-01", TraceID: traceID, SpanID: "0000000000000009"}, "received trace id"},
+		{"other parent received", interop.Response{Traceparent: "00-" + traceID + "-0000000000000009-01", TraceID: traceID, SpanID: "0000000000000009"}, "parent span id"},
+		{"new trace started", interop.Response{Traceparent: traceparent, TraceID: otherTrace, SpanID: "0000000000000009"}, "peer span has trace id"},
+		{"invalid span id", interop.Response{Traceparent: traceparent, TraceID: traceID, SpanID: "xyz"}, "peer span id"},
+		{"client span reused", interop.Response{Traceparent: traceparent, TraceID: traceID, SpanID: spanID}, "reused the client span id"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := interop.Verify(client, tt.resp)
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Fatalf("Verify: %v", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Fatalf("Verify = %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,257 @@
+// Package masking hides personal data and credentials, e.g. emails and
+// tokens, in what the services expose for debugging: recorded requests,
+// debug endpoints and exported span attributes. The same rules apply
+// everywhere, see Rule.
+package masking
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Mask replaces masked values unless a rule sets its own replacement.
+const Mask = "****"
+
+// MaskedEmail replaces emails. It is still a valid address, in the reserved
+// .invalid domain, so recorded requests carrying one replay without failing
+// validation, see internal/replay.
+const MaskedEmail = Mask + "@masked.invalid"
+
+// Rule masks either the matches of Pattern in any text, or the values at
+// Path in JSON documents. Path is a JSON path limited to $.key, $..key (key
+// at any depth) and [*] (every array element), e.g. $.users[*].email.
+type Rule struct {
+	Pattern     string `yaml:"pattern" json:"pattern"`
+	Path        string `yaml:"path" json:"path"`
+	Replacement string `yaml:"replacement" json:"replacement"`
+}
+
+// DefaultRules mask emails, bearer tokens, JWTs and the usual credential
+// fields of JSON bodies.
+var DefaultRules = []Rule{
+	{Pattern: `[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`, Replacement: MaskedEmail},
+	{Pattern: `(?i)(bearer\s+)[A-Za-z0-9._~+/=-]+`, Replacement: "${1}" + Mask},
+	{Pattern: `eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`},
+	{Path: "$..password"},
+	{Path: "$..token"},
+	{Path: "$..access_token"},
+	{Path: "$..client_secret"},
+	{Path: "$..api_key"},
+}
+
+// Masker applies rules. A nil *Masker masks nothing.
+type Masker struct {
+	patterns []pattern
+	paths    []path
+}
+
+type pattern struct {
+	re          *regexp.Regexp
+	replacement string
+}
+
+type path struct {
+	segments    []segment
+	replacement string
+}
+
+// segment is one step of a path: a key, a key at any depth, or every element
+// of an array.
+type segment struct {
+	key       string
+	recursive bool
+	each      bool
+}
+
+// New compiles rules, on top of DefaultRules unless noDefaults is set.
+func New(rules []Rule, noDefaults bool) (*Masker, error) {
+	if !noDefaults {
+		rules = append(append([]Rule(nil), DefaultRules...), rules...)
+	}
+	m := &Masker{}
+	for i, r := range rules {
+		replacement := r.Replacement
+		if replacement == "" {
+			replacement = Mask
+		}
+		switch {
+		case r.Pattern != "" && r.Path != "":
+			return nil, fmt.Errorf("rule %d: set either pattern or path", i)
+		case r.Pattern != "":
+			re, err := regexp.Compile(r.Pattern)
+			if err != nil {
+				return nil, fmt.Errorf("rule %d: %w", i, err)
+			}
+			m.patterns = append(m.patterns, pattern{re: re, replacement: replacement})
+		case r.Path != "":
+			segments, err := parsePath(r.Path)
+			if err != nil {
+				return nil, fmt.Errorf("rule %d: %w", i, err)
+			}
+			m.paths = append(m.paths, path{segments: segments, replacement: replacement})
+		default:
+			return nil, fmt.Errorf("rule %d: pattern or path is required", i)
+		}
+	}
+	return m, nil
+}
+
+// Default returns a Masker applying DefaultRules.
+func Default() *Masker {
+	m, _ := New(nil, false)
+	return m
+}
+
+func parsePath(p string) ([]segment, error) {
+	rest, ok := strings.CutPrefix(p, "$")
+	if !ok {
+		return nil, fmt.Errorf("path %q must start with $", p)
+	}
+	var segments []segment
+	for rest != "" {
+		var s segment
+		switch {
+		case strings.HasPrefix(rest, "[*]"):
+			s.each, rest = true, rest[3:]
+			segments = append(segments, s)
+			continue
+		case strings.HasPrefix(rest, ".."):
+			s.recursive, rest = true, rest[2:]
+		case strings.HasPrefix(rest, "."):
+			rest = rest[1:]
+		default:
+			return nil, fmt.Errorf("path %q: unexpected %q", p, rest)
+		}
+		end := strings.IndexAny(rest, ".[")
+		if end < 0 {
+			end = len(rest)
+		}
+		if s.key, rest = rest[:end], rest[end:]; s.key == "" {
+			return nil, fmt.Errorf("path %q: empty key", p)
+		}
+		segments = append(segments, s)
+	}
+	if len(segments) == 0 {
+		return nil, fmt.Errorf("path %q selects the whole document", p)
+	}
+	return segments, nil
+}
+
+// String masks the pattern matches in s.
+func (m *Masker) String(s string) string {
+	if m == nil {
+		return s
+	}
+	for _, p := range m.patterns {
+		s = p.re.ReplaceAllString(s, p.replacement)
+	}
+	return s
+}
+
+// Body masks a payload: a JSON document has the values at the rule paths
+// replaced and the patterns masked in its strings, anything else is masked
+// as text.
+func (m *Masker) Body(b []byte) []byte {
+	if m == nil || len(b) == 0 {
+		return b
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var doc any
+	if err := dec.Decode(&doc); err != nil || dec.More() {
+		return []byte(m.String(string(b)))
+	}
+	for _, p := range m.paths {
+		doc = p.apply(doc, p.segments)
+	}
+	doc = m.strings(doc)
+	out, err := json.Marshal(doc)
+	if err != nil {
+		return []byte(m.String(string(b)))
+	}
+	return out
+}
+
+// Attributes masks the string values of attrs. The second result tells
+// whether any value changed, attrs is returned as is otherwise.
+func (m *Masker) Attributes(attrs []attribute.KeyValue) ([]attribute.KeyValue, bool) {
+	if m == nil {
+		return attrs, false
+	}
+	var out []attribute.KeyValue
+	for i, kv := range attrs {
+		if kv.Value.Type() != attribute.STRING {
+			continue
+		}
+		masked := m.String(kv.Value.AsString())
+		if masked == kv.Value.AsString() {
+			continue
+		}
+		if out == nil {
+			out = append([]attribute.KeyValue(nil), attrs...)
+		}
+		out[i] = kv.Key.String(masked)
+	}
+	if out == nil {
+		return attrs, false
+	}
+	return out, true
+}
+
+// strings masks the patterns in every string of a decoded JSON document.
+func (m *Masker) strings(v any) any {
+	switch v := v.(type) {
+	case string:
+		return m.String(v)
+	case map[string]any:
+		for k, e := range v {
+			v[k] = m.strings(e)
+		}
+	case []any:
+		for i, e := range v {
+			v[i] = m.strings(e)
+		}
+	}
+	return v
+}
+
+// apply replaces the values of v at segments. Empty and null values are
+// kept, they reveal nothing.
+func (p path) apply(v any, segments []segment) any {
+	if len(segments) == 0 {
+		if v == nil || v == "" {
+			return v
+		}
+		return p.replacement
+	}
+	s, rest := segments[0], segments[1:]
+	switch v := v.(type) {
+	case map[string]any:
+		if s.recursive {
+			for k, e := range v {
+				if k == s.key {
+					v[k] = p.apply(e, rest)
+				} else {
+					v[k] = p.apply(e, segments)
+				}
+			}
+		} else if e, ok := v[s.key]; ok && !s.each {
+			v[s.key] = p.apply(e, rest)
+		}
+	case []any:
+		for i, e := range v {
+			switch {
+			case s.each:
+				v[i] = p.apply(e, rest)
+			case s.recursive:
+				v[i] = p.apply(e, segments)
+			}
+		}
+	}
+	return v
+}
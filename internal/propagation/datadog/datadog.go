@@ -0,0 +1,109 @@
+// Package datadog propagates span context through the x-datadog-* headers of
+// the Datadog tracing libraries, so services instrumented with them can call
+// the demo services, and be called by them, without breaking the trace.
+package datadog
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Header names used by the Datadog libraries. The IDs are unsigned 64-bit
+// decimal integers.
+const (
+	TraceIDHeader          = "x-datadog-trace-id"
+	ParentIDHeader         = "x-datadog-parent-id"
+	SamplingPriorityHeader = "x-datadog-sampling-priority"
+	TagsHeader             = "x-datadog-tags"
+)
+
+// traceIDHighTag carries the upper 64 bits of a 128-bit trace ID in hex, in
+// the comma separated key=value list of TagsHeader.
+const traceIDHighTag = "_dd.p.tid"
+
+// Propagator extracts and injects the Datadog headers. A positive sampling
+// priority means sampled, a missing one is treated as sampled like the
+// legacy propagator does.
+type Propagator struct{}
+
+var _ propagation.TextMapPropagator = Propagator{}
+
+// Inject sets the Datadog headers from the span context in ctx: the lower 64
+// bits of the trace ID as x-datadog-trace-id and the upper ones, when set, as
+// _dd.p.tid in x-datadog-tags.
+func (Propagator) Inject(ctx context.Context, carrier propagation.TextMapCarrier) {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return
+	}
+	traceID, spanID := sc.TraceID(), sc.SpanID()
+	carrier.Set(TraceIDHeader, strconv.FormatUint(binary.BigEndian.Uint64(traceID[8:]), 10))
+	carrier.Set(ParentIDHeader, strconv.FormatUint(binary.BigEndian.Uint64(spanID[:]), 10))
+	priority := "0"
+	if sc.IsSampled() {
+		priority = "1"
+	}
+	carrier.Set(SamplingPriorityHeader, priority)
+	if high := binary.BigEndian.Uint64(traceID[:8]); high != 0 {
+		carrier.Set(TagsHeader, traceIDHighTag+"="+hex.EncodeToString(traceID[:8]))
+	}
+}
+
+// Extract returns ctx with the remote span context read from the Datadog
+// headers, or ctx unchanged when they are missing or malformed.
+func (Propagator) Extract(ctx context.Context, carrier propagation.TextMapCarrier) context.Context {
+	low, err := strconv.ParseUint(carrier.Get(TraceIDHeader), 10, 64)
+	if err != nil || low == 0 {
+		return ctx
+	}
+	parent, err := strconv.ParseUint(carrier.Get(ParentIDHeader), 10, 64)
+	if err != nil || parent == 0 {
+		return ctx
+	}
+
+	var traceID trace.TraceID
+	binary.BigEndian.PutUint64(traceID[8:], low)
+	if high, ok := traceIDHigh(carrier.Get(TagsHeader)); ok {
+		copy(traceID[:8], high)
+	}
+	var spanID trace.SpanID
+	binary.BigEndian.PutUint64(spanID[:], parent)
+
+	flags := trace.FlagsSampled
+	if p := carrier.Get(SamplingPriorityHeader); p != "" {
+		if n, err := strconv.Atoi(p); err == nil && n <= 0 {
+			flags = 0
+		}
+	}
+	sc := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: flags,
+		Remote:     true,
+	})
+	return trace.ContextWithRemoteSpanContext(ctx, sc)
+}
+
+// Fields returns the headers this propagator reads and writes.
+func (Propagator) Fields() []string {
+	return []string{TraceIDHeader, ParentIDHeader, SamplingPriorityHeader, TagsHeader}
+}
+
+// traceIDHigh reads the upper 64 bits of the trace ID from the tags.
+func traceIDHigh(tags string) ([]byte, bool) {
+	for _, tag := range strings.Split(tags, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(tag), "=")
+		if key != traceIDHighTag {
+			continue
+		}
+		high, err := hex.DecodeString(value)
+		return high, err == nil && len(high) == 8
+	}
+	return nil, false
+}
@@ -0,0 +1,70 @@
+// Package legacy propagates span context through the TraceID/SpanID headers
+// sent by callers that predate W3C trace context. Combine it with
+// propagation.TraceContext in a composite so both formats are understood
+// during the migration.
+package legacy
+
+import (
+	"context"
+	"strings"
+
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Header names used by the legacy callers. Both carry lowercase hex.
+const (
+	TraceIDHeader = "TraceID"
+	SpanIDHeader  = "SpanID"
+)
+
+// Propagator extracts and injects the legacy headers. The legacy format has no
+// sampling flag, so extracted contexts are always treated as sampled.
+type Propagator struct{}
+
+var _ propagation.TextMapPropagator = Propagator{}
+
+// Inject sets the legacy headers from the span context in ctx.
+func (Propagator) Inject(ctx context.Context, carrier propagation.TextMapCarrier) {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return
+	}
+	carrier.Set(TraceIDHeader, sc.TraceID().String())
+	carrier.Set(SpanIDHeader, sc.SpanID().String())
+}
+
+// Extract returns ctx with the remote span context read from the legacy
+// headers, or ctx unchanged when they are missing or malformed.
+func (Propagator) Extract(ctx context.Context, carrier propagation.TextMapCarrier) context.Context {
+	traceID, err := parseTraceID(carrier.Get(TraceIDHeader))
+	if err != nil {
+		return ctx
+	}
+	spanID, err := trace.SpanIDFromHex(strings.ToLower(carrier.Get(SpanIDHeader)))
+	if err != nil {
+		return ctx
+	}
+	sc := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: trace.FlagsSampled,
+		Remote:     true,
+	})
+	return trace.ContextWithRemoteSpanContext(ctx, sc)
+}
+
+// Fields returns the headers this propagator reads and writes.
+func (Propagator) Fields() []string {
+	return []string{TraceIDHeader, SpanIDHeader}
+}
+
+// parseTraceID accepts both 128-bit and the older 64-bit trace ids, padding the
+// latter with zeros.
+func parseTraceID(s string) (trace.TraceID, error) {
+	s = strings.ToLower(s)
+	if len(s) == 16 {
+		s = strings.Repeat("0", 16) + s
+	}
+	return trace.TraceIDFromHex(s)
+}
@@ -0,0 +1,189 @@
+// Package replay records the traffic of a service so that it can be replayed
+// against another build, see cmd/replay. Recordings are JSON lines, one
+// Exchange per request.
+package replay
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/trace"
+
+	"test-jaeger/internal/masking"
+)
+
+// MaxBodySize caps the recorded request and response bodies. Longer bodies
+// are truncated and marked as such.
+const MaxBodySize = 64 << 10
+
+// Redacted replaces the values of sensitive headers in recordings.
+const Redacted = "REDACTED"
+
+// sensitiveHeaders never reach a recording in clear.
+var sensitiveHeaders = []string{"Authorization", "Cookie", "Set-Cookie", "Proxy-Authorization", "X-Api-Key", "Api-Key"}
+
+// Exchange is a recorded request and the response the service gave.
+type Exchange struct {
+	Time    time.Time `json:"time"`
+	TraceID string    `json:"trace_id,omitempty"`
+
+	Method        string      `json:"method"`
+	URL           string      `json:"url"`
+	Header        http.Header `json:"header"`
+	Body          []byte      `json:"body,omitempty"`
+	BodyTruncated bool        `json:"body_truncated,omitempty"`
+
+	Status                int         `json:"status"`
+	ResponseHeader        http.Header `json:"response_header"`
+	ResponseBody          []byte      `json:"response_body,omitempty"`
+	ResponseBodyTruncated bool        `json:"response_body_truncated,omitempty"`
+	DurationMs            float64     `json:"duration_ms"`
+}
+
+// Recorder appends exchanges to a file.
+type Recorder struct {
+	masker *masking.Masker
+
+	mu   sync.Mutex
+	file *os.File
+	w    *bufio.Writer
+	enc  *json.Encoder
+}
+
+// NewRecorder appends to the file at path, creating it if needed. URLs,
+// headers and bodies are masked with masker.
+func NewRecorder(path string, masker *masking.Masker) (*Recorder, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	w := bufio.NewWriter(f)
+	return &Recorder{masker: masker, file: f, w: w, enc: json.NewEncoder(w)}, nil
+}
+
+// Middleware records every request handled after it, with the trace ID of
+// the request, sensitive headers redacted, the rest masked and bodies capped
+// at MaxBodySize.
+func (r *Recorder) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		ex := Exchange{
+			Time:   start,
+			Method: c.Request.Method,
+			URL:    r.masker.String(c.Request.URL.RequestURI()),
+			Header: r.sanitize(c.Request.Header),
+		}
+		if c.Request.Body != nil {
+			body, err := io.ReadAll(c.Request.Body)
+			c.Request.Body.Close()
+			c.Request.Body = io.NopCloser(bytes.NewReader(body))
+			if err != nil {
+				c.Error(err)
+			}
+			ex.Body, ex.BodyTruncated = capBody(r.masker.Body(body))
+		}
+
+		w := &teeWriter{ResponseWriter: c.Writer}
+		c.Writer = w
+		c.Next()
+
+		if sc := trace.SpanContextFromContext(c.Request.Context()); sc.HasTraceID() {
+			ex.TraceID = sc.TraceID().String()
+		}
+		ex.Status = w.Status()
+		ex.ResponseHeader = r.sanitize(w.Header())
+		ex.ResponseBody, ex.ResponseBodyTruncated = r.masker.Body(w.body.Bytes()), w.truncated
+		ex.DurationMs = float64(time.Since(start)) / float64(time.Millisecond)
+		if err := r.write(ex); err != nil {
+			c.Error(err)
+		}
+	}
+}
+
+func (r *Recorder) write(ex Exchange) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.enc.Encode(ex); err != nil {
+		return err
+	}
+	return r.w.Flush()
+}
+
+// Close flushes and closes the recording.
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.w.Flush(); err != nil {
+		r.file.Close()
+		return err
+	}
+	return r.file.Close()
+}
+
+// sanitize copies h with the values of sensitive headers redacted and the
+// others masked.
+func (r *Recorder) sanitize(h http.Header) http.Header {
+	out := h.Clone()
+	for name, values := range out {
+		for i, v := range values {
+			values[i] = r.masker.String(v)
+		}
+		out[name] = values
+	}
+	for _, name := range sensitiveHeaders {
+		if _, ok := out[name]; ok {
+			out[name] = []string{Redacted}
+		}
+	}
+	return out
+}
+
+// IsRedacted reports whether a recorded header was redacted.
+func IsRedacted(name string) bool {
+	for _, s := range sensitiveHeaders {
+		if strings.EqualFold(s, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func capBody(b []byte) ([]byte, bool) {
+	if len(b) > MaxBodySize {
+		return b[:MaxBodySize], true
+	}
+	return b, false
+}
+
+// teeWriter keeps a copy of the first MaxBodySize bytes of the response.
+type teeWriter struct {
+	gin.ResponseWriter
+	body      bytes.Buffer
+	truncated bool
+}
+
+func (w *teeWriter) Write(b []byte) (int, error) {
+	w.keep(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *teeWriter) WriteString(s string) (int, error) {
+	w.keep([]byte(s))
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (w *teeWriter) keep(b []byte) {
+	if room := MaxBodySize - w.body.Len(); len(b) > room {
+		b = b[:room]
+		w.truncated = true
+	}
+	w.body.Write(b)
+}
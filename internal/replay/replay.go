@@ -0,0 +1,63 @@
+package replay
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// skippedHeaders are not replayed: the client sets them itself, and the trace
+// context of the replayed request is the one of the replay, not the original.
+var skippedHeaders = []string{"Content-Length", "Connection", "Traceparent", "Tracestate", "Baggage", "Traceid", "Spanid"}
+
+// ReadFile reads the exchanges of a recording.
+func ReadFile(path string) ([]Exchange, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var exchanges []Exchange
+	scanner := bufio.NewScanner(f)
+	// Bodies are base64 encoded, so a line can be larger than both caps.
+	scanner.Buffer(make([]byte, 0, 64<<10), 4*MaxBodySize)
+	for line := 1; scanner.Scan(); line++ {
+		var ex Exchange
+		if err := json.Unmarshal(scanner.Bytes(), &ex); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		exchanges = append(exchanges, ex)
+	}
+	return exchanges, scanner.Err()
+}
+
+// Request rebuilds the recorded request against baseURL, e.g.
+// http://localhost:5001. Redacted headers and trace context are left out.
+func (ex Exchange) Request(ctx context.Context, baseURL string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, ex.Method, strings.TrimSuffix(baseURL, "/")+ex.URL, bytes.NewReader(ex.Body))
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range ex.Header {
+		if IsRedacted(name) || skipped(name) {
+			continue
+		}
+		req.Header[name] = values
+	}
+	return req, nil
+}
+
+func skipped(name string) bool {
+	for _, s := range skippedHeaders {
+		if strings.EqualFold(s, name) {
+			return true
+		}
+	}
+	return false
+}
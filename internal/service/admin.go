@@ -0,0 +1,23 @@
+package service
+
+import (
+	"net/http"
+	"net/http/pprof"
+)
+
+// adminHandler serves the net/http/pprof profiles under /debug/pprof/, e.g.
+// to profile the CPU and heap of the tracing pipeline while reproducing an
+// overhead issue, and the routes of admin, changing the state of the
+// service, under /admin/. It is served on its own address, see
+// config.Config.AdminListen, so neither reaches the public port. None of the
+// middleware of the router applies to it, admin has its own.
+func adminHandler(admin http.Handler) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.Handle("/admin/", admin)
+	return mux
+}
@@ -0,0 +1,172 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+const cgroupRoot = "/sys/fs/cgroup"
+
+// cgroup holds the CPU and memory limits of the container the service runs
+// in, and where to read its throttling stats, for cgroup v1 and v2.
+type cgroup struct {
+	version     int
+	cpuLimit    float64 // cores, 0 when unlimited
+	memoryLimit int64   // bytes, 0 when unlimited
+
+	cpuStat       string
+	throttledKey  string
+	throttledUnit time.Duration
+	memoryUsage   string
+}
+
+// detectCgroup reads the limits of the cgroup of the process. It returns nil
+// outside of Linux or when no cgroup filesystem is mounted.
+func detectCgroup() *cgroup {
+	if _, err := os.Stat(filepath.Join(cgroupRoot, "cgroup.controllers")); err == nil {
+		return detectCgroupV2()
+	}
+	if _, err := os.Stat(filepath.Join(cgroupRoot, "cpu", "cpu.cfs_quota_us")); err == nil {
+		return detectCgroupV1()
+	}
+	return nil
+}
+
+func detectCgroupV2() *cgroup {
+	cg := &cgroup{
+		version:       2,
+		cpuStat:       filepath.Join(cgroupRoot, "cpu.stat"),
+		throttledKey:  "throttled_usec",
+		throttledUnit: time.Microsecond,
+		memoryUsage:   filepath.Join(cgroupRoot, "memory.current"),
+	}
+	// cpu.max is "<quota> <period>", quota being "max" when unlimited
+	if fields := strings.Fields(readCgroupFile(filepath.Join(cgroupRoot, "cpu.max"))); len(fields) == 2 {
+		quota, err1 := strconv.ParseFloat(fields[0], 64)
+		period, err2 := strconv.ParseFloat(fields[1], 64)
+		if err1 == nil && err2 == nil && period > 0 {
+			cg.cpuLimit = quota / period
+		}
+	}
+	cg.memoryLimit, _ = strconv.ParseInt(readCgroupFile(filepath.Join(cgroupRoot, "memory.max")), 10, 64)
+	return cg
+}
+
+func detectCgroupV1() *cgroup {
+	cg := &cgroup{
+		version:       1,
+		cpuStat:       filepath.Join(cgroupRoot, "cpu", "cpu.stat"),
+		throttledKey:  "throttled_time",
+		throttledUnit: time.Nanosecond,
+		memoryUsage:   filepath.Join(cgroupRoot, "memory", "memory.usage_in_bytes"),
+	}
+	quota, err1 := strconv.ParseFloat(readCgroupFile(filepath.Join(cgroupRoot, "cpu", "cpu.cfs_quota_us")), 64)
+	period, err2 := strconv.ParseFloat(readCgroupFile(filepath.Join(cgroupRoot, "cpu", "cpu.cfs_period_us")), 64)
+	if err1 == nil && err2 == nil && quota > 0 && period > 0 {
+		cg.cpuLimit = quota / period
+	}
+	// No limit reads as the largest page aligned int64
+	if limit, err := strconv.ParseInt(readCgroupFile(filepath.Join(cgroupRoot, "memory", "memory.limit_in_bytes")), 10, 64); err == nil && limit < 1<<62 {
+		cg.memoryLimit = limit
+	}
+	return cg
+}
+
+func readCgroupFile(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// attributes describe the limits as resource attributes. Unlimited
+// resources are left out.
+func (cg *cgroup) attributes() []attribute.KeyValue {
+	if cg == nil {
+		return nil
+	}
+	attrs := []attribute.KeyValue{attribute.Int("cgroup.version", cg.version)}
+	if cg.cpuLimit > 0 {
+		attrs = append(attrs, attribute.Float64("cgroup.cpu.limit", cg.cpuLimit))
+	}
+	if cg.memoryLimit > 0 {
+		attrs = append(attrs, attribute.Int64("cgroup.memory.limit", cg.memoryLimit))
+	}
+	return attrs
+}
+
+// throttling returns the number of periods the cgroup was throttled in and
+// the total throttled time, from cpu.stat.
+func (cg *cgroup) throttling() (periods int64, throttled time.Duration, err error) {
+	f, err := os.Open(cg.cpuStat)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		key, value, _ := strings.Cut(scanner.Text(), " ")
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			continue
+		}
+		switch key {
+		case "nr_throttled":
+			periods = n
+		case cg.throttledKey:
+			throttled = time.Duration(n) * cg.throttledUnit
+		}
+	}
+	return periods, throttled, scanner.Err()
+}
+
+// observe reports the throttling of the cgroup and its memory usage at every
+// collection, so latency regressions can be put down to CPU throttling.
+func (cg *cgroup) observe() {
+	if cg == nil {
+		return
+	}
+	meter := otel.Meter(instrumentationName)
+	periods, err := meter.Int64ObservableCounter("cgroup.cpu.throttled_periods",
+		metric.WithDescription("Number of CPU periods the container was throttled in"))
+	if err != nil {
+		log.Printf("failed to create cgroup.cpu.throttled_periods counter: %v", err)
+		return
+	}
+	throttled, err := meter.Float64ObservableCounter("cgroup.cpu.throttled_time",
+		metric.WithDescription("Time the container was throttled for"), metric.WithUnit("s"))
+	if err != nil {
+		log.Printf("failed to create cgroup.cpu.throttled_time counter: %v", err)
+		return
+	}
+	usage, err := meter.Int64ObservableGauge("cgroup.memory.usage",
+		metric.WithDescription("Memory used by the container"), metric.WithUnit("By"))
+	if err != nil {
+		log.Printf("failed to create cgroup.memory.usage gauge: %v", err)
+		return
+	}
+	_, err = meter.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+		if n, d, err := cg.throttling(); err == nil {
+			o.ObserveInt64(periods, n)
+			o.ObserveFloat64(throttled, d.Seconds())
+		}
+		if n, err := strconv.ParseInt(readCgroupFile(cg.memoryUsage), 10, 64); err == nil {
+			o.ObserveInt64(usage, n)
+		}
+		return nil
+	}, periods, throttled, usage)
+	if err != nil {
+		log.Printf("failed to register cgroup callback: %v", err)
+	}
+}
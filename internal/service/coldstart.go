@@ -0,0 +1,77 @@
+package service
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"test-jaeger/internal/telemetry"
+)
+
+// coldStartRequests is the number of first requests tagged coldstart=true.
+const coldStartRequests = 10
+
+// processStart approximates the start of the process, the service package
+// being initialized before main runs.
+var processStart = time.Now()
+
+// coldStart times the startup of the service, from the start of the process
+// to the server listening, and records it as a service.cold_start trace once
+// the tracer provider is up: a span per step, config.load, telemetry.init
+// (the exporters dialing), store.connect, router.setup and routes.register,
+// the time the service took to register its routes between New and Run. The
+// first coldStartRequests requests get coldstart=true on their top span, so
+// the warm-up shows when comparing their latency to the later ones.
+type coldStart struct {
+	steps []coldStartStep
+	last  time.Time
+	left  atomic.Int64
+}
+
+type coldStartStep struct {
+	name       string
+	start, end time.Time
+}
+
+func newColdStart() *coldStart {
+	cs := &coldStart{last: time.Now()}
+	cs.left.Store(coldStartRequests)
+	return cs
+}
+
+// step records the step name as lasting from the end of the previous one,
+// or the call to newColdStart, to now.
+func (cs *coldStart) step(name string) {
+	now := time.Now()
+	cs.steps = append(cs.steps, coldStartStep{name: name, start: cs.last, end: now})
+	cs.last = now
+}
+
+// finish records the last step and emits the trace, ending now.
+func (cs *coldStart) finish(ctx context.Context, last string) {
+	cs.step(last)
+	tracer := otel.Tracer(instrumentationName)
+	end := cs.last
+	ctx, span := tracer.Start(ctx, "service.cold_start", trace.WithNewRoot(), trace.WithTimestamp(processStart),
+		trace.WithAttributes(attribute.Float64("service.cold_start.duration_ms", float64(end.Sub(processStart))/float64(time.Millisecond))))
+	for _, s := range cs.steps {
+		_, child := tracer.Start(ctx, s.name, trace.WithTimestamp(s.start))
+		child.End(trace.WithTimestamp(s.end))
+	}
+	span.End(trace.WithTimestamp(end))
+}
+
+// Middleware tags the first requests as cold start ones.
+func (cs *coldStart) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if cs.left.Load() > 0 && cs.left.Add(-1) >= 0 {
+			telemetry.AddAttributes(c.Request.Context(), "coldstart", true)
+		}
+		c.Next()
+	}
+}
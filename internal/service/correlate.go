@@ -0,0 +1,123 @@
+package service
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.opentelemetry.io/otel/trace"
+
+	"test-jaeger/internal/masking"
+	"test-jaeger/internal/telemetry"
+)
+
+// correlator gathers what the service kept in memory about a trace: its
+// spans, the log lines written under it and the metric exemplars pointing at
+// it, in one document, for debugging without a backend.
+type correlator struct {
+	spans  *telemetry.SpanCapture
+	logs   *telemetry.LogRing
+	reader *sdkmetric.ManualReader
+	masker *masking.Masker
+}
+
+// exemplar is a measurement a metric kept with the trace it was taken in.
+type exemplar struct {
+	Metric     string         `json:"metric"`
+	Value      any            `json:"value"`
+	Time       time.Time      `json:"time"`
+	SpanID     string         `json:"span_id"`
+	Attributes map[string]any `json:"attributes,omitempty"`
+}
+
+// Handler serves GET /debug/correlate/:traceID.
+func (co *correlator) Handler(c *gin.Context) {
+	id, err := trace.TraceIDFromHex(c.Param("traceID"))
+	if err != nil {
+		c.String(http.StatusBadRequest, "invalid trace ID %q", c.Param("traceID"))
+		return
+	}
+	exemplars, err := co.exemplars(c, id)
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	// Empty lists rather than nulls
+	data, err := json.Marshal(gin.H{
+		"trace_id":  id.String(),
+		"spans":     append([]telemetry.CapturedSpan{}, co.spans.Trace(id)...),
+		"logs":      append([]json.RawMessage{}, co.logs.Trace(id)...),
+		"exemplars": append([]exemplar{}, exemplars...),
+	})
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	c.Data(http.StatusOK, "application/json; charset=utf-8", co.masker.Body(data))
+}
+
+// exemplars collects the metrics and keeps the exemplars of trace id.
+func (co *correlator) exemplars(c *gin.Context, id trace.TraceID) ([]exemplar, error) {
+	var rm metricdata.ResourceMetrics
+	if err := co.reader.Collect(c.Request.Context(), &rm); err != nil {
+		return nil, err
+	}
+	var found []exemplar
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			switch data := m.Data.(type) {
+			case metricdata.Sum[int64]:
+				found = appendExemplars(found, m.Name, id, pointExemplars(data.DataPoints))
+			case metricdata.Sum[float64]:
+				found = appendExemplars(found, m.Name, id, pointExemplars(data.DataPoints))
+			case metricdata.Gauge[int64]:
+				found = appendExemplars(found, m.Name, id, pointExemplars(data.DataPoints))
+			case metricdata.Gauge[float64]:
+				found = appendExemplars(found, m.Name, id, pointExemplars(data.DataPoints))
+			case metricdata.Histogram[int64]:
+				found = appendExemplars(found, m.Name, id, histogramExemplars(data.DataPoints))
+			case metricdata.Histogram[float64]:
+				found = appendExemplars(found, m.Name, id, histogramExemplars(data.DataPoints))
+			}
+		}
+	}
+	return found, nil
+}
+
+func pointExemplars[N int64 | float64](points []metricdata.DataPoint[N]) []metricdata.Exemplar[N] {
+	var all []metricdata.Exemplar[N]
+	for _, p := range points {
+		all = append(all, p.Exemplars...)
+	}
+	return all
+}
+
+func histogramExemplars[N int64 | float64](points []metricdata.HistogramDataPoint[N]) []metricdata.Exemplar[N] {
+	var all []metricdata.Exemplar[N]
+	for _, p := range points {
+		all = append(all, p.Exemplars...)
+	}
+	return all
+}
+
+func appendExemplars[N int64 | float64](found []exemplar, metric string, id trace.TraceID, all []metricdata.Exemplar[N]) []exemplar {
+	for _, e := range all {
+		if !bytes.Equal(e.TraceID, id[:]) {
+			continue
+		}
+		ex := exemplar{Metric: metric, Value: e.Value, Time: e.Time, SpanID: hex.EncodeToString(e.SpanID)}
+		if len(e.FilteredAttributes) > 0 {
+			ex.Attributes = make(map[string]any, len(e.FilteredAttributes))
+			for _, kv := range e.FilteredAttributes {
+				ex.Attributes[string(kv.Key)] = kv.Value.AsInterface()
+			}
+		}
+		found = append(found, ex)
+	}
+	return found
+}
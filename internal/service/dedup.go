@@ -0,0 +1,115 @@
+package service
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"io"
+	"log"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/metric"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+
+	"test-jaeger/internal/telemetry"
+)
+
+// duplicateWindow is how long a write is remembered to tell a resubmission
+// of it.
+const duplicateWindow = 10 * time.Second
+
+// maxFingerprintedBody is the size of the largest body fingerprinted, the
+// larger ones are let through unchecked.
+const maxFingerprintedBody = 1 << 20
+
+// duplicateDetector flags the writes, POST, PUT, PATCH and DELETE requests,
+// sent again by the same client with the same body within duplicateWindow,
+// e.g. a form submitted twice or a retry without an idempotency key. The
+// requests are still served: the top span of a suspect gets
+// request.duplicate_suspect=true, see telemetry.AddAttributes, and it is
+// counted in http.server.duplicate_suspects by route, so client misbehavior
+// shows in the telemetry.
+type duplicateDetector struct {
+	suspects metric.Int64Counter
+
+	mu     sync.Mutex
+	seen   map[[sha256.Size]byte]time.Time
+	pruned time.Time
+}
+
+func newDuplicateDetector() *duplicateDetector {
+	suspects, err := otel.Meter(instrumentationName).Int64Counter("http.server.duplicate_suspects",
+		metric.WithDescription("Write requests resubmitted with the same body by the same client"))
+	if err != nil {
+		log.Printf("failed to create http.server.duplicate_suspects counter: %v", err)
+	}
+	return &duplicateDetector{suspects: suspects, seen: make(map[[sha256.Size]byte]time.Time)}
+}
+
+// Middleware fingerprints the writes and annotates the suspects.
+func (d *duplicateDetector) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		default:
+			c.Next()
+			return
+		}
+		body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxFingerprintedBody+1))
+		c.Request.Body = readCloser{io.MultiReader(bytes.NewReader(body), c.Request.Body), c.Request.Body}
+		if err != nil || len(body) > maxFingerprintedBody {
+			c.Next()
+			return
+		}
+
+		h := sha256.New()
+		for _, part := range []string{c.ClientIP(), c.Request.Method, c.Request.URL.Path} {
+			h.Write([]byte(part))
+			h.Write([]byte{0})
+		}
+		h.Write(body)
+		var fingerprint [sha256.Size]byte
+		h.Sum(fingerprint[:0])
+
+		if d.check(fingerprint, time.Now()) {
+			ctx := c.Request.Context()
+			route := c.FullPath()
+			telemetry.AddAttributes(ctx, "request.duplicate_suspect", true)
+			if d.suspects != nil {
+				d.suspects.Add(ctx, 1, metric.WithAttributes(
+					semconv.HTTPRouteKey.String(route), semconv.HTTPRequestMethodKey.String(c.Request.Method)))
+			}
+			slog.WarnContext(ctx, "duplicate write suspected", "method", c.Request.Method, "route", route)
+		}
+		c.Next()
+	}
+}
+
+// check remembers fingerprint at now and reports whether it was seen within
+// duplicateWindow.
+func (d *duplicateDetector) check(fingerprint [sha256.Size]byte, now time.Time) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if now.Sub(d.pruned) > duplicateWindow {
+		for f, at := range d.seen {
+			if now.Sub(at) > duplicateWindow {
+				delete(d.seen, f)
+			}
+		}
+		d.pruned = now
+	}
+	at, seen := d.seen[fingerprint]
+	d.seen[fingerprint] = now
+	return seen && now.Sub(at) <= duplicateWindow
+}
+
+// readCloser reads the buffered start of a body, then its rest, and closes
+// the original body.
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
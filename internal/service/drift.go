@@ -0,0 +1,130 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+
+	"test-jaeger/internal/config"
+	"test-jaeger/internal/masking"
+)
+
+// driftCheckInterval is how often the config file is compared to the
+// running configuration.
+const driftCheckInterval = 30 * time.Second
+
+// driftChecker compares the running configuration to the one its file would
+// produce now. A drift means the file was edited since the service started,
+// and the service must be reloaded or restarted for the edit to apply.
+type driftChecker struct {
+	running config.Config
+	masker  *masking.Masker
+
+	mu       sync.Mutex
+	diskHash string
+	err      error
+	checked  time.Time
+}
+
+func newDriftChecker(running config.Config, masker *masking.Masker) *driftChecker {
+	return &driftChecker{running: running, masker: masker, diskHash: running.Hash()}
+}
+
+// drifted reports whether the file produced a different configuration at the
+// last check. A file that no longer loads counts as drift too.
+func (d *driftChecker) drifted() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.err != nil || d.diskHash != d.running.Hash()
+}
+
+func (d *driftChecker) check() {
+	disk, err := d.running.Reload()
+	wasDrifted := d.drifted()
+
+	d.mu.Lock()
+	d.err = err
+	if err == nil {
+		d.diskHash = disk.Hash()
+	}
+	d.checked = time.Now()
+	d.mu.Unlock()
+
+	switch {
+	case wasDrifted:
+	case err != nil:
+		slog.Warn("config file no longer loads", "path", d.running.Path(), "error", err)
+	case d.drifted():
+		slog.Warn("config file differs from the running configuration",
+			"path", d.running.Path(), "running_hash", d.running.Hash(), "disk_hash", d.diskHash)
+	}
+}
+
+// run checks the file every driftCheckInterval until ctx is done, reporting
+// the outcome as the config.drift gauge.
+func (d *driftChecker) run(ctx context.Context) {
+	drift, err := otel.Meter(instrumentationName).Int64ObservableGauge("config.drift",
+		metric.WithDescription("1 when the config file differs from the running configuration"))
+	if err != nil {
+		log.Printf("failed to create config.drift gauge: %v", err)
+	} else {
+		hash := attribute.String("config.hash", d.running.Hash())
+		_, err = otel.Meter(instrumentationName).RegisterCallback(func(_ context.Context, o metric.Observer) error {
+			var v int64
+			if d.drifted() {
+				v = 1
+			}
+			o.ObserveInt64(drift, v, metric.WithAttributes(hash))
+			return nil
+		}, drift)
+		if err != nil {
+			log.Printf("failed to register config drift callback: %v", err)
+		}
+	}
+
+	ticker := time.NewTicker(driftCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			d.check()
+		}
+	}
+}
+
+// Handler serves /debug/config: the running configuration with its secrets
+// redacted and the rest masked, its hash and whether its file drifted.
+func (d *driftChecker) Handler(c *gin.Context) {
+	d.mu.Lock()
+	resp := gin.H{
+		"config":    d.running.Redacted(),
+		"hash":      d.running.Hash(),
+		"path":      d.running.Path(),
+		"disk_hash": d.diskHash,
+		"drift":     d.err != nil || d.diskHash != d.running.Hash(),
+	}
+	if !d.checked.IsZero() {
+		resp["checked_at"] = d.checked
+	}
+	if d.err != nil {
+		resp["error"] = d.err.Error()
+	}
+	d.mu.Unlock()
+	data, err := json.Marshal(resp)
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	c.Data(http.StatusOK, "application/json; charset=utf-8", d.masker.Body(data))
+}
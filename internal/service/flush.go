@@ -0,0 +1,58 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+
+	"test-jaeger/internal/telemetry"
+)
+
+// maxFlushTimeout bounds the timeout of a flush.
+const maxFlushTimeout = time.Minute
+
+// flusher exports the buffered spans on demand, e.g. before looking for a
+// trace in the backend or draining a pod.
+type flusher struct {
+	provider *sdktrace.TracerProvider
+}
+
+// Flush serves POST /admin/flush?timeout=5s, exporting the spans buffered by
+// the tracer provider within timeout, telemetry.ShutdownTimeout by default.
+// It answers 200 once every exporter is done, and 504 or 502 with the error
+// when the timeout expired or an export failed.
+func (f flusher) Flush(c *gin.Context) {
+	timeout := telemetry.ShutdownTimeout
+	if v := c.Query("timeout"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 || d > maxFlushTimeout {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid timeout %q, want a positive duration up to %s", v, maxFlushTimeout)})
+			return
+		}
+		timeout = d
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+	defer cancel()
+	start := time.Now()
+	err := f.provider.ForceFlush(ctx)
+	took := time.Since(start)
+	result := gin.H{"duration_ms": took.Milliseconds()}
+	if err == nil {
+		slog.InfoContext(c.Request.Context(), "spans flushed", "duration", took)
+		c.JSON(http.StatusOK, result)
+		return
+	}
+	slog.WarnContext(c.Request.Context(), "failed to flush spans", "duration", took, "error", err)
+	result["error"] = err.Error()
+	status := http.StatusBadGateway
+	if ctx.Err() != nil {
+		status = http.StatusGatewayTimeout
+	}
+	c.JSON(status, result)
+}
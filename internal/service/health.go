@@ -0,0 +1,203 @@
+package service
+
+import (
+	"context"
+	"log"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	grpchealth "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// healthCheckInterval is how often the dependencies are checked.
+const healthCheckInterval = 10 * time.Second
+
+// healthCheckTimeout bounds a single dependency check.
+const healthCheckTimeout = 2 * time.Second
+
+// dependencyKey names the dependency of the health metrics.
+const dependencyKey = attribute.Key("service.dependency")
+
+// dependency is something the service needs to serve, e.g. its store.
+type dependency struct {
+	name  string
+	check func(ctx context.Context) error
+}
+
+// healthChecker checks the dependencies of the service every
+// healthCheckInterval and reports them through the gRPC health checking
+// protocol (grpc.health.v1): each dependency as a service of its own name,
+// and the service as a whole, under its name and the empty name, as serving
+// only while every dependency is healthy. Over HTTP, /readyz answers the same
+// and /healthz whether the process serves at all, see registerProbes.
+// Transitions are logged and counted in service.health.transitions.
+type healthChecker struct {
+	service string
+	deps    []dependency
+	server  *grpchealth.Server
+
+	transitions metric.Int64Counter
+
+	mu       sync.Mutex
+	errs     map[string]error
+	status   healthpb.HealthCheckResponse_ServingStatus
+	draining bool
+}
+
+func newHealthChecker(service string, deps ...dependency) *healthChecker {
+	h := &healthChecker{
+		service: service,
+		deps:    deps,
+		server:  grpchealth.NewServer(),
+		errs:    make(map[string]error),
+		status:  healthpb.HealthCheckResponse_SERVING,
+	}
+	var err error
+	if h.transitions, err = otel.Meter(instrumentationName).Int64Counter("service.health.transitions",
+		metric.WithDescription("Number of times a dependency or the service changed health")); err != nil {
+		log.Printf("failed to create service.health.transitions counter: %v", err)
+	}
+	h.set(healthpb.HealthCheckResponse_SERVING)
+	for _, d := range deps {
+		h.server.SetServingStatus(d.name, healthpb.HealthCheckResponse_SERVING)
+	}
+	return h
+}
+
+// run checks the dependencies until ctx is done, then reports every service
+// as not serving so the probes fail while the service drains.
+func (h *healthChecker) run(ctx context.Context) {
+	healthy, err := otel.Meter(instrumentationName).Int64ObservableGauge("service.health",
+		metric.WithDescription("1 when the dependency is healthy"))
+	if err != nil {
+		log.Printf("failed to create service.health gauge: %v", err)
+	} else {
+		_, err = otel.Meter(instrumentationName).RegisterCallback(func(_ context.Context, o metric.Observer) error {
+			h.mu.Lock()
+			defer h.mu.Unlock()
+			for _, d := range h.deps {
+				var v int64
+				if h.errs[d.name] == nil {
+					v = 1
+				}
+				o.ObserveInt64(healthy, v, metric.WithAttributes(dependencyKey.String(d.name)))
+			}
+			return nil
+		}, healthy)
+		if err != nil {
+			log.Printf("failed to register service health callback: %v", err)
+		}
+	}
+
+	h.check(ctx)
+	ticker := time.NewTicker(healthCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			h.mu.Lock()
+			h.draining = true
+			h.mu.Unlock()
+			h.server.Shutdown()
+			return
+		case <-ticker.C:
+			h.check(ctx)
+		}
+	}
+}
+
+// check runs every dependency check and updates the statuses that changed.
+func (h *healthChecker) check(ctx context.Context) {
+	status := healthpb.HealthCheckResponse_SERVING
+	for _, d := range h.deps {
+		cctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+		err := d.check(cctx)
+		cancel()
+		if ctx.Err() != nil {
+			return
+		}
+
+		h.mu.Lock()
+		prev := h.errs[d.name]
+		h.errs[d.name] = err
+		h.mu.Unlock()
+		if err != nil {
+			status = healthpb.HealthCheckResponse_NOT_SERVING
+		}
+		switch {
+		case err != nil && prev == nil:
+			slog.WarnContext(ctx, "dependency unhealthy", "dependency", d.name, "error", err)
+			h.server.SetServingStatus(d.name, healthpb.HealthCheckResponse_NOT_SERVING)
+			h.count(ctx, d.name, false)
+		case err == nil && prev != nil:
+			slog.InfoContext(ctx, "dependency healthy again", "dependency", d.name)
+			h.server.SetServingStatus(d.name, healthpb.HealthCheckResponse_SERVING)
+			h.count(ctx, d.name, true)
+		}
+	}
+
+	h.mu.Lock()
+	changed := status != h.status
+	h.status = status
+	h.mu.Unlock()
+	if changed {
+		slog.InfoContext(ctx, "service health changed", "status", status.String())
+		h.set(status)
+		h.count(ctx, h.service, status == healthpb.HealthCheckResponse_SERVING)
+	}
+}
+
+// set reports the status of the service as a whole.
+func (h *healthChecker) set(status healthpb.HealthCheckResponse_ServingStatus) {
+	h.server.SetServingStatus("", status)
+	h.server.SetServingStatus(h.service, status)
+}
+
+func (h *healthChecker) count(ctx context.Context, name string, healthy bool) {
+	if h.transitions != nil {
+		h.transitions.Add(ctx, 1, metric.WithAttributes(dependencyKey.String(name), attribute.Bool("healthy", healthy)))
+	}
+}
+
+// registerProbes serves the liveness and readiness probes of h on r. They
+// are registered before the middleware of the service, so the probes are
+// neither traced, sampled nor measured.
+func registerProbes(r *gin.Engine, h *healthChecker) {
+	r.GET("/healthz", h.Healthz)
+	r.GET("/readyz", h.Readyz)
+}
+
+// Healthz answers the liveness probe: the process serves requests.
+func (h *healthChecker) Healthz(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// Readyz answers the readiness probe with 503, and the failing dependencies,
+// while a dependency is unhealthy or the service drains.
+func (h *healthChecker) Readyz(c *gin.Context) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	deps := gin.H{}
+	for _, d := range h.deps {
+		if err := h.errs[d.name]; err != nil {
+			deps[d.name] = err.Error()
+		} else {
+			deps[d.name] = "ok"
+		}
+	}
+	switch {
+	case h.draining:
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "draining", "dependencies": deps})
+	case h.status != healthpb.HealthCheckResponse_SERVING:
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "not ready", "dependencies": deps})
+	default:
+		c.JSON(http.StatusOK, gin.H{"status": "ready", "dependencies": deps})
+	}
+}
@@ -0,0 +1,49 @@
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+
+	"test-jaeger/internal/telemetry"
+)
+
+// heartbeat emits a "heartbeat" span and increments service.heartbeats every
+// interval until ctx is done, so a backend can tell a service that stopped
+// reporting from one without traffic. Both carry the uptime of the service
+// and the hash of its configuration, to spot restarts and config changes.
+// The span is sampled whatever the sampler, see telemetry.AlwaysSample.
+func heartbeat(ctx context.Context, interval time.Duration, configHash string) {
+	tracer := otel.Tracer(instrumentationName)
+	beats, err := otel.Meter(instrumentationName).Int64Counter("service.heartbeats",
+		metric.WithDescription("Number of heartbeats emitted by the service"))
+	if err != nil {
+		log.Printf("failed to create service.heartbeats counter: %v", err)
+	}
+	started := time.Now()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		attrs := []attribute.KeyValue{
+			attribute.String("config.hash", configHash),
+			attribute.Float64("service.uptime_s", time.Since(started).Seconds()),
+		}
+		_, span := tracer.Start(context.Background(), "heartbeat", telemetry.AlwaysSample())
+		span.SetAttributes(attrs...)
+		span.End()
+		if beats != nil {
+			beats.Add(ctx, 1, metric.WithAttributes(attribute.String("config.hash", configHash)))
+		}
+	}
+}
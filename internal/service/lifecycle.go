@@ -0,0 +1,123 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// crashLoopWindow and crashLoopStarts define a crash loop: at least
+// crashLoopStarts starts within crashLoopWindow, the previous run having
+// exited abnormally.
+const (
+	crashLoopWindow = 5 * time.Minute
+	crashLoopStarts = 3
+)
+
+// lifecycleState is persisted between runs of a service.
+type lifecycleState struct {
+	Restarts int         `json:"restarts"`
+	Running  bool        `json:"running"`
+	Starts   []time.Time `json:"starts"`
+}
+
+// lifecycle tracks the restarts of a service in a file of the temporary
+// directory. A run that ends without Close, e.g. after a crash or a fatal
+// error, is seen as an abnormal exit by the next one.
+type lifecycle struct {
+	path            string
+	state           lifecycleState
+	started         time.Time
+	abnormalRestart bool
+	crashLoop       bool
+}
+
+func startLifecycle(service string) *lifecycle {
+	l := &lifecycle{
+		path:    filepath.Join(os.TempDir(), service+".lifecycle.json"),
+		started: time.Now(),
+	}
+	if data, err := os.ReadFile(l.path); err == nil {
+		if err := json.Unmarshal(data, &l.state); err != nil {
+			log.Printf("ignoring corrupt lifecycle state %s: %v", l.path, err)
+			l.state = lifecycleState{}
+		} else {
+			l.state.Restarts++
+			l.abnormalRestart = l.state.Running
+		}
+	}
+
+	starts := []time.Time{l.started}
+	for _, t := range l.state.Starts {
+		if l.started.Sub(t) < crashLoopWindow {
+			starts = append(starts, t)
+		}
+	}
+	l.state.Starts = starts
+	l.crashLoop = l.abnormalRestart && len(starts) >= crashLoopStarts
+	if l.crashLoop {
+		log.Printf("%s is crash looping: %d starts in the last %s", service, len(starts), crashLoopWindow)
+	}
+
+	l.state.Running = true
+	l.save()
+	return l
+}
+
+// attributes describe the restart history as resource attributes.
+func (l *lifecycle) attributes() []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.Int("service.restart_count", l.state.Restarts),
+		attribute.Bool("service.previous_exit_abnormal", l.abnormalRestart),
+		attribute.Bool("service.crash_loop", l.crashLoop),
+	}
+}
+
+// observe reports service.uptime and service.restarts at every collection.
+func (l *lifecycle) observe() {
+	meter := otel.Meter(instrumentationName)
+	uptime, err := meter.Float64ObservableGauge("service.uptime",
+		metric.WithDescription("Time since the service started"), metric.WithUnit("s"))
+	if err != nil {
+		log.Printf("failed to create service.uptime gauge: %v", err)
+		return
+	}
+	restarts, err := meter.Int64ObservableGauge("service.restarts",
+		metric.WithDescription("Number of times the service was restarted"))
+	if err != nil {
+		log.Printf("failed to create service.restarts gauge: %v", err)
+		return
+	}
+	_, err = meter.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+		o.ObserveFloat64(uptime, time.Since(l.started).Seconds())
+		o.ObserveInt64(restarts, int64(l.state.Restarts),
+			metric.WithAttributes(attribute.Bool("service.crash_loop", l.crashLoop)))
+		return nil
+	}, uptime, restarts)
+	if err != nil {
+		log.Printf("failed to register lifecycle callback: %v", err)
+	}
+}
+
+// stop records a clean exit.
+func (l *lifecycle) stop() {
+	l.state.Running = false
+	l.save()
+}
+
+func (l *lifecycle) save() {
+	data, err := json.Marshal(l.state)
+	if err == nil {
+		err = os.WriteFile(l.path, data, 0o600)
+	}
+	if err != nil {
+		log.Printf("failed to save lifecycle state: %v", err)
+	}
+}
@@ -0,0 +1,179 @@
+package service
+
+import (
+	"fmt"
+	"log"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// defaultRetryAfter is the Retry-After of a route put into maintenance
+// without one.
+const defaultRetryAfter = 5 * time.Minute
+
+// alwaysUp lists the route prefixes that cannot be put into maintenance: the
+// health checks and the debug routes. The toggle itself is on the admin
+// server, see Service.Admin.
+var alwaysUp = []string{"/healthz", "/readyz", "/debug/"}
+
+// maintenance rejects the requests to the routes put into maintenance with
+// 503 and a Retry-After header until the time they are expected back, for demoing planned downtime. The routes are
+// toggled at runtime through /admin/maintenance. Each rejection sets
+// maintenance.rejected on the span of the request and is counted in
+// http.server.maintenance.rejections by route.
+type maintenance struct {
+	router     *gin.Engine
+	rejections metric.Int64Counter
+
+	mu     sync.RWMutex
+	routes map[string]time.Time // route to the time it is expected back
+}
+
+func newMaintenance(router *gin.Engine) *maintenance {
+	rejections, err := otel.Meter(instrumentationName).Int64Counter("http.server.maintenance.rejections",
+		metric.WithDescription("Requests rejected because their route is in maintenance"))
+	if err != nil {
+		log.Printf("failed to create http.server.maintenance.rejections counter: %v", err)
+	}
+	return &maintenance{
+		router:     router,
+		rejections: rejections,
+		routes:     make(map[string]time.Time),
+	}
+}
+
+// Middleware rejects the requests to the routes in maintenance.
+func (m *maintenance) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		route := c.FullPath()
+		m.mu.RLock()
+		until, down := m.routes[route]
+		m.mu.RUnlock()
+		if down && !time.Now().Before(until) {
+			m.expire(c, route, until)
+			down = false
+		}
+		if !down {
+			c.Next()
+			return
+		}
+
+		retryAfter := max(int(time.Until(until).Round(time.Second)/time.Second), 1)
+		ctx := c.Request.Context()
+		trace.SpanFromContext(ctx).SetAttributes(
+			attribute.Bool("maintenance.rejected", true),
+			attribute.Int("http.retry_after", retryAfter))
+		if m.rejections != nil {
+			m.rejections.Add(ctx, 1, metric.WithAttributes(
+				semconv.HTTPRouteKey.String(route), semconv.HTTPRequestMethodKey.String(c.Request.Method)))
+		}
+		c.Header("Retry-After", strconv.Itoa(retryAfter))
+		c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{
+			"error": "route in maintenance", "route": route, "retry_after": retryAfter})
+	}
+}
+
+// expire puts route back in service once the time it was expected back has
+// passed, unless it was put into maintenance again meanwhile.
+func (m *maintenance) expire(c *gin.Context, route string, until time.Time) {
+	m.mu.Lock()
+	expired := m.routes[route].Equal(until)
+	if expired {
+		delete(m.routes, route)
+	}
+	m.mu.Unlock()
+	if expired {
+		slog.InfoContext(c.Request.Context(), "route back in service", "route", route, "until", until)
+	}
+}
+
+// maintenanceRoute is a route in maintenance as listed by the handlers.
+type maintenanceRoute struct {
+	Route string    `json:"route"`
+	Until time.Time `json:"until"`
+}
+
+// List serves GET /admin/maintenance, the routes in maintenance.
+func (m *maintenance) List(c *gin.Context) {
+	now := time.Now()
+	m.mu.RLock()
+	routes := make([]maintenanceRoute, 0, len(m.routes))
+	for route, until := range m.routes {
+		if now.Before(until) {
+			routes = append(routes, maintenanceRoute{Route: route, Until: until})
+		}
+	}
+	m.mu.RUnlock()
+	sort.Slice(routes, func(i, j int) bool { return routes[i].Route < routes[j].Route })
+	c.JSON(http.StatusOK, routes)
+}
+
+// Enable serves PUT /admin/maintenance?route=/hello&retry_after=10m, putting
+// a route of the router, as registered, into maintenance for retry_after,
+// after which it is back in service on its own. Enabling it again moves the
+// time it is expected back.
+func (m *maintenance) Enable(c *gin.Context) {
+	route, err := m.route(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	retryAfter := defaultRetryAfter
+	if v := c.Query("retry_after"); v != "" {
+		if retryAfter, err = time.ParseDuration(v); err != nil || retryAfter <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid retry_after %q, want a positive duration", v)})
+			return
+		}
+	}
+	until := time.Now().Add(retryAfter)
+	m.mu.Lock()
+	m.routes[route] = until
+	m.mu.Unlock()
+	slog.InfoContext(c.Request.Context(), "route put into maintenance", "route", route, "until", until)
+	c.JSON(http.StatusOK, maintenanceRoute{Route: route, Until: until})
+}
+
+// Disable serves DELETE /admin/maintenance?route=/hello, putting the route
+// back in service.
+func (m *maintenance) Disable(c *gin.Context) {
+	route := c.Query("route")
+	m.mu.Lock()
+	_, down := m.routes[route]
+	delete(m.routes, route)
+	m.mu.Unlock()
+	if !down {
+		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("route %q is not in maintenance", route)})
+		return
+	}
+	slog.InfoContext(c.Request.Context(), "route back in service", "route", route)
+	c.Status(http.StatusNoContent)
+}
+
+// route returns the route of the request to toggle, checking it is
+// registered on the router and may be put into maintenance.
+func (m *maintenance) route(c *gin.Context) (string, error) {
+	route := c.Query("route")
+	for _, prefix := range alwaysUp {
+		if route == strings.TrimSuffix(prefix, "/") || strings.HasPrefix(route, prefix) {
+			return "", fmt.Errorf("route %q always stays up", route)
+		}
+	}
+	for _, r := range m.router.Routes() {
+		if r.Path == route {
+			return route, nil
+		}
+	}
+	return "", fmt.Errorf("no route %q", route)
+}
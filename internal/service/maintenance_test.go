@@ -0,0 +1,40 @@
+package service
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestMaintenanceExpires(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		name  string
+		until time.Duration
+		want  int
+	}{
+		{"in maintenance", time.Minute, http.StatusServiceUnavailable},
+		{"expected back", -time.Second, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			m := newMaintenance(router)
+			router.Use(m.Middleware())
+			router.GET("/hello", func(c *gin.Context) { c.Status(http.StatusOK) })
+			m.routes["/hello"] = time.Now().Add(tt.until)
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/hello", nil))
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+			if _, down := m.routes["/hello"]; down != (tt.want == http.StatusServiceUnavailable) {
+				t.Errorf("route still in maintenance = %v", down)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package service
+
+import (
+	"log"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/metric"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+
+	"test-jaeger/internal/telemetry"
+)
+
+const instrumentationName = "test-jaeger/internal/service"
+
+// MetricsMiddleware records the duration of every request, in milliseconds,
+// as the http.server.duration histogram and the size of its response body as
+// http.server.response.size, with the route, method and status code as
+// attributes. Shadow traffic is not recorded so that mirrored
+// requests do not skew the latency of the service.
+//
+// Measurements are made in the request context left by the handlers, so a
+// handler that stores its span there with c.Request.WithContext links the
+// histograms to its trace through exemplars.
+func MetricsMiddleware() gin.HandlerFunc {
+	duration, err := otel.Meter(instrumentationName).Float64Histogram("http.server.duration",
+		metric.WithDescription("Duration of inbound HTTP requests"),
+		metric.WithUnit("ms"))
+	if err != nil {
+		log.Printf("failed to create http.server.duration histogram: %v", err)
+	}
+	size, err := otel.Meter(instrumentationName).Int64Histogram("http.server.response.size",
+		metric.WithDescription("Size of the bodies of HTTP responses"),
+		metric.WithUnit("By"))
+	if err != nil {
+		log.Printf("failed to create http.server.response.size histogram: %v", err)
+	}
+
+	return func(c *gin.Context) {
+		start := time.Now()
+		w := telemetry.NewResponseWriterWrapper(c.Writer)
+		c.Writer = w
+		c.Next()
+
+		ctx := c.Request.Context()
+		if telemetry.IsShadow(ctx) {
+			return
+		}
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		attrs := metric.WithAttributes(
+			semconv.HTTPRouteKey.String(route),
+			semconv.HTTPRequestMethodKey.String(c.Request.Method),
+			semconv.HTTPResponseStatusCodeKey.Int(w.StatusCode()))
+		if duration != nil {
+			duration.Record(ctx, float64(time.Since(start))/float64(time.Millisecond), attrs)
+		}
+		if size != nil {
+			size.Record(ctx, w.BytesWritten(), attrs)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package service
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"test-jaeger/internal/telemetry"
+)
+
+// samplingAdmin swaps the sampler of the service while it runs, to sample
+// more of a misbehaving service for a while, or stop sampling it, without a
+// restart. The swap is lost on restart.
+type samplingAdmin struct {
+	sampler *telemetry.SamplerSwitch
+}
+
+type samplingState struct {
+	Sampler    string  `json:"sampler"`
+	Arg        float64 `json:"arg"`
+	Configured bool    `json:"configured"`
+}
+
+func (a samplingAdmin) state() samplingState {
+	name, arg, configured := a.sampler.Active()
+	return samplingState{Sampler: name, Arg: arg, Configured: configured}
+}
+
+// Get serves GET /admin/sampling, the active sampler.
+func (a samplingAdmin) Get(c *gin.Context) {
+	c.JSON(http.StatusOK, a.state())
+}
+
+// Set serves PUT /admin/sampling?sampler=parentbased_traceidratio&arg=0.1,
+// swapping the sampler for the one named like the sampler setting, e.g.
+// always_off to stop sampling or traceidratio with the ratio as arg.
+func (a samplingAdmin) Set(c *gin.Context) {
+	name := c.Query("sampler")
+	arg := telemetry.DefaultSamplerArg(name)
+	if v := c.Query("arg"); v != "" {
+		var err error
+		if arg, err = strconv.ParseFloat(v, 64); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid arg %q, want a number", v)})
+			return
+		}
+	}
+	if name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing sampler"})
+		return
+	}
+	if err := a.sampler.Set(name, arg); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	slog.InfoContext(c.Request.Context(), "sampler swapped", "sampler", name, "arg", arg)
+	c.JSON(http.StatusOK, a.state())
+}
+
+// Reset serves DELETE /admin/sampling, going back to the configured
+// sampler.
+func (a samplingAdmin) Reset(c *gin.Context) {
+	a.sampler.Reset()
+	state := a.state()
+	slog.InfoContext(c.Request.Context(), "sampler reset", "sampler", state.Sampler, "arg", state.Arg)
+	c.JSON(http.StatusOK, state)
+}
@@ -0,0 +1,313 @@
+// Package service holds the runtime shared by the demo services: the
+// configuration, the tracer, meter and logger providers, a router with the
+// common middleware and debug routes, and graceful shutdown. A service only
+// adds its own routes, so a feature added here lands in every service at once.
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"google.golang.org/grpc"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+
+	"test-jaeger/internal/config"
+	"test-jaeger/internal/httpclient"
+	"test-jaeger/internal/replay"
+	"test-jaeger/internal/store"
+	"test-jaeger/internal/telemetry"
+	"test-jaeger/internal/telemetry/logs"
+	"test-jaeger/internal/telemetry/metrics"
+	"test-jaeger/internal/telemetry/profiling"
+)
+
+// StatusClientClosedRequest is the status of a request the client cancelled
+// before it was answered, as logged by nginx. Nothing reaches the client, it
+// only shows on the span and in the metrics.
+const StatusClientClosedRequest = 499
+
+// Service is a demo service being set up. Register routes on Router, then
+// call Run, and defer Close right after New.
+type Service struct {
+	Config config.Config
+	Tracer *sdktrace.TracerProvider
+	Meters *sdkmetric.MeterProvider
+	Logs   *sdklog.LoggerProvider
+	Router *gin.Engine
+	// Admin holds the routes changing the state of the service, e.g.
+	// /admin/maintenance. It is only served on Config.AdminListen, next to
+	// the pprof profiles, never on the public port: without an admin
+	// address the routes are off.
+	Admin *gin.Engine
+	Store store.Store
+	// GRPC is served on Config.GRPCListen when set, services may register
+	// theirs on it before Run. It serves the health checks, see
+	// healthChecker, traces and logs every call, and serves reflection with
+	// Config.GRPCReflection.
+	GRPC *grpc.Server
+
+	listener  net.Listener
+	recorder  *replay.Recorder
+	profiler  *profiling.Profiler
+	coldStart *coldStart
+	lifecycle *lifecycle
+	ctx       context.Context
+	stop      context.CancelFunc
+}
+
+// New parses the command line on top of defaults, see config.Parse, installs
+// the telemetry providers, opens the data store and builds the router. The
+// returned service stops on SIGINT or SIGTERM.
+func New(defaults config.Config) (*Service, error) {
+	cold := newColdStart()
+	cfg, err := config.Parse(defaults)
+	if err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+	cold.step("config.load")
+
+	s := &Service{Config: cfg, coldStart: cold}
+	httpclient.SetEgressAllowlist(cfg.EgressAllowlist)
+	s.ctx, s.stop = signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+
+	// Listening right away resolves a port 0 and reports a port in use
+	// before anything starts
+	if s.listener, err = net.Listen("tcp", cfg.Listen); err != nil {
+		s.stop()
+		return nil, err
+	}
+	host, _, _ := net.SplitHostPort(cfg.Listen)
+	port := s.listener.Addr().(*net.TCPAddr).Port
+
+	// Instances listening on different ports restart independently
+	s.lifecycle = startLifecycle(cfg.ServiceName + "-" + strconv.Itoa(port))
+	masker := cfg.Masker()
+	tcfg := cfg.Telemetry()
+	tcfg.Masker = masker
+	tcfg.ResourceAttributes = append(tcfg.ResourceAttributes, semconv.ServerPort(port))
+	if host != "" {
+		tcfg.ResourceAttributes = append(tcfg.ResourceAttributes, semconv.ServerAddress(host))
+	}
+	tcfg.ResourceAttributes = append(tcfg.ResourceAttributes, s.lifecycle.attributes()...)
+	cg := detectCgroup()
+	tcfg.ResourceAttributes = append(tcfg.ResourceAttributes, cg.attributes()...)
+
+	// Kept in memory for /debug/correlate and /debug/traces
+	spans, logRing, metricReader := telemetry.NewSpanCapture(cfg.DebugSpans), telemetry.NewLogRing(0), sdkmetric.NewManualReader()
+	tcfg.SpanCapture = spans
+	costs := telemetry.NewCostEstimator()
+	tcfg.CostEstimator = costs
+	sampler := telemetry.NewSamplerSwitch()
+	tcfg.SamplerSwitch = sampler
+
+	if s.Tracer, err = telemetry.NewTracerProvider(s.ctx, tcfg); err != nil {
+		s.listener.Close()
+		s.stop()
+		return nil, fmt.Errorf("initialize tracing: %w", err)
+	}
+	if s.Meters, err = metrics.NewMeterProvider(s.ctx, tcfg, metricReader); err != nil {
+		telemetry.Shutdown(s.Tracer)
+		s.listener.Close()
+		s.stop()
+		return nil, fmt.Errorf("initialize metrics: %w", err)
+	}
+	if s.Logs, err = logs.NewLoggerProvider(s.ctx, tcfg); err != nil {
+		metrics.Shutdown(s.Meters)
+		telemetry.Shutdown(s.Tracer)
+		s.listener.Close()
+		s.stop()
+		return nil, fmt.Errorf("initialize logs: %w", err)
+	}
+	// The standard logger keeps reporting the failures of the telemetry
+	// pipeline itself to stderr, exporting them could loop.
+	link := telemetry.NewTraceLink(tcfg)
+	slog.SetDefault(slog.New(telemetry.NewLogHandler(io.MultiWriter(os.Stdout, logRing), &telemetry.LogHandlerOptions{Export: true, TraceLink: link})))
+	log.SetOutput(os.Stderr)
+	log.SetFlags(log.LstdFlags)
+	s.lifecycle.observe()
+	cg.observe()
+	// A failing profiler leaves the service unprofiled, like a failing exporter
+	if s.profiler, err = profiling.Start(tcfg, cfg.Profiler()); err != nil {
+		log.Printf("failed to start profiling: %v", err)
+	}
+	cold.step("telemetry.init")
+
+	if s.Store, err = store.Open(s.ctx, cfg.Store); err != nil {
+		logs.Shutdown(s.Logs)
+		metrics.Shutdown(s.Meters)
+		telemetry.Shutdown(s.Tracer)
+		s.listener.Close()
+		s.stop()
+		return nil, err
+	}
+	cold.step("store.connect")
+
+	if cfg.Record != "" {
+		if s.recorder, err = replay.NewRecorder(cfg.Record, masker); err != nil {
+			s.Close()
+			return nil, fmt.Errorf("open recording: %w", err)
+		}
+	}
+
+	health := newHealthChecker(cfg.ServiceName,
+		dependency{name: "store", check: s.Store.Ping},
+		dependency{name: "exporter", check: func(context.Context) error { return telemetry.CheckExporters() }})
+
+	s.Router = gin.New()
+	s.Router.Use(gin.Logger(), telemetry.Recover())
+	registerProbes(s.Router, health)
+	s.Router.Use(telemetry.ExtractContext())
+	s.Router.Use(telemetry.CollectAttributes())
+	s.Router.Use(telemetry.RecordRoute())
+	s.Router.Use(telemetry.TraceRequests())
+	s.Router.Use(cold.Middleware())
+	if s.recorder != nil {
+		s.Router.Use(s.recorder.Middleware())
+	}
+	s.Router.Use(MetricsMiddleware())
+	maintenance := newMaintenance(s.Router)
+	s.Router.Use(maintenance.Middleware())
+	s.Router.Use(newDuplicateDetector().Middleware())
+	if gin.IsDebugging() {
+		s.Router.Use(link.Middleware())
+	}
+	if cfg.ServiceVersion != "" {
+		s.Router.Use(telemetry.AdvertiseVersion(cfg.ServiceVersion))
+	}
+	s.Router.GET("/debug/telemetry-cost", costs.Handler)
+	s.Router.GET("/debug/telemetry", telemetryInfo{cfg: tcfg, sampler: sampler}.Handler)
+	correlate := &correlator{spans: spans, logs: logRing, reader: metricReader, masker: masker}
+	s.Router.GET("/debug/correlate/:traceID", correlate.Handler)
+	traces := traceBrowser{spans: spans, masker: masker}
+	s.Router.GET("/debug/traces", traces.List)
+	s.Router.GET("/debug/traces/:traceID", traces.Get)
+	s.Admin = gin.New()
+	s.Admin.Use(gin.Logger(), telemetry.Recover())
+	s.Admin.Use(telemetry.ExtractContext(), telemetry.CollectAttributes(), telemetry.RecordRoute(), telemetry.TraceRequests())
+	s.Admin.GET("/admin/maintenance", maintenance.List)
+	s.Admin.PUT("/admin/maintenance", maintenance.Enable)
+	s.Admin.DELETE("/admin/maintenance", maintenance.Disable)
+	s.Admin.POST("/admin/flush", flusher{provider: s.Tracer}.Flush)
+	sampling := samplingAdmin{sampler: sampler}
+	s.Admin.GET("/admin/sampling", sampling.Get)
+	s.Admin.PUT("/admin/sampling", sampling.Set)
+	s.Admin.DELETE("/admin/sampling", sampling.Reset)
+	drift := newDriftChecker(cfg, masker)
+	s.Router.GET("/debug/config", drift.Handler)
+	if cfg.Path() != "" {
+		go drift.run(s.ctx)
+	}
+
+	s.GRPC = grpc.NewServer(
+		grpc.ChainUnaryInterceptor(telemetry.UnaryServerInterceptor()),
+		grpc.ChainStreamInterceptor(telemetry.StreamServerInterceptor()))
+	if cfg.GRPCReflection {
+		reflection.Register(s.GRPC)
+	}
+	healthpb.RegisterHealthServer(s.GRPC, health.server)
+	go health.run(s.ctx)
+
+	if cfg.HeartbeatInterval != "" {
+		interval, _ := time.ParseDuration(cfg.HeartbeatInterval)
+		go heartbeat(s.ctx, interval, cfg.Hash())
+	}
+	announceStart(s.ctx, cfg, tcfg)
+	cold.step("router.setup")
+	return s, nil
+}
+
+// Context is canceled when the service is asked to stop.
+func (s *Service) Context() context.Context { return s.ctx }
+
+// Run serves the router, and the gRPC and admin servers when enabled, on the
+// configured addresses until the service is asked to stop, then waits for
+// in-flight requests to finish.
+func (s *Service) Run() error {
+	srv := &http.Server{Handler: s.Router}
+	var grpcErr chan error
+	if s.Config.GRPCListen != "" {
+		l, err := net.Listen("tcp", s.Config.GRPCListen)
+		if err != nil {
+			return err
+		}
+		grpcErr = make(chan error, 1)
+		go func() { grpcErr <- s.GRPC.Serve(l) }()
+		slog.Info("grpc server started", "listen", s.Config.GRPCListen)
+	}
+	var admin *http.Server
+	if s.Config.AdminListen != "" {
+		admin = &http.Server{Addr: s.Config.AdminListen, Handler: adminHandler(s.Admin)}
+		l, err := net.Listen("tcp", s.Config.AdminListen)
+		if err != nil {
+			s.GRPC.Stop()
+			return err
+		}
+		go admin.Serve(l)
+		slog.Info("admin server started", "listen", s.Config.AdminListen)
+	}
+	defer func() {
+		if admin != nil {
+			admin.Close()
+		}
+	}()
+
+	served := make(chan error, 1)
+	go func() { served <- srv.Serve(s.listener) }()
+	slog.Info("server started", "listen", s.listener.Addr().String())
+	s.coldStart.finish(s.ctx, "routes.register")
+
+	select {
+	case err := <-served:
+		s.GRPC.Stop()
+		return err
+	case <-s.ctx.Done():
+	}
+	// Serve returns as soon as Shutdown is called, Shutdown only once the
+	// in-flight requests are done, so the deferred Close does not pull the
+	// store and the providers from under them
+	err := srv.Shutdown(context.Background())
+	s.GRPC.GracefulStop()
+	if grpcErr != nil {
+		err = errors.Join(err, <-grpcErr)
+	}
+	return err
+}
+
+// Close closes the data store, flushes the buffered telemetry, stops the
+// providers and records the exit as a clean one.
+func (s *Service) Close() {
+	s.stop()
+	s.listener.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), telemetry.ShutdownTimeout)
+	defer cancel()
+	if err := s.Store.Close(ctx); err != nil {
+		log.Printf("failed to close store: %v", err)
+	}
+	if s.recorder != nil {
+		if err := s.recorder.Close(); err != nil {
+			log.Printf("failed to close recording: %v", err)
+		}
+	}
+	s.profiler.Stop()
+	logs.Shutdown(s.Logs)
+	metrics.Shutdown(s.Meters)
+	telemetry.Shutdown(s.Tracer)
+	s.lifecycle.stop()
+}
@@ -0,0 +1,96 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"log/slog"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+
+	"test-jaeger/internal/config"
+	"test-jaeger/internal/telemetry"
+)
+
+// announceStart emits a single "service.start" span and log record carrying
+// how the instance was configured once the environment and the defaults are
+// applied: exporters, sampler, propagators, resource and the optional
+// features turned on. Every backend thereby keeps a queryable record of the
+// configuration of each instance, next to the config.hash of its heartbeats.
+func announceStart(ctx context.Context, cfg config.Config, tcfg telemetry.Config) {
+	resolved, err := tcfg.Resolved()
+	if err != nil {
+		log.Printf("failed to resolve telemetry configuration: %v", err)
+		return
+	}
+	store, _, _ := strings.Cut(cfg.Store, "://")
+	attrs := []attribute.KeyValue{
+		attribute.String("config.hash", cfg.Hash()),
+		attribute.String("config.file", cfg.Path()),
+		attribute.String("config.exporter.backend", string(resolved.Backend)),
+		attribute.String("config.exporter.endpoint", resolved.Endpoint),
+		attribute.String("config.sampler", resolved.Sampler),
+		attribute.Float64("config.sampler.arg", *resolved.SamplerArg),
+		attribute.StringSlice("config.propagators", resolved.Propagators),
+		// The scheme only, the DSN may hold credentials
+		attribute.String("config.store", store),
+	}
+	var exporters []string
+	for _, e := range resolved.Exporters {
+		exporters = append(exporters, fmt.Sprintf("%s %s", e.Backend, e.Endpoint))
+	}
+	if len(exporters) > 0 {
+		attrs = append(attrs, attribute.StringSlice("config.exporters", exporters))
+	}
+	if res, err := telemetry.NewResource(ctx, tcfg); err == nil {
+		var kvs []string
+		for _, kv := range res.Attributes() {
+			kvs = append(kvs, fmt.Sprintf("%s=%s", kv.Key, kv.Value.Emit()))
+		}
+		attrs = append(attrs, attribute.StringSlice("config.resource", kvs))
+	}
+	for _, f := range features(cfg) {
+		attrs = append(attrs, attribute.Bool("config.feature."+f.name, f.on))
+	}
+
+	_, span := otel.Tracer(instrumentationName).Start(ctx, "service.start")
+	span.SetAttributes(attrs...)
+	span.End()
+
+	args := make([]any, 0, len(attrs))
+	for _, kv := range attrs {
+		args = append(args, slog.Any(string(kv.Key), kv.Value.AsInterface()))
+	}
+	slog.InfoContext(ctx, "service.start", args...)
+}
+
+type feature struct {
+	name string
+	on   bool
+}
+
+// features lists the optional features of cfg and whether they are on.
+func features(cfg config.Config) []feature {
+	return []feature{
+		{"grpc", cfg.GRPCListen != ""},
+		{"grpc_reflection", cfg.GRPCListen != "" && cfg.GRPCReflection},
+		{"pprof", cfg.AdminListen != ""},
+		{"profiling", cfg.Profiling.ServerAddress != ""},
+		{"aws_xray", cfg.AWSXRay},
+		{"heartbeat", cfg.HeartbeatInterval != ""},
+		{"sampling_report", cfg.Sampler.ReportInterval != ""},
+		{"record", cfg.Record != ""},
+		{"load_balancer", len(cfg.LoadBalancer.Instances) > 0},
+		{"egress_allowlist", len(cfg.EgressAllowlist) > 0},
+		{"masking_defaults", !cfg.Masking.NoDefaults},
+		{"sanitize_urls", cfg.SanitizeURLs},
+		{"sync_export", cfg.SyncExport},
+		{"failover", len(cfg.Exporter.FailoverEndpoints) > 0},
+		{"spool", cfg.Exporter.Spool.Dir != ""},
+		{"shadow", cfg.Shadow.URL != ""},
+		{"canary", cfg.Canary.Baseline != ""},
+		{"config_reload", cfg.Path() != ""},
+	}
+}
@@ -0,0 +1,73 @@
+package service
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/sdk"
+
+	"test-jaeger/internal/telemetry"
+)
+
+// telemetryInfo serves the telemetry setup the instance runs with, as
+// resolved from its configuration, the environment and the defaults, see
+// telemetry.Config.Resolved, so operators can check what a running
+// instance actually exports, where, and how it samples.
+type telemetryInfo struct {
+	cfg     telemetry.Config
+	sampler *telemetry.SamplerSwitch
+}
+
+type telemetryExporter struct {
+	Provider          telemetry.Backend `json:"provider"`
+	Endpoint          string            `json:"endpoint,omitempty"`
+	FailoverEndpoints []string          `json:"failover_endpoints,omitempty"`
+	Compression       string            `json:"compression,omitempty"`
+	Spool             string            `json:"spool,omitempty"`
+}
+
+// Handler serves GET /debug/telemetry.
+func (t telemetryInfo) Handler(c *gin.Context) {
+	resolved, err := t.cfg.Resolved()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	var exporters []telemetryExporter
+	for _, e := range append([]telemetry.Exporter{resolved.Exporter}, resolved.Exporters...) {
+		exporters = append(exporters, telemetryExporter{
+			Provider:          e.Backend,
+			Endpoint:          e.ExporterEndpoint(),
+			FailoverEndpoints: e.FailoverEndpoints,
+			Compression:       e.Compression,
+			Spool:             e.Spool.Dir,
+		})
+	}
+	resource := map[string]string{}
+	if res, err := telemetry.NewResource(context.Background(), t.cfg); err == nil {
+		for _, kv := range res.Attributes() {
+			resource[string(kv.Key)] = kv.Value.Emit()
+		}
+	}
+	name, arg, configured := t.sampler.Active()
+
+	c.JSON(http.StatusOK, gin.H{
+		"service":   resolved.ServiceName,
+		"exporters": exporters,
+		"sampler": gin.H{
+			"name":       name,
+			"arg":        arg,
+			"configured": configured,
+		},
+		"sync_export":     resolved.SyncExport,
+		"propagators":     resolved.Propagators,
+		"resource":        resource,
+		"drop_targets":    resolved.DropSpanTargets,
+		"sanitize_urls":   resolved.SanitizeURLs,
+		"exemplar_filter": resolved.ExemplarFilter,
+		"sdk_version":     sdk.Version(),
+		"api_version":     otel.Version(),
+	})
+}
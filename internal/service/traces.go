@@ -0,0 +1,129 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/trace"
+
+	"test-jaeger/internal/masking"
+	"test-jaeger/internal/telemetry"
+)
+
+// defaultTraceListLimit is the number of traces /debug/traces lists by
+// default.
+const defaultTraceListLimit = 50
+
+// traceBrowser lists and shows the traces of the spans the service kept in
+// memory, see telemetry.SpanCapture, for when the tracing backend is
+// unavailable. Only the spans of this service are known, a trace continued
+// by another one shows its part here.
+type traceBrowser struct {
+	spans  *telemetry.SpanCapture
+	masker *masking.Masker
+}
+
+// traceSummary is a trace in the list of /debug/traces.
+type traceSummary struct {
+	TraceID    string    `json:"trace_id"`
+	Root       string    `json:"root"`
+	Start      time.Time `json:"start"`
+	DurationMS float64   `json:"duration_ms"`
+	Spans      int       `json:"spans"`
+	Errors     int       `json:"errors"`
+}
+
+// List serves GET /debug/traces, the kept traces, the latest first. The
+// list is narrowed by name, a substring of the name of one of their spans,
+// min_duration, e.g. 250ms, error=true for the traces with a failed span,
+// and limit, defaultTraceListLimit by default.
+func (b traceBrowser) List(c *gin.Context) {
+	limit := defaultTraceListLimit
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid limit %q, want a positive number", v)})
+			return
+		}
+		limit = n
+	}
+	var minDuration time.Duration
+	if v := c.Query("min_duration"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid min_duration %q, want a duration", v)})
+			return
+		}
+		minDuration = d
+	}
+	name, onlyErrors := c.Query("name"), c.Query("error") == "true"
+
+	traces := map[string]*traceSummary{}
+	ends := map[string]time.Time{}
+	named, rooted := map[string]bool{}, map[string]bool{}
+	for _, s := range b.spans.Spans(nil) {
+		t, ok := traces[s.TraceID]
+		if !ok {
+			t = &traceSummary{TraceID: s.TraceID, Root: s.Name, Start: s.Start}
+			traces[s.TraceID] = t
+		}
+		// The root, or the earliest span when the root is another service's
+		switch {
+		case s.ParentSpanID == "":
+			t.Root, rooted[s.TraceID] = s.Name, true
+		case !rooted[s.TraceID] && s.Start.Before(t.Start):
+			t.Root = s.Name
+		}
+		if s.Start.Before(t.Start) {
+			t.Start = s.Start
+		}
+		if end := s.Start.Add(time.Duration(s.DurationMS * float64(time.Millisecond))); end.After(ends[s.TraceID]) {
+			ends[s.TraceID] = end
+		}
+		t.Spans++
+		if s.Status == "Error" {
+			t.Errors++
+		}
+		named[s.TraceID] = named[s.TraceID] || name == "" || strings.Contains(s.Name, name)
+	}
+
+	list := []traceSummary{}
+	for id, t := range traces {
+		t.DurationMS = float64(ends[id].Sub(t.Start)) / float64(time.Millisecond)
+		if !named[id] || (onlyErrors && t.Errors == 0) || ends[id].Sub(t.Start) < minDuration {
+			continue
+		}
+		list = append(list, *t)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Start.After(list[j].Start) })
+	c.JSON(http.StatusOK, list[:min(limit, len(list))])
+}
+
+// Get serves GET /debug/traces/:traceID, the kept spans of a trace in the
+// order they started, masked like the exported ones.
+func (b traceBrowser) Get(c *gin.Context) {
+	id, err := trace.TraceIDFromHex(c.Param("traceID"))
+	if err != nil {
+		c.String(http.StatusBadRequest, "invalid trace ID %q", c.Param("traceID"))
+		return
+	}
+	spans := b.spans.Trace(id)
+	if len(spans) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("trace %s is not kept", id)})
+		return
+	}
+	slices.SortStableFunc(spans, func(a, b telemetry.CapturedSpan) int { return a.Start.Compare(b.Start) })
+	data, err := json.Marshal(gin.H{"trace_id": id.String(), "spans": spans})
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	c.Data(http.StatusOK, "application/json; charset=utf-8", b.masker.Body(data))
+}
@@ -0,0 +1,52 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"sync"
+)
+
+// memory keeps the records in process, they are lost on restart.
+type memory struct {
+	mu    sync.RWMutex
+	users map[string]User
+}
+
+func newMemory() *memory {
+	return &memory{users: map[string]User{}}
+}
+
+func (m *memory) GetUsers(context.Context) ([]User, error) {
+	m.mu.RLock()
+	users := make([]User, 0, len(m.users))
+	for _, u := range m.users {
+		users = append(users, u)
+	}
+	m.mu.RUnlock()
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+	return users, nil
+}
+
+func (m *memory) GetUser(_ context.Context, id string) (User, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	u, ok := m.users[id]
+	if !ok {
+		return User{}, ErrNotFound
+	}
+	return u, nil
+}
+
+func (m *memory) CreateUser(_ context.Context, u User) (User, error) {
+	if u.ID == "" {
+		u.ID = newID()
+	}
+	m.mu.Lock()
+	m.users[u.ID] = u
+	m.mu.Unlock()
+	return u, nil
+}
+
+func (m *memory) Ping(context.Context) error { return nil }
+
+func (m *memory) Close(context.Context) error { return nil }
@@ -0,0 +1,74 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// mongoDatabase is used when the DSN does not name a database.
+const mongoDatabase = "demo"
+
+// mongoStore stores the records in MongoDB, one collection per record type.
+type mongoStore struct {
+	client *mongo.Client
+	users  *mongo.Collection
+}
+
+func openMongo(ctx context.Context, u *url.URL) (*mongoStore, error) {
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(u.String()))
+	if err != nil {
+		return nil, err
+	}
+	if err := client.Ping(ctx, nil); err != nil {
+		client.Disconnect(ctx)
+		return nil, err
+	}
+	db := strings.TrimPrefix(u.Path, "/")
+	if db == "" {
+		db = mongoDatabase
+	}
+	return &mongoStore{client: client, users: client.Database(db).Collection("users")}, nil
+}
+
+func (m *mongoStore) GetUsers(ctx context.Context) ([]User, error) {
+	cur, err := m.users.Find(ctx, bson.D{}, options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	users := []User{}
+	if err := cur.All(ctx, &users); err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+func (m *mongoStore) GetUser(ctx context.Context, id string) (User, error) {
+	var u User
+	err := m.users.FindOne(ctx, bson.D{{Key: "_id", Value: id}}).Decode(&u)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return User{}, ErrNotFound
+	}
+	return u, err
+}
+
+func (m *mongoStore) CreateUser(ctx context.Context, u User) (User, error) {
+	if u.ID == "" {
+		u.ID = newID()
+	}
+	_, err := m.users.ReplaceOne(ctx, bson.D{{Key: "_id", Value: u.ID}}, u, options.Replace().SetUpsert(true))
+	return u, err
+}
+
+func (m *mongoStore) Ping(ctx context.Context) error {
+	return m.client.Ping(ctx, nil)
+}
+
+func (m *mongoStore) Close(ctx context.Context) error {
+	return m.client.Disconnect(ctx)
+}
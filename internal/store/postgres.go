@@ -0,0 +1,70 @@
+package store
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const postgresSchema = `CREATE TABLE IF NOT EXISTS users (
+	id    TEXT PRIMARY KEY,
+	name  TEXT NOT NULL,
+	email TEXT NOT NULL
+)`
+
+// postgres stores the records in a PostgreSQL database, creating the tables
+// on open.
+type postgres struct {
+	pool *pgxpool.Pool
+}
+
+func openPostgres(ctx context.Context, dsn string) (*postgres, error) {
+	pool, err := pgxpool.New(ctx, dsn)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := pool.Exec(ctx, postgresSchema); err != nil {
+		pool.Close()
+		return nil, err
+	}
+	return &postgres{pool: pool}, nil
+}
+
+func (p *postgres) GetUsers(ctx context.Context) ([]User, error) {
+	rows, err := p.pool.Query(ctx, `SELECT id, name, email FROM users ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowToStructByPos[User])
+}
+
+func (p *postgres) GetUser(ctx context.Context, id string) (User, error) {
+	var u User
+	err := p.pool.QueryRow(ctx, `SELECT id, name, email FROM users WHERE id = $1`, id).
+		Scan(&u.ID, &u.Name, &u.Email)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return User{}, ErrNotFound
+	}
+	return u, err
+}
+
+func (p *postgres) CreateUser(ctx context.Context, u User) (User, error) {
+	if u.ID == "" {
+		u.ID = newID()
+	}
+	_, err := p.pool.Exec(ctx, `INSERT INTO users (id, name, email) VALUES ($1, $2, $3)
+		ON CONFLICT (id) DO UPDATE SET name = EXCLUDED.name, email = EXCLUDED.email`,
+		u.ID, u.Name, u.Email)
+	return u, err
+}
+
+func (p *postgres) Ping(ctx context.Context) error {
+	return p.pool.Ping(ctx)
+}
+
+func (p *postgres) Close(context.Context) error {
+	p.pool.Close()
+	return nil
+}
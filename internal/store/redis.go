@@ -0,0 +1,80 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// redisUsers is the hash holding the users as JSON, keyed by ID.
+const redisUsers = "users"
+
+// redisStore stores the records as JSON in Redis hashes.
+type redisStore struct {
+	client *redis.Client
+}
+
+func openRedis(ctx context.Context, dsn string) (*redisStore, error) {
+	opts, err := redis.ParseURL(dsn)
+	if err != nil {
+		return nil, err
+	}
+	client := redis.NewClient(opts)
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, err
+	}
+	return &redisStore{client: client}, nil
+}
+
+func (r *redisStore) GetUsers(ctx context.Context) ([]User, error) {
+	values, err := r.client.HVals(ctx, redisUsers).Result()
+	if err != nil {
+		return nil, err
+	}
+	users := make([]User, 0, len(values))
+	for _, v := range values {
+		var u User
+		if err := json.Unmarshal([]byte(v), &u); err != nil {
+			return nil, err
+		}
+		users = append(users, u)
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+	return users, nil
+}
+
+func (r *redisStore) GetUser(ctx context.Context, id string) (User, error) {
+	v, err := r.client.HGet(ctx, redisUsers, id).Result()
+	if errors.Is(err, redis.Nil) {
+		return User{}, ErrNotFound
+	}
+	if err != nil {
+		return User{}, err
+	}
+	var u User
+	err = json.Unmarshal([]byte(v), &u)
+	return u, err
+}
+
+func (r *redisStore) CreateUser(ctx context.Context, u User) (User, error) {
+	if u.ID == "" {
+		u.ID = newID()
+	}
+	data, err := json.Marshal(u)
+	if err != nil {
+		return User{}, err
+	}
+	return u, r.client.HSet(ctx, redisUsers, u.ID, data).Err()
+}
+
+func (r *redisStore) Ping(ctx context.Context) error {
+	return r.client.Ping(ctx).Err()
+}
+
+func (r *redisStore) Close(context.Context) error {
+	return r.client.Close()
+}
@@ -0,0 +1,112 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+
+	"test-jaeger/internal/telemetry"
+)
+
+// MaxSeedUsers bounds the users a single seeding job creates.
+const MaxSeedUsers = 100000
+
+// seedBatchSize is the number of records created under each seed.batch span,
+// so a large job stays readable in a trace view.
+const seedBatchSize = 100
+
+var (
+	firstNames = []string{"Ada", "Alan", "Barbara", "Dennis", "Edsger", "Frances", "Grace", "Guido",
+		"Hedy", "Ken", "Linus", "Margaret", "Niklaus", "Radia", "Rob", "Sophie", "Tim", "Yukihiro"}
+	lastNames = []string{"Allen", "Hamilton", "Hopper", "Kernighan", "Knuth", "Lamarr", "Liskov",
+		"Lovelace", "Perlman", "Pike", "Ritchie", "Stroustrup", "Thompson", "Torvalds", "Turing", "Wirth"}
+	emailDomains = []string{"example.com", "example.org", "example.net"}
+)
+
+// SeedOptions sizes a seeding job.
+type SeedOptions struct {
+	// Users is the number of users to create, at most MaxSeedUsers.
+	Users int
+	// Seed makes the generated data reproducible: the same seed gives the
+	// same users.
+	Seed uint64
+}
+
+// Seed fills s with fake but plausible users, for load tests and search
+// demos. Their IDs are seed-000001 onwards and every backend upserts, so
+// running it again with the same options leaves the same data. The job is
+// traced as a seed span, with a seed.batch span per hundred records and a
+// seed.progress event per tenth done, and counts the records created in the
+// store.seed.records counter. It returns the number of users created, which
+// is short of opts.Users on error.
+func Seed(ctx context.Context, s Store, opts SeedOptions) (int, error) {
+	if opts.Users < 0 || opts.Users > MaxSeedUsers {
+		return 0, fmt.Errorf("cannot seed %d users, want 0 to %d", opts.Users, MaxSeedUsers)
+	}
+	records, err := otel.Meter(instrumentationName).Int64Counter("store.seed.records",
+		metric.WithDescription("Records created by seeding jobs"),
+		metric.WithUnit("{record}"))
+	if err != nil {
+		log.Printf("failed to create store.seed.records counter: %v", err)
+	}
+	tracer := otel.Tracer(instrumentationName)
+	ctx, span := tracer.Start(ctx, "seed", trace.WithAttributes(
+		attribute.Int("seed.users", opts.Users),
+		attribute.Int64("seed.seed", int64(opts.Seed))))
+	var created int
+	defer func() {
+		span.SetAttributes(attribute.Int("seed.created", created))
+		telemetry.SetStatus(span, 0, err)
+		span.End()
+	}()
+
+	rnd := rand.New(rand.NewPCG(opts.Seed, opts.Seed))
+	counted := metric.WithAttributes(attribute.String("seed.record_type", "user"))
+	step := max(opts.Users/10, 1)
+	for created < opts.Users {
+		n := min(seedBatchSize, opts.Users-created)
+		bctx, batch := tracer.Start(ctx, "seed.batch", trace.WithAttributes(
+			attribute.Int("seed.batch.offset", created),
+			attribute.Int("seed.batch.size", n)))
+		for i := 0; i < n && err == nil; i++ {
+			if err = bctx.Err(); err == nil {
+				_, err = s.CreateUser(bctx, fakeUser(rnd, created+1))
+			}
+			if err == nil {
+				created++
+				if records != nil {
+					records.Add(bctx, 1, counted)
+				}
+				if created%step == 0 || created == opts.Users {
+					span.AddEvent("seed.progress", trace.WithAttributes(
+						attribute.Int("seed.created", created),
+						attribute.Int("seed.percent", created*100/opts.Users)))
+				}
+			}
+		}
+		telemetry.SetStatus(batch, 0, err)
+		batch.End()
+		if err != nil {
+			return created, fmt.Errorf("seed user %d: %w", created+1, err)
+		}
+	}
+	return created, nil
+}
+
+// fakeUser returns the n-th seeded user, named at random from rnd.
+func fakeUser(rnd *rand.Rand, n int) User {
+	first := firstNames[rnd.IntN(len(firstNames))]
+	last := lastNames[rnd.IntN(len(lastNames))]
+	return User{
+		ID:    fmt.Sprintf("seed-%06d", n),
+		Name:  first + " " + last,
+		Email: fmt.Sprintf("%s.%s%d@%s", strings.ToLower(first), strings.ToLower(last), n, emailDomains[rnd.IntN(len(emailDomains))]),
+	}
+}
@@ -0,0 +1,82 @@
+// Package store is the data access layer shared by the demo services. Every
+// backend implements Store and is wrapped by Open with the same database
+// instrumentation, so traces look alike whichever database is configured.
+package store
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+
+	"test-jaeger/pkg/models"
+)
+
+// DefaultDSN keeps the data in memory, for demos without a database.
+const DefaultDSN = "memory://"
+
+// ErrNotFound is returned when a record does not exist.
+var ErrNotFound = errors.New("not found")
+
+// User is the record stored for a user.
+type User = models.User
+
+// Store reads and writes the records of the demo services.
+type Store interface {
+	// GetUsers returns every user ordered by ID.
+	GetUsers(ctx context.Context) ([]User, error)
+	// GetUser returns ErrNotFound when no user has the given ID.
+	GetUser(ctx context.Context, id string) (User, error)
+	// CreateUser stores u, assigning an ID when it has none, and returns it.
+	CreateUser(ctx context.Context, u User) (User, error)
+	// Ping checks that the backend is reachable.
+	Ping(ctx context.Context) error
+	// Close releases the connections of the store.
+	Close(ctx context.Context) error
+}
+
+// Schemes lists the DSN schemes understood by Open.
+var Schemes = []string{"memory", "postgres", "postgresql", "mongodb", "redis"}
+
+// Open connects to the store named by dsn, whose scheme selects the backend:
+// memory://, postgres://, mongodb:// or redis://.
+func Open(ctx context.Context, dsn string) (Store, error) {
+	if dsn == "" {
+		dsn = DefaultDSN
+	}
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("invalid store DSN: %w", err)
+	}
+
+	var s Store
+	var system string
+	switch u.Scheme {
+	case "memory":
+		s, system = newMemory(), "memory"
+	case "postgres", "postgresql":
+		s, err = openPostgres(ctx, dsn)
+		system = "postgresql"
+	case "mongodb":
+		s, err = openMongo(ctx, u)
+		system = "mongodb"
+	case "redis":
+		s, err = openRedis(ctx, dsn)
+		system = "redis"
+	default:
+		return nil, fmt.Errorf("unknown store %q, want one of %v", u.Scheme, Schemes)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("open %s store: %w", system, err)
+	}
+	return traced(s, system), nil
+}
+
+// newID returns a random ID for records created without one.
+func newID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,78 @@
+package store
+
+import (
+	"context"
+	"errors"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"test-jaeger/internal/telemetry"
+	"test-jaeger/pkg/models"
+)
+
+const instrumentationName = "test-jaeger/internal/store"
+
+// tracedStore records every call to a backend as a client span named after
+// the backend and the operation, e.g. "postgresql GetUsers", with db.system
+// and db.operation.name attributes. ErrNotFound is an answer, not a failure,
+// and does not mark the span as an error.
+type tracedStore struct {
+	next   Store
+	system string
+	tracer trace.Tracer
+}
+
+func traced(s Store, system string) Store {
+	return &tracedStore{next: s, system: system, tracer: otel.Tracer(instrumentationName)}
+}
+
+func (t *tracedStore) start(ctx context.Context, operation string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	attrs = append(attrs, semconv.DBSystemKey.String(t.system), semconv.DBOperationNameKey.String(operation))
+	return t.tracer.Start(ctx, t.system+" "+operation,
+		trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+}
+
+func end(span trace.Span, err error) {
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+func (t *tracedStore) GetUsers(ctx context.Context) ([]User, error) {
+	ctx, span := t.start(ctx, "GetUsers")
+	users, err := t.next.GetUsers(ctx)
+	span.SetAttributes(models.UsersAttributes(users)...)
+	telemetry.DBRowsRead.Add(ctx, int64(len(users)))
+	end(span, err)
+	return users, err
+}
+
+func (t *tracedStore) GetUser(ctx context.Context, id string) (User, error) {
+	ctx, span := t.start(ctx, "GetUser", attribute.String("user.id", id))
+	u, err := t.next.GetUser(ctx, id)
+	end(span, err)
+	return u, err
+}
+
+func (t *tracedStore) CreateUser(ctx context.Context, u User) (User, error) {
+	ctx, span := t.start(ctx, "CreateUser")
+	u, err := t.next.CreateUser(ctx, u)
+	span.SetAttributes(attribute.String("user.id", u.ID))
+	end(span, err)
+	return u, err
+}
+
+// Ping is not traced, health checks would flood the traces.
+func (t *tracedStore) Ping(ctx context.Context) error {
+	return t.next.Ping(ctx)
+}
+
+func (t *tracedStore) Close(ctx context.Context) error {
+	return t.next.Close(ctx)
+}
@@ -0,0 +1,173 @@
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// DefaultCaptureSize is the number of spans a SpanCapture keeps, and of log
+// lines a LogRing keeps, by default.
+const DefaultCaptureSize = 2048
+
+// SpanCapture keeps the last ended spans in memory, for the debug endpoints
+// to show what the service did without a backend. Set it as
+// Config.SpanCapture so it gets the spans as exported: masked and filtered.
+type SpanCapture struct {
+	mu    sync.Mutex
+	spans []CapturedSpan
+	next  int
+	full  bool
+}
+
+// NewSpanCapture keeps the last size spans, DefaultCaptureSize when size is
+// not positive.
+func NewSpanCapture(size int) *SpanCapture {
+	if size <= 0 {
+		size = DefaultCaptureSize
+	}
+	return &SpanCapture{spans: make([]CapturedSpan, size)}
+}
+
+// CapturedSpan is the JSON view of an ended span.
+type CapturedSpan struct {
+	TraceID      string          `json:"trace_id"`
+	SpanID       string          `json:"span_id"`
+	ParentSpanID string          `json:"parent_span_id,omitempty"`
+	Name         string          `json:"name"`
+	Kind         string          `json:"kind"`
+	Start        time.Time       `json:"start"`
+	DurationMS   float64         `json:"duration_ms"`
+	Status       string          `json:"status"`
+	StatusText   string          `json:"status_text,omitempty"`
+	Attributes   map[string]any  `json:"attributes,omitempty"`
+	Events       []CapturedEvent `json:"events,omitempty"`
+}
+
+// CapturedEvent is the JSON view of a span event.
+type CapturedEvent struct {
+	Name       string         `json:"name"`
+	Time       time.Time      `json:"time"`
+	Attributes map[string]any `json:"attributes,omitempty"`
+}
+
+func (c *SpanCapture) OnStart(context.Context, sdktrace.ReadWriteSpan) {}
+
+func (c *SpanCapture) OnEnd(s sdktrace.ReadOnlySpan) {
+	cs := CapturedSpan{
+		TraceID:    s.SpanContext().TraceID().String(),
+		SpanID:     s.SpanContext().SpanID().String(),
+		Name:       s.Name(),
+		Kind:       s.SpanKind().String(),
+		Start:      s.StartTime(),
+		DurationMS: float64(s.EndTime().Sub(s.StartTime())) / float64(time.Millisecond),
+		Status:     s.Status().Code.String(),
+		StatusText: s.Status().Description,
+	}
+	if s.Parent().IsValid() {
+		cs.ParentSpanID = s.Parent().SpanID().String()
+	}
+	if attrs := s.Attributes(); len(attrs) > 0 {
+		cs.Attributes = make(map[string]any, len(attrs))
+		for _, kv := range attrs {
+			cs.Attributes[string(kv.Key)] = kv.Value.AsInterface()
+		}
+	}
+	for _, e := range s.Events() {
+		ce := CapturedEvent{Name: e.Name, Time: e.Time}
+		if len(e.Attributes) > 0 {
+			ce.Attributes = make(map[string]any, len(e.Attributes))
+			for _, kv := range e.Attributes {
+				ce.Attributes[string(kv.Key)] = kv.Value.AsInterface()
+			}
+		}
+		cs.Events = append(cs.Events, ce)
+	}
+
+	c.mu.Lock()
+	c.spans[c.next] = cs
+	c.next = (c.next + 1) % len(c.spans)
+	c.full = c.full || c.next == 0
+	c.mu.Unlock()
+}
+
+func (c *SpanCapture) Shutdown(context.Context) error   { return nil }
+func (c *SpanCapture) ForceFlush(context.Context) error { return nil }
+
+// Spans returns the captured spans, oldest first, that keep returns true
+// for. A nil keep returns them all.
+func (c *SpanCapture) Spans(keep func(CapturedSpan) bool) []CapturedSpan {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ordered := c.spans[:c.next]
+	if c.full {
+		ordered = append(append([]CapturedSpan(nil), c.spans[c.next:]...), c.spans[:c.next]...)
+	}
+	var spans []CapturedSpan
+	for _, s := range ordered {
+		if keep == nil || keep(s) {
+			spans = append(spans, s)
+		}
+	}
+	return spans
+}
+
+// Trace returns the captured spans of the trace id, oldest first.
+func (c *SpanCapture) Trace(id trace.TraceID) []CapturedSpan {
+	hex := id.String()
+	return c.Spans(func(s CapturedSpan) bool { return s.TraceID == hex })
+}
+
+// LogRing keeps the last JSON lines written by a LogHandler, e.g. through
+// io.MultiWriter(os.Stdout, ring), so the log lines of a trace can be
+// found again by their trace_id.
+type LogRing struct {
+	mu    sync.Mutex
+	lines [][]byte
+	next  int
+	full  bool
+}
+
+// NewLogRing keeps the last size lines, DefaultCaptureSize when size is not
+// positive.
+func NewLogRing(size int) *LogRing {
+	if size <= 0 {
+		size = DefaultCaptureSize
+	}
+	return &LogRing{lines: make([][]byte, size)}
+}
+
+// Write keeps p, a line of JSON, as a LogHandler writes one per record.
+func (r *LogRing) Write(p []byte) (int, error) {
+	line := bytes.Clone(bytes.TrimSpace(p))
+	r.mu.Lock()
+	r.lines[r.next] = line
+	r.next = (r.next + 1) % len(r.lines)
+	r.full = r.full || r.next == 0
+	r.mu.Unlock()
+	return len(p), nil
+}
+
+// Trace returns the kept lines of the trace id, oldest first.
+func (r *LogRing) Trace(id trace.TraceID) []json.RawMessage {
+	r.mu.Lock()
+	ordered := append([][]byte(nil), r.lines[:r.next]...)
+	if r.full {
+		ordered = append(append([][]byte(nil), r.lines[r.next:]...), r.lines[:r.next]...)
+	}
+	r.mu.Unlock()
+
+	needle := []byte(`"trace_id":"` + id.String() + `"`)
+	var lines []json.RawMessage
+	for _, line := range ordered {
+		if bytes.Contains(line, needle) && json.Valid(line) {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
@@ -0,0 +1,133 @@
+//go:build !notelemetry && !wasm
+
+package telemetry
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log"
+	"net/url"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/encoding/gzip"
+	"google.golang.org/grpc/keepalive"
+)
+
+// Dial connects to the OTLP gRPC endpoint URL, with the keepalive, the TLS
+// settings, the compression and the bearer tokens of e, for the exporter of
+// signal, e.g. "traces". The connection state changes are logged and counted in
+// otlp.exporter.connection.transitions, and otlp.exporter.connection.ready
+// tells whether the connection is usable. The connection is the caller's to
+// close.
+func (e Exporter) Dial(endpoint, signal string) (*grpc.ClientConn, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid %s endpoint %q", signal, endpoint)
+	}
+	tlsConfig := &tls.Config{}
+	if !e.TLS.IsZero() {
+		if tlsConfig, err = e.TLS.Config(); err != nil {
+			return nil, fmt.Errorf("%s endpoint %s: %w", signal, u.Host, err)
+		}
+	}
+	opts := []grpc.DialOption{grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig))}
+	if u.Scheme == "http" {
+		opts[0] = grpc.WithTransportCredentials(insecure.NewCredentials())
+	}
+	if ts := e.TokenSource(); ts != nil {
+		opts = append(opts, grpc.WithPerRPCCredentials(ts))
+	}
+	// The exporters only apply their compression to the connections they dial
+	if e.Gzip() {
+		opts = append(opts, grpc.WithDefaultCallOptions(grpc.UseCompressor(gzip.Name)))
+	}
+	if k := e.Keepalive; k.Time > 0 {
+		opts = append(opts, grpc.WithKeepaliveParams(keepalive.ClientParameters{
+			Time: k.Time, Timeout: k.Timeout, PermitWithoutStream: k.PermitWithoutStream}))
+	}
+	conn, err := grpc.NewClient(u.Host, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("dial %s endpoint %s: %w", signal, u.Host, err)
+	}
+	go watchConn(conn, u.Host, signal)
+	return conn, nil
+}
+
+// watchConn reports the state changes of conn until it is closed. The
+// failures are logged like the other failures of the telemetry pipeline,
+// and so is the recovery from one.
+func watchConn(conn *grpc.ClientConn, host, signal string) {
+	meter := otel.Meter(instrumentationName)
+	transitions, err := meter.Int64Counter("otlp.exporter.connection.transitions",
+		metric.WithDescription("Number of state changes of the connections to the OTLP endpoints"))
+	if err != nil {
+		log.Printf("failed to create otlp.exporter.connection.transitions counter: %v", err)
+	}
+	connStates.once.Do(registerConnReady)
+
+	attrs := []attribute.KeyValue{attribute.String("server.address", host), attribute.String("otlp.signal", signal)}
+	key := [2]string{host, signal}
+	state := conn.GetState()
+	var failed bool
+	for conn.WaitForStateChange(context.Background(), state) {
+		state = conn.GetState()
+		if transitions != nil {
+			transitions.Add(context.Background(), 1,
+				metric.WithAttributes(append(attrs, attribute.String("state", state.String()))...))
+		}
+		connStates.Lock()
+		connStates.states[key] = state
+		connStates.Unlock()
+
+		switch state {
+		case connectivity.TransientFailure:
+			if !failed {
+				log.Printf("otlp %s connection to %s failed, exports are retried until it recovers", signal, host)
+			}
+			failed = true
+		case connectivity.Ready:
+			if failed {
+				log.Printf("otlp %s connection to %s recovered", signal, host)
+			}
+			failed = false
+		case connectivity.Shutdown:
+			connStates.Lock()
+			delete(connStates.states, key)
+			connStates.Unlock()
+			return
+		}
+	}
+}
+
+func registerConnReady() {
+	meter := otel.Meter(instrumentationName)
+	ready, err := meter.Int64ObservableGauge("otlp.exporter.connection.ready",
+		metric.WithDescription("1 while the connection to the OTLP endpoint is ready"))
+	if err != nil {
+		log.Printf("failed to create otlp.exporter.connection.ready gauge: %v", err)
+		return
+	}
+	_, err = meter.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+		connStates.Lock()
+		defer connStates.Unlock()
+		for key, state := range connStates.states {
+			var v int64
+			if state == connectivity.Ready {
+				v = 1
+			}
+			o.ObserveInt64(ready, v, metric.WithAttributes(
+				attribute.String("server.address", key[0]), attribute.String("otlp.signal", key[1])))
+		}
+		return nil
+	}, ready)
+	if err != nil {
+		log.Printf("failed to register otlp connection callback: %v", err)
+	}
+}
@@ -0,0 +1,33 @@
+package telemetry
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"google.golang.org/grpc/connectivity"
+)
+
+// connStates holds the state of the exporter connections by endpoint and
+// signal, see watchConn.
+var connStates = struct {
+	sync.Mutex
+	once   sync.Once
+	states map[[2]string]connectivity.State
+}{states: make(map[[2]string]connectivity.State)}
+
+// CheckExporters reports the exporter connections failing to connect to
+// their OTLP endpoint. Connections not used yet, or idle, are not failing.
+func CheckExporters() error {
+	connStates.Lock()
+	defer connStates.Unlock()
+	var errs []error
+	for key, state := range connStates.states {
+		if state == connectivity.TransientFailure {
+			errs = append(errs, fmt.Errorf("otlp %s connection to %s is failing", key[1], key[0]))
+		}
+	}
+	sort.Slice(errs, func(i, j int) bool { return errs[i].Error() < errs[j].Error() })
+	return errors.Join(errs...)
+}
@@ -0,0 +1,169 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"reflect"
+	"time"
+	"unicode/utf8"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// MaxAttributeLength caps string attribute values, including values coerced
+// to JSON, so a large payload never ends up on a span.
+const MaxAttributeLength = 1024
+
+const truncatedSuffix = "...(truncated)"
+
+// Attributes converts alternating keys and values into attributes, e.g.
+//
+//	span.SetAttributes(core.Attributes("user.count", n, "order", order)...)
+//
+// Values of supported types are kept, strings are capped at
+// MaxAttributeLength and common mistakes are coerced: durations, times,
+// errors and Stringers become strings, unsigned integers that overflow int64
+// become strings, and structs, maps and other slices become JSON. Pairs that
+// cannot be set (empty or non-string keys, nil values, a dangling key) are
+// logged instead of being dropped silently.
+func Attributes(keyvals ...any) []attribute.KeyValue {
+	attrs := make([]attribute.KeyValue, 0, len(keyvals)/2)
+	for i := 0; i < len(keyvals); i += 2 {
+		key, ok := keyvals[i].(string)
+		if !ok || key == "" {
+			log.Printf("dropping attribute with invalid key %#v", keyvals[i])
+			continue
+		}
+		if i+1 == len(keyvals) {
+			log.Printf("dropping attribute %q: missing value", key)
+			continue
+		}
+		kv, err := Attr(key, keyvals[i+1])
+		if err != nil {
+			log.Printf("dropping attribute %q: %v", key, err)
+			continue
+		}
+		attrs = append(attrs, kv)
+	}
+	return attrs
+}
+
+// SetAttributes is a shorthand for span.SetAttributes(Attributes(keyvals...)...).
+func SetAttributes(span trace.Span, keyvals ...any) {
+	span.SetAttributes(Attributes(keyvals...)...)
+}
+
+// Attr validates and coerces a single value, see Attributes.
+func Attr(key string, value any) (attribute.KeyValue, error) {
+	k := attribute.Key(key)
+	// A nil pointer is reported before the error and Stringer cases below
+	// call its methods, which would dereference it, e.g. a nil *url.URL
+	if rv := reflect.ValueOf(value); rv.Kind() == reflect.Pointer && rv.IsNil() {
+		return attribute.KeyValue{}, fmt.Errorf("nil %T", value)
+	}
+	switch v := value.(type) {
+	case nil:
+		return attribute.KeyValue{}, fmt.Errorf("nil value")
+	case string:
+		return k.String(capString(v)), nil
+	case bool:
+		return k.Bool(v), nil
+	case int:
+		return k.Int(v), nil
+	case int8:
+		return k.Int64(int64(v)), nil
+	case int16:
+		return k.Int64(int64(v)), nil
+	case int32:
+		return k.Int64(int64(v)), nil
+	case int64:
+		return k.Int64(v), nil
+	case uint8:
+		return k.Int64(int64(v)), nil
+	case uint16:
+		return k.Int64(int64(v)), nil
+	case uint32:
+		return k.Int64(int64(v)), nil
+	case uint:
+		return uintAttr(k, uint64(v)), nil
+	case uint64:
+		return uintAttr(k, v), nil
+	case float32:
+		return k.Float64(float64(v)), nil
+	case float64:
+		return k.Float64(v), nil
+	case []string:
+		capped := make([]string, len(v))
+		for i, s := range v {
+			capped[i] = capString(s)
+		}
+		return k.StringSlice(capped), nil
+	case []bool:
+		return k.BoolSlice(v), nil
+	case []int:
+		return k.IntSlice(v), nil
+	case []int64:
+		return k.Int64Slice(v), nil
+	case []float64:
+		return k.Float64Slice(v), nil
+	case time.Duration:
+		return k.String(v.String()), nil
+	case time.Time:
+		return k.String(v.Format(time.RFC3339Nano)), nil
+	case error:
+		return k.String(capString(v.Error())), nil
+	case fmt.Stringer:
+		return k.String(capString(v.String())), nil
+	}
+
+	rv := reflect.ValueOf(value)
+	for rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return attribute.KeyValue{}, fmt.Errorf("nil %T", value)
+		}
+		rv = rv.Elem()
+	}
+	// Pointers to supported types and named types based on them are unwrapped
+	// here; structs, maps and other slices fall through to JSON.
+	switch rv.Kind() {
+	case reflect.Struct, reflect.Map, reflect.Slice, reflect.Array:
+	case reflect.String:
+		return k.String(capString(rv.String())), nil
+	case reflect.Bool:
+		return k.Bool(rv.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return k.Int64(rv.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return uintAttr(k, rv.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		return k.Float64(rv.Float()), nil
+	default:
+		return attribute.KeyValue{}, fmt.Errorf("unsupported type %T", value)
+	}
+	data, err := json.Marshal(rv.Interface())
+	if err != nil {
+		return k.String(capString(fmt.Sprintf("%+v", rv.Interface()))), nil
+	}
+	return k.String(capString(string(data))), nil
+}
+
+func uintAttr(k attribute.Key, v uint64) attribute.KeyValue {
+	if v > math.MaxInt64 {
+		return k.String(fmt.Sprint(v))
+	}
+	return k.Int64(int64(v))
+}
+
+func capString(s string) string {
+	if len(s) <= MaxAttributeLength {
+		return s
+	}
+	s = s[:MaxAttributeLength-len(truncatedSuffix)]
+	for !utf8.ValidString(s) {
+		s = s[:len(s)-1]
+	}
+	return s + truncatedSuffix
+}
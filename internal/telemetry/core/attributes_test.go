@@ -0,0 +1,69 @@
+package core
+
+import (
+	"errors"
+	"math"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+type nilError struct{}
+
+func (*nilError) Error() string { return "never called on nil" }
+
+func TestAttr(t *testing.T) {
+	n := 3
+	tests := []struct {
+		name    string
+		value   any
+		want    attribute.Value
+		wantErr bool
+	}{
+		{"string", "a", attribute.StringValue("a"), false},
+		{"int pointer", &n, attribute.Int64Value(3), false},
+		{"large uint64", uint64(math.MaxUint64), attribute.StringValue("18446744073709551615"), false},
+		{"duration", 1500 * time.Millisecond, attribute.StringValue("1.5s"), false},
+		{"error", errors.New("boom"), attribute.StringValue("boom"), false},
+		{"stringer", &url.URL{Scheme: "http", Host: "a"}, attribute.StringValue("http://a"), false},
+		{"struct", struct{ A int }{1}, attribute.StringValue(`{"A":1}`), false},
+		{"nil", nil, attribute.Value{}, true},
+		{"nil stringer", (*url.URL)(nil), attribute.Value{}, true},
+		{"nil error", (*nilError)(nil), attribute.Value{}, true},
+		{"nil int pointer", (*int)(nil), attribute.Value{}, true},
+		{"unsupported", func() {}, attribute.Value{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kv, err := Attr("k", tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Attr(%#v) error = %v, want error %v", tt.value, err, tt.wantErr)
+			}
+			if err == nil && kv.Value != tt.want {
+				t.Errorf("Attr(%#v) = %v, want %v", tt.value, kv.Value.Emit(), tt.want.Emit())
+			}
+		})
+	}
+}
+
+func TestAttributesDropsNilValues(t *testing.T) {
+	var u *url.URL
+	attrs := Attributes("url", u, "count", 2, "", 3, "dangling")
+	if len(attrs) != 1 || attrs[0] != attribute.Int("count", 2) {
+		t.Errorf("Attributes = %v, want only count", attrs)
+	}
+}
+
+func TestAttrCapsStrings(t *testing.T) {
+	kv, err := Attr("k", strings.Repeat("é", MaxAttributeLength))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := kv.Value.AsString()
+	if len(s) > MaxAttributeLength || !strings.HasSuffix(s, truncatedSuffix) {
+		t.Errorf("capped value has length %d and ends with %q", len(s), s[len(s)-20:])
+	}
+}
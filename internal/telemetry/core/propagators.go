@@ -0,0 +1,60 @@
+// Package core holds the telemetry helpers that only depend on the
+// OpenTelemetry API: attribute conversion and context propagation. It leaves
+// out the SDK, the exporters, Gin and gRPC, so that clients built for
+// constrained targets, e.g. an edge demo client compiled to WASM with TinyGo,
+// can propagate traces the same way as the services.
+package core
+
+import (
+	"fmt"
+
+	"go.opentelemetry.io/contrib/propagators/b3"
+	"go.opentelemetry.io/contrib/propagators/jaeger"
+	"go.opentelemetry.io/otel/propagation"
+
+	"test-jaeger/internal/propagation/datadog"
+	"test-jaeger/internal/propagation/legacy"
+)
+
+// DefaultPropagators are used when no propagator is named.
+var DefaultPropagators = []string{"tracecontext", "baggage"}
+
+// NewPropagator builds a composite from propagator names as used by
+// OTEL_PROPAGATORS, "w3c" standing for "tracecontext". "b3" is the single
+// b3 header and "b3multi" the X-B3-* headers understood by Envoy, Istio and
+// Zipkin instrumentations. "jaeger" is the uber-trace-id header of the
+// Jaeger clients and "datadog" the x-datadog-* headers. extra adds names
+// whose propagators would pull heavier dependencies in, e.g. "xray". When
+// several formats are present on a request, the one listed last wins on
+// extraction.
+func NewPropagator(names []string, extra map[string]propagation.TextMapPropagator) (propagation.TextMapPropagator, error) {
+	if len(names) == 0 {
+		names = DefaultPropagators
+	}
+	propagators := make([]propagation.TextMapPropagator, 0, len(names))
+	for _, name := range names {
+		switch name {
+		case "tracecontext", "w3c":
+			propagators = append(propagators, propagation.TraceContext{})
+		case "baggage":
+			propagators = append(propagators, propagation.Baggage{})
+		case "legacy":
+			propagators = append(propagators, legacy.Propagator{})
+		case "b3":
+			propagators = append(propagators, b3.New(b3.WithInjectEncoding(b3.B3SingleHeader)))
+		case "b3multi":
+			propagators = append(propagators, b3.New(b3.WithInjectEncoding(b3.B3MultipleHeader)))
+		case "jaeger":
+			propagators = append(propagators, jaeger.Jaeger{})
+		case "datadog":
+			propagators = append(propagators, datadog.Propagator{})
+		default:
+			p, ok := extra[name]
+			if !ok {
+				return nil, fmt.Errorf("unknown propagator %q", name)
+			}
+			propagators = append(propagators, p)
+		}
+	}
+	return propagation.NewCompositeTextMapPropagator(propagators...), nil
+}
@@ -0,0 +1,155 @@
+package telemetry
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// CostEstimator counts the spans, metric data points and log records that
+// are exported and approximates their OTLP size, to help reason about the
+// ingestion cost of a SaaS backend. Sizes are estimates of the protobuf
+// encoding, not measurements of the wire traffic.
+//
+// It is the span processor of the traces, set as Config.CostEstimator so it
+// only sees the spans left by the filters, and the exporters of the metrics
+// and logs report to it with Add.
+type CostEstimator struct {
+	start time.Time
+
+	mu      sync.Mutex
+	signals map[string]*signalCount
+}
+
+type signalCount struct {
+	items, bytes int64
+}
+
+var _ sdktrace.SpanProcessor = (*CostEstimator)(nil)
+
+// Signals lists the signals a CostEstimator reports on.
+var Signals = []string{"traces", "metrics", "logs"}
+
+// NewCostEstimator returns an estimator, see CostEstimator.
+func NewCostEstimator() *CostEstimator {
+	e := &CostEstimator{start: time.Now(), signals: make(map[string]*signalCount, len(Signals))}
+	for _, signal := range Signals {
+		e.signals[signal] = &signalCount{}
+	}
+	return e
+}
+
+// Add accounts for n items of signal exported in about size bytes.
+func (e *CostEstimator) Add(signal string, n, size int64) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	c, ok := e.signals[signal]
+	if !ok {
+		c = &signalCount{}
+		e.signals[signal] = c
+	}
+	c.items += n
+	c.bytes += size
+}
+
+// OnEnd accounts for sampled spans, the only ones the exporter sends.
+func (e *CostEstimator) OnEnd(s sdktrace.ReadOnlySpan) {
+	if !s.SpanContext().IsSampled() {
+		return
+	}
+	e.Add("traces", 1, int64(estimateSpanSize(s)))
+}
+
+func (e *CostEstimator) OnStart(context.Context, sdktrace.ReadWriteSpan) {}
+func (e *CostEstimator) Shutdown(context.Context) error                  { return nil }
+func (e *CostEstimator) ForceFlush(context.Context) error                { return nil }
+
+// SignalCost is the observed and projected volume of one signal.
+type SignalCost struct {
+	Observed        int64   `json:"observed"`
+	ObservedBytes   int64   `json:"observed_bytes"`
+	AvgBytes        float64 `json:"avg_bytes"`
+	PerHour         float64 `json:"per_hour"`
+	BytesPerHour    float64 `json:"bytes_per_hour"`
+	GigabytesPer30d float64 `json:"gigabytes_per_30d"`
+}
+
+// CostReport is served by Handler.
+type CostReport struct {
+	WindowSeconds float64               `json:"window_seconds"`
+	Signals       map[string]SignalCost `json:"signals"`
+}
+
+// Report projects the throughput observed since the estimator was created.
+func (e *CostEstimator) Report() CostReport {
+	window := time.Since(e.start)
+	hours := window.Hours()
+	report := CostReport{WindowSeconds: window.Seconds(), Signals: make(map[string]SignalCost)}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for signal, c := range e.signals {
+		cost := SignalCost{Observed: c.items, ObservedBytes: c.bytes}
+		if c.items > 0 {
+			cost.AvgBytes = float64(c.bytes) / float64(c.items)
+		}
+		if hours > 0 {
+			cost.PerHour = float64(c.items) / hours
+			cost.BytesPerHour = float64(c.bytes) / hours
+			cost.GigabytesPer30d = cost.BytesPerHour * 24 * 30 / 1e9
+		}
+		report.Signals[signal] = cost
+	}
+	return report
+}
+
+// Handler serves the report, e.g. on /debug/telemetry-cost.
+func (e *CostEstimator) Handler(c *gin.Context) {
+	c.JSON(http.StatusOK, e.Report())
+}
+
+// estimateSpanSize approximates the OTLP protobuf size of s: ids, timestamps,
+// kind and status, plus the name, attributes, events and links with a few
+// bytes of framing each.
+func estimateSpanSize(s sdktrace.ReadOnlySpan) int {
+	size := 70 + len(s.Name()) + len(s.Status().Description)
+	size += AttributesSize(s.Attributes())
+	for _, ev := range s.Events() {
+		size += 12 + len(ev.Name) + AttributesSize(ev.Attributes)
+	}
+	for _, l := range s.Links() {
+		size += 30 + AttributesSize(l.Attributes)
+	}
+	return size
+}
+
+// AttributesSize approximates the OTLP protobuf size of attrs, for the
+// estimates of the signals reported with CostEstimator.Add.
+func AttributesSize(attrs []attribute.KeyValue) int {
+	size := 0
+	for _, kv := range attrs {
+		size += 4 + len(kv.Key)
+		switch kv.Value.Type() {
+		case attribute.STRING:
+			size += len(kv.Value.AsString())
+		case attribute.STRINGSLICE:
+			for _, v := range kv.Value.AsStringSlice() {
+				size += 2 + len(v)
+			}
+		case attribute.BOOLSLICE:
+			size += 2 * len(kv.Value.AsBoolSlice())
+		case attribute.INT64SLICE:
+			size += 9 * len(kv.Value.AsInt64Slice())
+		case attribute.FLOAT64SLICE:
+			size += 9 * len(kv.Value.AsFloat64Slice())
+		default:
+			size += 9
+		}
+	}
+	return size
+}
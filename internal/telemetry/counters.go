@@ -0,0 +1,87 @@
+package telemetry
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Counter is a count kept per span, e.g. the rows a query read, and set as
+// an attribute named after it when the span ends, so a trace tells how much
+// work a request did without an event per row or per retry.
+type Counter string
+
+const (
+	// DBRowsRead counts the rows read from a database.
+	DBRowsRead Counter = "db.rows.read"
+	// HTTPRetries counts the retries of HTTP requests.
+	HTTPRetries Counter = "http.retries"
+)
+
+// spanCounts holds the counters of the spans still running, by span ID.
+var spanCounts sync.Map
+
+type counts struct {
+	mu sync.Mutex
+	n  map[Counter]int64
+}
+
+// Add adds n to c on the span of ctx and, with CollectAttributes or
+// WithDeferredAttributes up the chain, to the total of the request set on
+// its top span, see AddAttributes:
+//
+//	telemetry.DBRowsRead.Add(ctx, int64(len(rows)))
+func (c Counter) Add(ctx context.Context, n int64) {
+	span := trace.SpanFromContext(ctx)
+	id := span.SpanContext().SpanID()
+	if set, _ := ctx.Value(deferredSetKey{}).(*deferredSet); set != nil {
+		set.mu.Lock()
+		if set.counts == nil {
+			set.counts = make(map[Counter]int64)
+		}
+		set.counts[c] += n
+		top := set.bound && set.top == id
+		set.mu.Unlock()
+		// The total of the request already lands on its top span
+		if top {
+			return
+		}
+	}
+
+	// Spans that are not recording never reach OnEnd to release them
+	if !span.IsRecording() {
+		return
+	}
+	v, _ := spanCounts.LoadOrStore(id, &counts{})
+	cs := v.(*counts)
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	if cs.n == nil {
+		cs.n = make(map[Counter]int64)
+	}
+	cs.n[c] += n
+}
+
+// takeSpanCounts releases the counters of a span as attributes.
+func takeSpanCounts(id trace.SpanID) []attribute.KeyValue {
+	v, ok := spanCounts.LoadAndDelete(id)
+	if !ok {
+		return nil
+	}
+	cs := v.(*counts)
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	return countAttributes(cs.n)
+}
+
+func countAttributes(n map[Counter]int64) []attribute.KeyValue {
+	attrs := make([]attribute.KeyValue, 0, len(n))
+	for c, v := range n {
+		attrs = append(attrs, attribute.Int64(string(c), v))
+	}
+	sort.Slice(attrs, func(i, j int) bool { return attrs[i].Key < attrs[j].Key })
+	return attrs
+}
@@ -0,0 +1,144 @@
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+
+	"test-jaeger/internal/telemetry/core"
+)
+
+// deferredSet holds the attributes added to a request with AddAttributes,
+// and the totals of its counters, until the top span of the request ends.
+type deferredSet struct {
+	mu     sync.Mutex
+	bound  bool
+	top    trace.SpanID
+	attrs  []attribute.KeyValue
+	counts map[Counter]int64
+}
+
+type deferredSetKey struct{}
+
+// CollectAttributes is a Gin middleware letting the code serving a request
+// add attributes to its top span from anywhere down the call chain, see
+// AddAttributes.
+func CollectAttributes() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Request = c.Request.WithContext(WithDeferredAttributes(c.Request.Context()))
+		c.Next()
+	}
+}
+
+// WithDeferredAttributes returns a context collecting the attributes added
+// with AddAttributes for the first span started from it, the top span of
+// e.g. a request or a job.
+func WithDeferredAttributes(ctx context.Context) context.Context {
+	return context.WithValue(ctx, deferredSetKey{}, &deferredSet{})
+}
+
+// AddAttributes adds alternating keys and values, converted as by
+// core.Attributes, to the top span of the request of ctx when it ends, e.g. the
+// rows scanned or the cache layers consulted deep down the call chain, without
+// passing the span around. A later value replaces an earlier one of the same
+// key. Without CollectAttributes or WithDeferredAttributes up the chain, they
+// are set on the span of ctx right away.
+func AddAttributes(ctx context.Context, keyvals ...any) {
+	set, _ := ctx.Value(deferredSetKey{}).(*deferredSet)
+	if set == nil {
+		core.SetAttributes(trace.SpanFromContext(ctx), keyvals...)
+		return
+	}
+	attrs := core.Attributes(keyvals...)
+	set.mu.Lock()
+	set.attrs = append(set.attrs, attrs...)
+	set.mu.Unlock()
+}
+
+// bind claims set for the first span started from its context.
+func (s *deferredSet) bind(id trace.SpanID) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.bound {
+		return false
+	}
+	s.bound, s.top = true, id
+	return true
+}
+
+func (s *deferredSet) take() []attribute.KeyValue {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	attrs := append(s.attrs, countAttributes(s.counts)...)
+	s.attrs, s.counts = nil, nil
+	return attrs
+}
+
+// deferredAttributes adds the attributes collected with AddAttributes to the
+// top span of their request when it ends, and the counters of Counter.Add
+// to their spans. Like gcPauseTagger, it hands a
+// view of the ended span with the attributes to next.
+type deferredAttributes struct {
+	next []sdktrace.SpanProcessor
+	sets sync.Map // span ID -> *deferredSet
+}
+
+func newDeferredAttributes(next ...sdktrace.SpanProcessor) *deferredAttributes {
+	return &deferredAttributes{next: next}
+}
+
+func (d *deferredAttributes) OnStart(parent context.Context, s sdktrace.ReadWriteSpan) {
+	if set, _ := parent.Value(deferredSetKey{}).(*deferredSet); set != nil && set.bind(s.SpanContext().SpanID()) && s.SpanContext().IsSampled() {
+		d.sets.Store(s.SpanContext().SpanID(), set)
+	}
+	for _, p := range d.next {
+		p.OnStart(parent, s)
+	}
+}
+
+func (d *deferredAttributes) OnEnd(s sdktrace.ReadOnlySpan) {
+	var attrs []attribute.KeyValue
+	if set, ok := d.sets.LoadAndDelete(s.SpanContext().SpanID()); ok {
+		attrs = set.(*deferredSet).take()
+	}
+	attrs = append(attrs, takeSpanCounts(s.SpanContext().SpanID())...)
+	if len(attrs) > 0 {
+		// The set keeps the last value of each key, the deferred one for a
+		// key also set on the span.
+		merged := attribute.NewSet(append(s.Attributes(), attrs...)...)
+		s = deferredSpan{ReadOnlySpan: s, attrs: merged.ToSlice()}
+	}
+	for _, p := range d.next {
+		p.OnEnd(s)
+	}
+}
+
+func (d *deferredAttributes) Shutdown(ctx context.Context) error {
+	var errs []error
+	for _, p := range d.next {
+		errs = append(errs, p.Shutdown(ctx))
+	}
+	return errors.Join(errs...)
+}
+
+func (d *deferredAttributes) ForceFlush(ctx context.Context) error {
+	var errs []error
+	for _, p := range d.next {
+		errs = append(errs, p.ForceFlush(ctx))
+	}
+	return errors.Join(errs...)
+}
+
+// deferredSpan is an ended span with the deferred attributes of its request
+// merged into its own.
+type deferredSpan struct {
+	sdktrace.ReadOnlySpan
+	attrs []attribute.KeyValue
+}
+
+func (s deferredSpan) Attributes() []attribute.KeyValue { return s.attrs }
@@ -0,0 +1,73 @@
+package telemetry
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// withEnv returns c overridden by the standard OpenTelemetry environment
+// variables, so deployments can reconfigure a service without touching its
+// flags or config file:
+//
+//	OTEL_SERVICE_NAME
+//	OTEL_EXPORTER_OTLP_TRACES_ENDPOINT, falling back to OTEL_EXPORTER_OTLP_ENDPOINT
+//	OTEL_TRACES_SAMPLER and OTEL_TRACES_SAMPLER_ARG
+//	OTEL_PROPAGATORS
+//	OTEL_EXPORTER_OTLP_TRACES_CERTIFICATE and the others of TLSConfig.WithEnv
+//
+// OTEL_RESOURCE_ATTRIBUTES is merged into the resource and the exporter reads
+// OTEL_EXPORTER_OTLP_HEADERS itself.
+func (c Config) withEnv() (Config, error) {
+	if v := os.Getenv("OTEL_SERVICE_NAME"); v != "" {
+		c.ServiceName = v
+	}
+	if v := os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT"); v != "" {
+		c.Endpoint = v
+	} else if v := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"); v != "" {
+		c.Endpoint = v
+	}
+	c.TLS = c.TLS.WithEnv("TRACES")
+	if v := os.Getenv("OTEL_TRACES_SAMPLER"); v != "" {
+		c.Sampler = v
+	}
+	if v := os.Getenv("OTEL_TRACES_SAMPLER_ARG"); v != "" {
+		arg, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return c, fmt.Errorf("OTEL_TRACES_SAMPLER_ARG: %w", err)
+		}
+		c.SamplerArg = &arg
+	}
+	if v := os.Getenv("OTEL_PROPAGATORS"); v != "" {
+		c.Propagators = nil
+		for _, name := range strings.Split(v, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				c.Propagators = append(c.Propagators, name)
+			}
+		}
+	}
+	return c, nil
+}
+
+// WithEnv returns t overridden by the OTEL_EXPORTER_OTLP_<signal>_CERTIFICATE,
+// _CLIENT_CERTIFICATE and _CLIENT_KEY variables of signal, e.g. "METRICS",
+// falling back to the OTEL_EXPORTER_OTLP_* ones. The exporters given a
+// connection of Exporter.Dial do not read them.
+func (t TLSConfig) WithEnv(signal string) TLSConfig {
+	for _, v := range []struct {
+		name  string
+		field *string
+	}{
+		{"CERTIFICATE", &t.CAFile},
+		{"CLIENT_CERTIFICATE", &t.CertFile},
+		{"CLIENT_KEY", &t.KeyFile},
+	} {
+		if env := os.Getenv("OTEL_EXPORTER_OTLP_" + signal + "_" + v.name); env != "" {
+			*v.field = env
+		} else if env := os.Getenv("OTEL_EXPORTER_OTLP_" + v.name); env != "" {
+			*v.field = env
+		}
+	}
+	return t
+}
@@ -0,0 +1,95 @@
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+)
+
+// errorLogInterval is how often the same OpenTelemetry error is logged again.
+// An unreachable collector fails every export, which would flood the log.
+const errorLogInterval = time.Minute
+
+// errorHandler logs errors reported by the SDK and the exporters, such as
+// failed exports, at most once per errorLogInterval for identical messages.
+type errorHandler struct {
+	mu   sync.Mutex
+	seen map[string]*suppressed
+}
+
+type suppressed struct {
+	logged time.Time
+	count  int
+}
+
+func (h *errorHandler) Handle(err error) {
+	msg := err.Error()
+	now := time.Now()
+	// The SDK reads OTEL_TRACES_SAMPLER as well before WithSampler replaces
+	// its choice, and rejects the samplers only newSampler knows.
+	if name, ok := strings.CutPrefix(msg, "unsupported sampler: "); ok && ValidateSampler(name, 1) == nil {
+		return
+	}
+
+	h.mu.Lock()
+	s, ok := h.seen[msg]
+	if ok && now.Sub(s.logged) < errorLogInterval {
+		s.count++
+		h.mu.Unlock()
+		return
+	}
+	repeated := 0
+	if ok {
+		repeated = s.count
+	}
+	h.seen[msg] = &suppressed{logged: now}
+	h.mu.Unlock()
+
+	if repeated > 0 {
+		log.Printf("opentelemetry error: %v (repeated %d times)", err, repeated)
+		return
+	}
+	log.Printf("opentelemetry error: %v", err)
+}
+
+var installErrorHandler sync.Once
+
+func setErrorHandler() {
+	installErrorHandler.Do(func() {
+		otel.SetErrorHandler(&errorHandler{seen: map[string]*suppressed{}})
+	})
+}
+
+// probeTimeout bounds the startup reachability check of the collector.
+const probeTimeout = 2 * time.Second
+
+// probeCollector reports through the error handler when nothing accepts
+// connections at endpoint. It never fails startup: the exporter keeps
+// reconnecting and spans are dropped until the collector is back.
+func probeCollector(endpoint string) {
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Host == "" {
+		return
+	}
+	host := u.Host
+	if u.Port() == "" {
+		host = net.JoinHostPort(u.Hostname(), "4317")
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), probeTimeout)
+		defer cancel()
+		conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", host)
+		if err != nil {
+			otel.Handle(fmt.Errorf("collector %s is unreachable, spans are dropped until it is back: %w", host, err))
+			return
+		}
+		conn.Close()
+	}()
+}
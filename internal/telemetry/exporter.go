@@ -0,0 +1,80 @@
+//go:build !notelemetry && !wasm
+
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"google.golang.org/grpc"
+)
+
+// newExporter creates the span exporter of a backend. Builds with the
+// notelemetry tag have none, see exporter_notelemetry.go, and WASM builds
+// print the spans, see exporter_wasm.go.
+func newExporter(ctx context.Context, cfg Exporter) (sdktrace.SpanExporter, error) {
+	switch cfg.backend() {
+	case Stdout:
+		return stdouttrace.New(stdouttrace.WithPrettyPrint())
+	case File:
+		return newFileExporter(ctx, cfg.File)
+	case Jaeger, OpsRamp, NewRelic:
+		var endpoints []failoverEndpoint
+		var conns []*grpc.ClientConn
+		for _, endpoint := range append([]string{cfg.ExporterEndpoint()}, cfg.FailoverEndpoints...) {
+			conn, err := cfg.Dial(endpoint, "traces")
+			if err != nil {
+				closeConns(conns)
+				return nil, err
+			}
+			conns = append(conns, conn)
+			opts := []otlptracegrpc.Option{
+				otlptracegrpc.WithGRPCConn(conn),
+				otlptracegrpc.WithRetry(otlptracegrpc.RetryConfig(cfg.Retry.Config())),
+			}
+			if headers := cfg.ExporterHeaders(); headers != nil {
+				opts = append(opts, otlptracegrpc.WithHeaders(headers))
+			}
+			endpoints = append(endpoints, failoverEndpoint{host: conn.Target(), client: otlptracegrpc.NewClient(opts...), conn: conn})
+		}
+		client := endpoints[0].client
+		if len(endpoints) > 1 {
+			client = newFailoverClient(endpoints)
+		}
+		if cfg.Spool.Dir != "" {
+			client = newSpoolClient(client, cfg.Spool)
+		}
+		exporter, err := otlptrace.New(ctx, client)
+		if err != nil {
+			closeConns(conns)
+			return nil, err
+		}
+		return connExporter{SpanExporter: exporter, conns: conns}, nil
+	default:
+		return nil, fmt.Errorf("unknown backend %q", cfg.Backend)
+	}
+}
+
+// connExporter closes the connections of its exporter, which the exporter
+// does not own, on shutdown.
+type connExporter struct {
+	sdktrace.SpanExporter
+	conns []*grpc.ClientConn
+}
+
+func (e connExporter) Shutdown(ctx context.Context) error {
+	return errors.Join(e.SpanExporter.Shutdown(ctx), closeConns(e.conns))
+}
+
+func closeConns(conns []*grpc.ClientConn) error {
+	var errs []error
+	for _, conn := range conns {
+		errs = append(errs, conn.Close())
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,20 @@
+//go:build notelemetry
+
+package telemetry
+
+import (
+	"context"
+	"errors"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// errNoTelemetry is returned for every exporter of a notelemetry build.
+var errNoTelemetry = errors.New("built with the notelemetry tag")
+
+// newExporter leaves the exporters and their dependencies out of builds with
+// the notelemetry tag: spans are still created and propagated, but never
+// exported.
+func newExporter(context.Context, Exporter) (sdktrace.SpanExporter, error) {
+	return nil, errNoTelemetry
+}
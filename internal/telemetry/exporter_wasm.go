@@ -0,0 +1,22 @@
+//go:build !notelemetry && wasm
+
+package telemetry
+
+import (
+	"context"
+	"log"
+
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// newExporter is the stub exporter of WASM builds, which can neither dial
+// the collectors over gRPC nor write files: every backend prints the spans
+// to stdout, the browser console under js/wasm, and the gRPC exporters are
+// left out of the build.
+func newExporter(_ context.Context, cfg Exporter) (sdktrace.SpanExporter, error) {
+	if cfg.backend() != Stdout {
+		log.Printf("%s exporter unavailable in WASM builds, printing spans to stdout", cfg.backend())
+	}
+	return stdouttrace.New(stdouttrace.WithPrettyPrint())
+}
@@ -0,0 +1,185 @@
+//go:build !notelemetry && !wasm
+
+package telemetry
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+)
+
+const (
+	// failoverThreshold is the number of uploads failing in a row, retries
+	// included, before the failoverClient moves to the next endpoint.
+	failoverThreshold = 3
+	// failoverCheckInterval is how often the failoverClient checks whether
+	// the primary endpoint is back.
+	failoverCheckInterval = 30 * time.Second
+)
+
+// failoverEndpoint is an endpoint of a failoverClient, its client uploading
+// over conn.
+type failoverEndpoint struct {
+	host   string
+	client otlptrace.Client
+	conn   *grpc.ClientConn
+}
+
+// failoverClient is an otlptrace.Client uploading to the first of its
+// endpoints, the primary, and moving on to the next one once
+// failoverThreshold uploads in a row failed, the last failed batch being
+// uploaded again there. While on a secondary, it checks the connection to
+// the primary every failoverCheckInterval and goes back to it once ready.
+// Each switch is logged, counted in otlp.exporter.failovers and recorded as
+// an otlp.exporter.failover span.
+type failoverClient struct {
+	endpoints []failoverEndpoint
+	failovers metric.Int64Counter
+
+	mu       sync.Mutex
+	active   int
+	failures int
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+func newFailoverClient(endpoints []failoverEndpoint) *failoverClient {
+	failovers, err := otel.Meter(instrumentationName).Int64Counter("otlp.exporter.failovers",
+		metric.WithDescription("Number of switches between the OTLP endpoints of an exporter"))
+	if err != nil {
+		log.Printf("failed to create otlp.exporter.failovers counter: %v", err)
+	}
+	return &failoverClient{
+		endpoints: endpoints,
+		failovers: failovers,
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+}
+
+func (c *failoverClient) Start(ctx context.Context) error {
+	for _, e := range c.endpoints {
+		if err := e.client.Start(ctx); err != nil {
+			return err
+		}
+	}
+	go c.checkPrimary()
+	return nil
+}
+
+func (c *failoverClient) Stop(ctx context.Context) error {
+	close(c.stop)
+	<-c.done
+	var err error
+	for _, e := range c.endpoints {
+		if serr := e.client.Stop(ctx); serr != nil && err == nil {
+			err = serr
+		}
+	}
+	return err
+}
+
+func (c *failoverClient) UploadTraces(ctx context.Context, spans []*tracepb.ResourceSpans) error {
+	c.mu.Lock()
+	active := c.active
+	c.mu.Unlock()
+	err := c.endpoints[active].client.UploadTraces(ctx, spans)
+
+	c.mu.Lock()
+	if active != c.active {
+		// Switched meanwhile, the count is the new endpoint's
+		c.mu.Unlock()
+		return err
+	}
+	if err == nil {
+		c.failures = 0
+		c.mu.Unlock()
+		return nil
+	}
+	c.failures++
+	if c.failures < failoverThreshold {
+		c.mu.Unlock()
+		return err
+	}
+	next := (active + 1) % len(c.endpoints)
+	from, to := c.switchTo(next)
+	c.mu.Unlock()
+	// The span cannot be ended within the export of a synchronous processor
+	go c.record(context.WithoutCancel(ctx), from, to, err)
+	return c.endpoints[next].client.UploadTraces(ctx, spans)
+}
+
+// switchTo makes endpoint i the active one and returns the endpoints
+// switched from and to. c.mu must be held.
+func (c *failoverClient) switchTo(i int) (from, to failoverEndpoint) {
+	from, to = c.endpoints[c.active], c.endpoints[i]
+	c.active, c.failures = i, 0
+	return from, to
+}
+
+// record reports the switch from one endpoint to another, failing over
+// because of reason or back to the primary for a nil reason. It must not be
+// called with c.mu held or during an upload, the span may be exported right
+// away.
+func (c *failoverClient) record(ctx context.Context, from, to failoverEndpoint, reason error) {
+	attrs := []attribute.KeyValue{
+		attribute.String("otlp.failover.from", from.host),
+		attribute.String("otlp.failover.to", to.host),
+		attribute.Bool("otlp.failover.primary", to.host == c.endpoints[0].host),
+	}
+	if reason != nil {
+		log.Printf("otlp endpoint %s failed %d exports in a row, failing over to %s: %v", from.host, failoverThreshold, to.host, reason)
+	} else {
+		log.Printf("otlp endpoint %s is back, failing back from %s", to.host, from.host)
+	}
+	if c.failovers != nil {
+		c.failovers.Add(ctx, 1, metric.WithAttributes(attrs...))
+	}
+	_, span := otel.Tracer(instrumentationName).Start(ctx, "otlp.exporter.failover", trace.WithNewRoot())
+	span.AddEvent("failover", trace.WithAttributes(attrs...))
+	if reason != nil {
+		span.RecordError(reason)
+	}
+	span.End()
+}
+
+// checkPrimary fails back to the primary endpoint once its connection is
+// ready, until Stop.
+func (c *failoverClient) checkPrimary() {
+	defer close(c.done)
+	ticker := time.NewTicker(failoverCheckInterval)
+	defer ticker.Stop()
+	primary := c.endpoints[0].conn
+	for {
+		select {
+		case <-c.stop:
+			return
+		case <-ticker.C:
+		}
+		c.mu.Lock()
+		if c.active == 0 {
+			c.mu.Unlock()
+			continue
+		}
+		// An idle connection does not reconnect by itself
+		primary.Connect()
+		if primary.GetState() != connectivity.Ready {
+			c.mu.Unlock()
+			continue
+		}
+		from, to := c.switchTo(0)
+		c.mu.Unlock()
+		c.record(context.Background(), from, to, nil)
+	}
+}
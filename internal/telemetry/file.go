@@ -0,0 +1,49 @@
+package telemetry
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// DefaultFilePath is the file the File backend writes to by default.
+const DefaultFilePath = "traces.jsonl"
+
+// DefaultFileMaxSize is the size past which the file of the File backend is
+// rotated by default.
+const DefaultFileMaxSize = 100 << 20
+
+// FileConfig configures the File backend.
+type FileConfig struct {
+	// Path is the file the spans are appended to, one OTLP JSON
+	// ExportTraceServiceRequest per line. Defaults to DefaultFilePath.
+	Path string
+	// MaxSize is the size in bytes past which the file is rotated: it is
+	// renamed after the time of the rotation, e.g. traces-20240102T150405.jsonl,
+	// and a new one is started. Rotated files are never deleted. Defaults to
+	// DefaultFileMaxSize.
+	MaxSize int64
+}
+
+func (c FileConfig) path() string {
+	if c.Path == "" {
+		return DefaultFilePath
+	}
+	return c.Path
+}
+
+func (c FileConfig) maxSize() int64 {
+	if c.MaxSize <= 0 {
+		return DefaultFileMaxSize
+	}
+	return c.MaxSize
+}
+
+// checkFile reports a file that cannot be written.
+func (e Exporter) checkFile() error {
+	dir := filepath.Dir(e.File.path())
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return fmt.Errorf("file exporter: directory %q of %q does not exist", dir, e.File.path())
+	}
+	return nil
+}
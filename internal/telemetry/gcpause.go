@@ -0,0 +1,174 @@
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"log"
+	"runtime/debug"
+	"runtime/metrics"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// GCPauseOverlapKey is set on exported spans that overlapped stop-the-world
+// GC pauses, to the total overlap in milliseconds.
+const GCPauseOverlapKey = attribute.Key("gc.pause_overlap_ms")
+
+const gcCyclesMetric = "/gc/cycles/total:gc-cycles"
+
+type gcPause struct {
+	start, end time.Time
+}
+
+// gcPauseTagger sits in front of the exporting span processors and tags the
+// spans that overlapped a GC pause with GCPauseOverlapKey, so a slow span can
+// be explained by the runtime. Every pause is also recorded in the
+// runtime.gc.pause histogram. Ended spans are read-only, so the tag is added
+// to the view of the span given to next rather than to the span itself.
+type gcPauseTagger struct {
+	next      []sdktrace.SpanProcessor
+	histogram metric.Float64Histogram
+
+	mu      sync.Mutex
+	sample  []metrics.Sample
+	cycles  uint64
+	lastEnd time.Time
+	pauses  []gcPause
+}
+
+func newGCPauseTagger(next ...sdktrace.SpanProcessor) *gcPauseTagger {
+	t := &gcPauseTagger{
+		next:   next,
+		sample: []metrics.Sample{{Name: gcCyclesMetric}},
+	}
+	// Pauses before the provider existed are not reported
+	t.refresh()
+	t.pauses = nil
+
+	var err error
+	if t.histogram, err = otel.Meter(instrumentationName).Float64Histogram("runtime.gc.pause",
+		metric.WithDescription("Duration of stop-the-world GC pauses"),
+		metric.WithUnit("ms")); err != nil {
+		log.Printf("failed to create runtime.gc.pause histogram: %v", err)
+	}
+	return t
+}
+
+func (t *gcPauseTagger) OnStart(parent context.Context, s sdktrace.ReadWriteSpan) {
+	for _, p := range t.next {
+		p.OnStart(parent, s)
+	}
+}
+
+func (t *gcPauseTagger) OnEnd(s sdktrace.ReadOnlySpan) {
+	if s.SpanContext().IsSampled() {
+		if overlap := t.overlap(s.StartTime(), s.EndTime()); overlap > 0 {
+			s = gcTaggedSpan{ReadOnlySpan: s,
+				attr: GCPauseOverlapKey.Float64(float64(overlap) / float64(time.Millisecond))}
+		}
+	}
+	for _, p := range t.next {
+		p.OnEnd(s)
+	}
+}
+
+func (t *gcPauseTagger) Shutdown(ctx context.Context) error {
+	var errs []error
+	for _, p := range t.next {
+		errs = append(errs, p.Shutdown(ctx))
+	}
+	return errors.Join(errs...)
+}
+
+func (t *gcPauseTagger) ForceFlush(ctx context.Context) error {
+	var errs []error
+	for _, p := range t.next {
+		errs = append(errs, p.ForceFlush(ctx))
+	}
+	return errors.Join(errs...)
+}
+
+// overlap returns how long the pauses since start overlapped [start, end].
+func (t *gcPauseTagger) overlap(start, end time.Time) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.refresh()
+
+	var total time.Duration
+	for _, p := range t.pauses {
+		if p.end.Before(start) {
+			break
+		}
+		from, to := p.start, p.end
+		if from.Before(start) {
+			from = start
+		}
+		if to.After(end) {
+			to = end
+		}
+		if to.After(from) {
+			total += to.Sub(from)
+		}
+	}
+	return total
+}
+
+// refresh reads the new pauses when a GC cycle completed since the last call.
+// Reading the cycle count is cheap, the pause history is only read when it
+// changed. t.mu must be held.
+func (t *gcPauseTagger) refresh() {
+	metrics.Read(t.sample)
+	if t.sample[0].Value.Kind() != metrics.KindUint64 {
+		return
+	}
+	cycles := t.sample[0].Value.Uint64()
+	if cycles == t.cycles {
+		return
+	}
+	t.cycles = cycles
+
+	var stats debug.GCStats
+	debug.ReadGCStats(&stats)
+	// Most recent first, like stats.Pause and stats.PauseEnd
+	var fresh []gcPause
+	for i, end := range stats.PauseEnd {
+		if !end.After(t.lastEnd) {
+			break
+		}
+		p := gcPause{start: end.Add(-stats.Pause[i]), end: end}
+		fresh = append(fresh, p)
+		if t.histogram != nil {
+			t.histogram.Record(context.Background(), float64(stats.Pause[i])/float64(time.Millisecond))
+		}
+	}
+	if len(fresh) == 0 {
+		return
+	}
+	t.lastEnd = fresh[0].end
+	t.pauses = append(fresh, t.pauses...)
+	// Spans rarely last longer than a minute, older pauses cannot overlap
+	cutoff := time.Now().Add(-time.Minute)
+	for i, p := range t.pauses {
+		if p.end.Before(cutoff) {
+			t.pauses = t.pauses[:i]
+			break
+		}
+	}
+}
+
+// gcTaggedSpan is an ended span with one more attribute.
+type gcTaggedSpan struct {
+	sdktrace.ReadOnlySpan
+	attr attribute.KeyValue
+}
+
+func (s gcTaggedSpan) Attributes() []attribute.KeyValue {
+	attrs := s.ReadOnlySpan.Attributes()
+	out := make([]attribute.KeyValue, 0, len(attrs)+1)
+	return append(append(out, attrs...), s.attr)
+}
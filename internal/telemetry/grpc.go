@@ -0,0 +1,156 @@
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"log"
+	"log/slog"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	grpccodes "google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// DeadlineRemainingKey records on a gRPC server span how long the caller
+// left the call to complete, in milliseconds. Deadlines travel in the
+// grpc-timeout header and are set on the context of the handler, so the
+// calls it makes with that context share what is left.
+const DeadlineRemainingKey = attribute.Key("rpc.grpc.deadline_remaining_ms")
+
+// DeadlineExceededKey is set on gRPC server spans whose deadline passed
+// before the handler returned, whatever the handler answered.
+const DeadlineExceededKey = attribute.Key("rpc.grpc.deadline_exceeded")
+
+// UnaryServerInterceptor is the gRPC counterpart of ExtractContext and
+// FinishSpan: it continues the caller's trace from the request metadata,
+// records the call as a server span named after the full method, e.g.
+// "grpc.health.v1.Health/Check", with the deadline left by the caller, and
+// logs it with its trace. Calls that outlive their deadline are counted in
+// rpc.server.deadline_exceeded.
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	t := newGRPCServerTelemetry()
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, span, start := t.start(ctx, info.FullMethod)
+		resp, err := handler(ctx, req)
+		t.finish(ctx, span, info.FullMethod, start, err)
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor is UnaryServerInterceptor for streaming calls, the
+// span covering the whole stream.
+func StreamServerInterceptor() grpc.StreamServerInterceptor {
+	t := newGRPCServerTelemetry()
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, span, start := t.start(ss.Context(), info.FullMethod)
+		err := handler(srv, &tracedStream{ServerStream: ss, ctx: ctx})
+		t.finish(ctx, span, info.FullMethod, start, err)
+		return err
+	}
+}
+
+type grpcServerTelemetry struct {
+	tracer           trace.Tracer
+	deadlineExceeded metric.Int64Counter
+}
+
+func newGRPCServerTelemetry() *grpcServerTelemetry {
+	t := &grpcServerTelemetry{tracer: otel.Tracer(instrumentationName)}
+	var err error
+	if t.deadlineExceeded, err = otel.Meter(instrumentationName).Int64Counter("rpc.server.deadline_exceeded",
+		metric.WithDescription("Number of gRPC calls still running when their deadline passed")); err != nil {
+		log.Printf("failed to create rpc.server.deadline_exceeded counter: %v", err)
+	}
+	return t
+}
+
+func (t *grpcServerTelemetry) start(ctx context.Context, fullMethod string) (context.Context, trace.Span, time.Time) {
+	now := time.Now()
+	md, _ := metadata.FromIncomingContext(ctx)
+	ctx = otel.GetTextMapPropagator().Extract(ctx, metadataCarrier(md))
+	service, method := splitFullMethod(fullMethod)
+	attrs := []attribute.KeyValue{semconv.RPCSystemGRPC, semconv.RPCServiceKey.String(service), semconv.RPCMethodKey.String(method)}
+	if deadline, ok := ctx.Deadline(); ok {
+		attrs = append(attrs, DeadlineRemainingKey.Float64(float64(deadline.Sub(now))/float64(time.Millisecond)))
+	}
+	ctx, span := t.tracer.Start(ctx, strings.TrimPrefix(fullMethod, "/"),
+		trace.WithSpanKind(trace.SpanKindServer), trace.WithAttributes(attrs...))
+	return ctx, span, now
+}
+
+// finish follows the server span status rules of the gRPC semantic
+// conventions: only the codes that point at a server fault mark the span as
+// an error.
+func (t *grpcServerTelemetry) finish(ctx context.Context, span trace.Span, fullMethod string, start time.Time, err error) {
+	code := status.Code(err)
+	span.SetAttributes(semconv.RPCGRPCStatusCodeKey.Int(int(code)))
+	switch code {
+	case grpccodes.OK:
+	case grpccodes.Unknown, grpccodes.DeadlineExceeded, grpccodes.Unimplemented, grpccodes.Internal,
+		grpccodes.Unavailable, grpccodes.DataLoss:
+		span.RecordError(err)
+		span.SetStatus(codes.Error, status.Convert(err).Message())
+	default:
+		span.RecordError(err)
+	}
+	exceeded := errors.Is(ctx.Err(), context.DeadlineExceeded) || code == grpccodes.DeadlineExceeded
+	if exceeded {
+		span.SetAttributes(DeadlineExceededKey.Bool(true))
+		if t.deadlineExceeded != nil {
+			service, method := splitFullMethod(fullMethod)
+			t.deadlineExceeded.Add(ctx, 1, metric.WithAttributes(
+				semconv.RPCServiceKey.String(service), semconv.RPCMethodKey.String(method)))
+		}
+	}
+	span.End()
+
+	level := slog.LevelInfo
+	if code != grpccodes.OK || exceeded {
+		level = slog.LevelWarn
+	}
+	slog.Log(ctx, level, "grpc call", "method", fullMethod, "code", code.String(), "deadline_exceeded", exceeded,
+		"duration_ms", float64(time.Since(start))/float64(time.Millisecond))
+}
+
+// splitFullMethod splits "/package.Service/Method".
+func splitFullMethod(fullMethod string) (service, method string) {
+	service, method, _ = strings.Cut(strings.TrimPrefix(fullMethod, "/"), "/")
+	return service, method
+}
+
+// tracedStream hands the context with the server span to the handler.
+type tracedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *tracedStream) Context() context.Context { return s.ctx }
+
+// metadataCarrier adapts gRPC metadata to the propagators.
+type metadataCarrier metadata.MD
+
+func (c metadataCarrier) Get(key string) string {
+	if v := metadata.MD(c).Get(key); len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+
+func (c metadataCarrier) Set(key, value string) { metadata.MD(c).Set(key, value) }
+
+func (c metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for k := range c {
+		keys = append(keys, k)
+	}
+	return keys
+}
@@ -0,0 +1,67 @@
+package telemetry
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// SchemaURL is the semantic conventions version the attributes of the
+// services follow. Every package sets them with the keys of this version,
+// so backends can translate them to the version they expect.
+const SchemaURL = semconv.SchemaURL
+
+// HTTPClientAttributes describes an outgoing request as the HTTP client
+// semantic conventions do: http.request.method, url.full without the user
+// info, server.address and server.port, the default one of the scheme when
+// the URL has none.
+func HTTPClientAttributes(req *http.Request) []attribute.KeyValue {
+	u := *req.URL
+	u.User = nil
+	attrs := []attribute.KeyValue{
+		semconv.HTTPRequestMethodKey.String(req.Method),
+		semconv.URLFull(u.String()),
+		semconv.ServerAddress(u.Hostname()),
+	}
+	port, err := strconv.Atoi(u.Port())
+	switch {
+	case err == nil:
+	case u.Scheme == "https":
+		port = 443
+	default:
+		port = 80
+	}
+	return append(attrs, semconv.ServerPort(port))
+}
+
+// PeerAttributes describes the other end of a connection, addr being
+// host:port, as network.peer.address and network.peer.port.
+func PeerAttributes(addr string) []attribute.KeyValue {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return []attribute.KeyValue{semconv.NetworkPeerAddress(addr)}
+	}
+	attrs := []attribute.KeyValue{semconv.NetworkPeerAddress(host)}
+	if p, err := strconv.Atoi(port); err == nil {
+		attrs = append(attrs, semconv.NetworkPeerPort(p))
+	}
+	return attrs
+}
+
+// HTTPSpanStatus is the span status of an HTTP response for a span of kind:
+// a status code out of range or a server error is an error, and so is a
+// client error on the client side only. The others leave the status unset.
+func HTTPSpanStatus(statusCode int, kind trace.SpanKind) (codes.Code, string) {
+	switch {
+	case statusCode < 100 || statusCode >= 600:
+		return codes.Error, "invalid HTTP status code " + strconv.Itoa(statusCode)
+	case statusCode >= 500, statusCode >= 400 && kind == trace.SpanKindClient:
+		return codes.Error, ""
+	}
+	return codes.Unset, ""
+}
@@ -0,0 +1,155 @@
+package telemetry
+
+import (
+	"context"
+	"io"
+	"log/slog"
+
+	otellog "go.opentelemetry.io/otel/log"
+	"go.opentelemetry.io/otel/log/global"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// LogHandlerOptions configure NewLogHandler.
+type LogHandlerOptions struct {
+	// Level is the minimum level of the records handled, slog.LevelInfo by
+	// default.
+	Level slog.Leveler
+	// Export also emits every record as an OpenTelemetry log record through
+	// the global logger provider, see logs.NewLoggerProvider.
+	Export bool
+	// TraceLink adds the link to the trace of the record context to the
+	// error records, as trace_url.
+	TraceLink TraceLink
+}
+
+// LogHandler writes records as JSON lines, with the trace_id, span_id and
+// trace_flags of the span in the record context, and optionally emits them as
+// OpenTelemetry log records, which the SDK correlates with the span itself.
+type LogHandler struct {
+	json   slog.Handler
+	logger otellog.Logger // nil unless exporting
+	link   TraceLink
+
+	attrs  []otellog.KeyValue
+	prefix string // groups opened with WithGroup, dot separated
+}
+
+// NewLogHandler returns a handler writing to w, e.g.
+//
+//	slog.SetDefault(slog.New(telemetry.NewLogHandler(os.Stdout, &telemetry.LogHandlerOptions{Export: true})))
+func NewLogHandler(w io.Writer, opts *LogHandlerOptions) *LogHandler {
+	if opts == nil {
+		opts = &LogHandlerOptions{}
+	}
+	h := &LogHandler{json: slog.NewJSONHandler(w, &slog.HandlerOptions{Level: opts.Level}), link: opts.TraceLink}
+	if opts.Export {
+		h.logger = global.Logger(instrumentationName)
+	}
+	return h
+}
+
+func (h *LogHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.json.Enabled(ctx, level)
+}
+
+func (h *LogHandler) Handle(ctx context.Context, r slog.Record) error {
+	if h.logger != nil {
+		h.logger.Emit(ctx, h.otelRecord(r))
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		r = r.Clone()
+		r.AddAttrs(
+			slog.String("trace_id", sc.TraceID().String()),
+			slog.String("span_id", sc.SpanID().String()),
+			slog.String("trace_flags", sc.TraceFlags().String()))
+		if u := h.link.URL(sc); u != "" && r.Level >= slog.LevelError {
+			r.AddAttrs(slog.String("trace_url", u))
+		}
+	}
+	return h.json.Handle(ctx, r)
+}
+
+func (h *LogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	c := *h
+	c.json = h.json.WithAttrs(attrs)
+	c.attrs = append(append([]otellog.KeyValue(nil), h.attrs...), otelAttrs(h.prefix, attrs)...)
+	return &c
+}
+
+func (h *LogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	c := *h
+	c.json = h.json.WithGroup(name)
+	c.prefix = h.prefix + name + "."
+	return &c
+}
+
+func (h *LogHandler) otelRecord(r slog.Record) otellog.Record {
+	var rec otellog.Record
+	rec.SetTimestamp(r.Time)
+	rec.SetSeverity(severity(r.Level))
+	rec.SetSeverityText(r.Level.String())
+	rec.SetBody(otellog.StringValue(r.Message))
+	rec.AddAttributes(h.attrs...)
+	r.Attrs(func(a slog.Attr) bool {
+		rec.AddAttributes(otelAttrs(h.prefix, []slog.Attr{a})...)
+		return true
+	})
+	return rec
+}
+
+// severity maps the slog levels, 4 apart, onto the OpenTelemetry severity
+// ranges, e.g. slog.LevelWarn+1 to SeverityWarn2.
+func severity(level slog.Level) otellog.Severity {
+	s := otellog.Severity(level) + otellog.SeverityInfo
+	if s < otellog.SeverityTrace1 {
+		return otellog.SeverityTrace1
+	}
+	if s > otellog.SeverityFatal4 {
+		return otellog.SeverityFatal4
+	}
+	return s
+}
+
+// otelAttrs converts attrs, flattening groups into dot separated keys.
+func otelAttrs(prefix string, attrs []slog.Attr) []otellog.KeyValue {
+	var kvs []otellog.KeyValue
+	for _, a := range attrs {
+		v := a.Value.Resolve()
+		if v.Kind() == slog.KindGroup {
+			p := prefix
+			if a.Key != "" {
+				p += a.Key + "."
+			}
+			kvs = append(kvs, otelAttrs(p, v.Group())...)
+			continue
+		}
+		if a.Key == "" {
+			continue
+		}
+		kvs = append(kvs, otellog.KeyValue{Key: prefix + a.Key, Value: otelValue(v)})
+	}
+	return kvs
+}
+
+func otelValue(v slog.Value) otellog.Value {
+	switch v.Kind() {
+	case slog.KindString:
+		return otellog.StringValue(v.String())
+	case slog.KindInt64:
+		return otellog.Int64Value(v.Int64())
+	case slog.KindUint64:
+		return otellog.Int64Value(int64(v.Uint64()))
+	case slog.KindFloat64:
+		return otellog.Float64Value(v.Float64())
+	case slog.KindBool:
+		return otellog.BoolValue(v.Bool())
+	}
+	if err, ok := v.Any().(error); ok {
+		return otellog.StringValue(err.Error())
+	}
+	return otellog.StringValue(v.String())
+}
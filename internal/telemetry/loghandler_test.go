@@ -0,0 +1,37 @@
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestLogHandlerAddsTraceContext(t *testing.T) {
+	sc := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16},
+		SpanID:     trace.SpanID{1, 2, 3, 4, 5, 6, 7, 8},
+		TraceFlags: trace.FlagsSampled,
+	})
+	var buf bytes.Buffer
+	logger := slog.New(NewLogHandler(&buf, nil))
+	logger.InfoContext(trace.ContextWithSpanContext(context.Background(), sc), "hello")
+
+	var line map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+		t.Fatalf("decode %q: %v", buf.String(), err)
+	}
+	want := map[string]string{
+		"trace_id":    sc.TraceID().String(),
+		"span_id":     sc.SpanID().String(),
+		"trace_flags": "01",
+	}
+	for k, v := range want {
+		if line[k] != v {
+			t.Errorf("%s = %v, want %s", k, line[k], v)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+//go:build !notelemetry && !wasm
+
+package logs
+
+import (
+	"context"
+	"sync"
+
+	"go.opentelemetry.io/otel/exporters/otlp/otlplog/otlploghttp"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+
+	"test-jaeger/internal/telemetry"
+)
+
+// bearerExporter authorizes the log exports with the tokens of an OpsRamp
+// telemetry.TokenSource. The HTTP exporter only takes static headers, so it
+// is created again whenever the token changes.
+type bearerExporter struct {
+	tokens *telemetry.TokenSource
+	opts   []otlploghttp.Option
+
+	mu       sync.Mutex
+	token    string
+	exporter *otlploghttp.Exporter
+}
+
+func (e *bearerExporter) Export(ctx context.Context, records []sdklog.Record) error {
+	exporter, err := e.current(ctx)
+	if err != nil {
+		return err
+	}
+	return exporter.Export(ctx, records)
+}
+
+// current returns the exporter sending the current token.
+func (e *bearerExporter) current(ctx context.Context) (*otlploghttp.Exporter, error) {
+	token, err := e.tokens.Token(ctx)
+	if err != nil {
+		return nil, err
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.exporter != nil && token == e.token {
+		return e.exporter, nil
+	}
+
+	opts := append(e.opts[:len(e.opts):len(e.opts)],
+		otlploghttp.WithHeaders(map[string]string{"Authorization": "Bearer " + token}))
+	exporter, err := otlploghttp.New(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if e.exporter != nil {
+		e.exporter.Shutdown(ctx)
+	}
+	e.token, e.exporter = token, exporter
+	return exporter, nil
+}
+
+func (e *bearerExporter) Shutdown(ctx context.Context) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.exporter == nil {
+		return nil
+	}
+	return e.exporter.Shutdown(ctx)
+}
+
+func (e *bearerExporter) ForceFlush(ctx context.Context) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.exporter == nil {
+		return nil
+	}
+	return e.exporter.ForceFlush(ctx)
+}
@@ -0,0 +1,37 @@
+package logs
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/log"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+
+	"test-jaeger/internal/telemetry"
+)
+
+// costExporter reports the records it exports to a telemetry.CostEstimator.
+type costExporter struct {
+	sdklog.Exporter
+	costs *telemetry.CostEstimator
+}
+
+func (e costExporter) Export(ctx context.Context, records []sdklog.Record) error {
+	size := 0
+	for _, r := range records {
+		size += recordSize(r)
+	}
+	e.costs.Add("logs", int64(len(records)), int64(size))
+	return e.Exporter.Export(ctx, records)
+}
+
+// recordSize approximates the OTLP protobuf size of r: timestamps, severity
+// and trace context, plus the body and attributes with a few bytes of
+// framing each.
+func recordSize(r sdklog.Record) int {
+	size := 60 + len(r.SeverityText()) + len(r.Body().String())
+	r.WalkAttributes(func(kv log.KeyValue) bool {
+		size += 4 + len(kv.Key) + len(kv.Value.String())
+		return true
+	})
+	return size
+}
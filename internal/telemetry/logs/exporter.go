@@ -0,0 +1,74 @@
+//go:build !notelemetry && !wasm
+
+package logs
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+
+	"go.opentelemetry.io/otel/exporters/otlp/otlplog/otlploghttp"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+
+	"test-jaeger/internal/telemetry"
+)
+
+// otlpHTTPPort is the port of the OTLP HTTP receiver of a collector. Only an
+// HTTP log exporter is available, the gRPC port of cfg.Endpoint is swapped
+// for this one.
+const otlpHTTPPort = "4318"
+
+// newExporter creates the OTLP HTTP log exporter of cfg, refreshing its
+// bearer token for OpsRamp. Builds with the notelemetry tag have none, see
+// exporter_noop.go.
+func newExporter(ctx context.Context, cfg telemetry.Config) (sdklog.Exporter, error) {
+	opts, err := exporterOptions(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if ts := cfg.TokenSource(); ts != nil {
+		return &bearerExporter{tokens: ts, opts: opts}, nil
+	}
+	exporter, err := otlploghttp.New(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("create log exporter: %w", err)
+	}
+	return exporter, nil
+}
+
+// exporterOptions points the exporter at the host of the endpoint of cfg,
+// see telemetry.Config.ExporterEndpoint, with its headers, retry policy,
+// compression and TLS settings.
+// OTEL_EXPORTER_OTLP_* variables take precedence, as for traces.
+func exporterOptions(cfg telemetry.Config) ([]otlploghttp.Option, error) {
+	opts := []otlploghttp.Option{otlploghttp.WithRetry(otlploghttp.RetryConfig(cfg.Retry.Config()))}
+	if headers := cfg.ExporterHeaders(); headers != nil {
+		opts = append(opts, otlploghttp.WithHeaders(headers))
+	}
+	if cfg.Gzip() {
+		opts = append(opts, otlploghttp.WithCompression(otlploghttp.GzipCompression))
+	}
+	if os.Getenv("OTEL_EXPORTER_OTLP_LOGS_ENDPOINT") != "" || os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") != "" {
+		return opts, nil
+	}
+
+	endpoint := cfg.ExporterEndpoint()
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Hostname() == "" {
+		return nil, fmt.Errorf("invalid log endpoint %q", endpoint)
+	}
+	opts = append(opts, otlploghttp.WithEndpoint(net.JoinHostPort(u.Hostname(), otlpHTTPPort)))
+	switch {
+	case u.Scheme == "http":
+		opts = append(opts, otlploghttp.WithInsecure())
+	case !cfg.TLS.IsZero():
+		tlsConfig, err := cfg.TLS.Config()
+		if err != nil {
+			return nil, fmt.Errorf("log endpoint %s: %w", u.Host, err)
+		}
+		opts = append(opts, otlploghttp.WithTLSClientConfig(tlsConfig))
+	}
+	return opts, nil
+}
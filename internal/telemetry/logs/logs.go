@@ -0,0 +1,55 @@
+// Package logs builds the logger provider shared by every demo service. It
+// exports to the same collector as the traces, see telemetry.Config, so the
+// logs carry the same resource and can be joined to the spans.
+package logs
+
+import (
+	"context"
+	"log"
+
+	"go.opentelemetry.io/otel/log/global"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+
+	"test-jaeger/internal/telemetry"
+)
+
+// NewLoggerProvider creates an OTLP HTTP log exporter, batches the records
+// to it and installs the resulting provider globally, where
+// telemetry.LogHandler emits to it. Callers should defer Shutdown on the
+// returned provider.
+func NewLoggerProvider(ctx context.Context, cfg telemetry.Config) (*sdklog.LoggerProvider, error) {
+	res, err := telemetry.NewResource(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	popts := []sdklog.LoggerProviderOption{sdklog.WithResource(res)}
+	// Without a collector, or in builds without telemetry, the slog handler
+	// still writes the records to stdout.
+	if cfg.ExportsToCollector() {
+		exporter, err := newExporter(ctx, cfg)
+		if err != nil {
+			return nil, err
+		}
+		if exporter != nil && cfg.CostEstimator != nil {
+			exporter = costExporter{Exporter: exporter, costs: cfg.CostEstimator}
+		}
+		if exporter != nil {
+			popts = append(popts, sdklog.WithProcessor(sdklog.NewBatchProcessor(exporter)))
+		}
+	}
+
+	provider := sdklog.NewLoggerProvider(popts...)
+	global.SetLoggerProvider(provider)
+	return provider, nil
+}
+
+// Shutdown exports the pending records and stops the provider. Failures are
+// logged rather than returned because it is meant to be deferred from main.
+func Shutdown(provider *sdklog.LoggerProvider) {
+	ctx, cancel := context.WithTimeout(context.Background(), telemetry.ShutdownTimeout)
+	defer cancel()
+	if err := provider.Shutdown(ctx); err != nil {
+		log.Printf("failed to shutdown logger provider: %v", err)
+	}
+}
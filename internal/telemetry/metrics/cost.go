@@ -0,0 +1,67 @@
+package metrics
+
+import (
+	"context"
+
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+
+	"test-jaeger/internal/telemetry"
+)
+
+// costExporter reports the data points it exports to a
+// telemetry.CostEstimator.
+type costExporter struct {
+	sdkmetric.Exporter
+	costs *telemetry.CostEstimator
+}
+
+func (e costExporter) Export(ctx context.Context, rm *metricdata.ResourceMetrics) error {
+	var points, size int
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			n, s := dataSize(m.Data)
+			points += n
+			size += 10 + len(m.Name) + len(m.Description) + len(m.Unit) + s
+		}
+	}
+	e.costs.Add("metrics", int64(points), int64(size))
+	return e.Exporter.Export(ctx, rm)
+}
+
+// dataSize counts the data points of data and approximates their OTLP
+// protobuf size: timestamps and value, the buckets of the histograms, and
+// the attributes.
+func dataSize(data metricdata.Aggregation) (points, size int) {
+	switch d := data.(type) {
+	case metricdata.Gauge[int64]:
+		return len(d.DataPoints), pointsSize(d.DataPoints)
+	case metricdata.Gauge[float64]:
+		return len(d.DataPoints), pointsSize(d.DataPoints)
+	case metricdata.Sum[int64]:
+		return len(d.DataPoints), pointsSize(d.DataPoints)
+	case metricdata.Sum[float64]:
+		return len(d.DataPoints), pointsSize(d.DataPoints)
+	case metricdata.Histogram[int64]:
+		return len(d.DataPoints), histogramSize(d.DataPoints)
+	case metricdata.Histogram[float64]:
+		return len(d.DataPoints), histogramSize(d.DataPoints)
+	}
+	return 0, 0
+}
+
+func pointsSize[N int64 | float64](dps []metricdata.DataPoint[N]) int {
+	size := 0
+	for _, dp := range dps {
+		size += 30 + telemetry.AttributesSize(dp.Attributes.ToSlice())
+	}
+	return size
+}
+
+func histogramSize[N int64 | float64](dps []metricdata.HistogramDataPoint[N]) int {
+	size := 0
+	for _, dp := range dps {
+		size += 50 + 9*(len(dp.Bounds)+len(dp.BucketCounts)) + telemetry.AttributesSize(dp.Attributes.ToSlice())
+	}
+	return size
+}
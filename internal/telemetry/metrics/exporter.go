@@ -0,0 +1,67 @@
+//go:build !nometrics && !notelemetry && !wasm
+
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"google.golang.org/grpc"
+
+	"test-jaeger/internal/telemetry"
+)
+
+// newReader periodically exports the measurements to the endpoint of cfg.
+// Builds with the nometrics or notelemetry tag have none, see
+// exporter_noop.go.
+func newReader(ctx context.Context, cfg telemetry.Config) (sdkmetric.Reader, error) {
+	cfg.TLS = cfg.TLS.WithEnv("METRICS")
+	conn, err := cfg.Dial(endpoint(cfg), "metrics")
+	if err != nil {
+		return nil, err
+	}
+	opts := []otlpmetricgrpc.Option{
+		otlpmetricgrpc.WithGRPCConn(conn),
+		otlpmetricgrpc.WithRetry(otlpmetricgrpc.RetryConfig(cfg.Retry.Config())),
+	}
+	if headers := cfg.ExporterHeaders(); headers != nil {
+		opts = append(opts, otlpmetricgrpc.WithHeaders(headers))
+	}
+	exporter, err := otlpmetricgrpc.New(ctx, opts...)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("create metric exporter: %w", err)
+	}
+	var export sdkmetric.Exporter = connExporter{Exporter: exporter, conn: conn}
+	if cfg.CostEstimator != nil {
+		export = costExporter{Exporter: export, costs: cfg.CostEstimator}
+	}
+	return sdkmetric.NewPeriodicReader(export), nil
+}
+
+// endpoint is the endpoint of cfg, see telemetry.Config.ExporterEndpoint,
+// unless an OTEL_EXPORTER_OTLP_* variable sets one, as for traces.
+func endpoint(cfg telemetry.Config) string {
+	if v := os.Getenv("OTEL_EXPORTER_OTLP_METRICS_ENDPOINT"); v != "" {
+		return v
+	}
+	if v := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"); v != "" {
+		return v
+	}
+	return cfg.ExporterEndpoint()
+}
+
+// connExporter closes the connection of its exporter, which the exporter
+// does not own, on shutdown.
+type connExporter struct {
+	sdkmetric.Exporter
+	conn *grpc.ClientConn
+}
+
+func (e connExporter) Shutdown(ctx context.Context) error {
+	return errors.Join(e.Exporter.Shutdown(ctx), e.conn.Close())
+}
@@ -0,0 +1,77 @@
+// Package metrics builds the meter provider shared by every demo service. It
+// exports to the same backend and endpoint as the traces, see telemetry.Config.
+package metrics
+
+import (
+	"context"
+	"log"
+	"os"
+	"strconv"
+
+	"go.opentelemetry.io/otel"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+
+	"test-jaeger/internal/telemetry"
+)
+
+// NewMeterProvider creates an OTLP gRPC metric exporter, reads it with a
+// periodic reader and installs the resulting provider globally. The export
+// interval defaults to one minute and follows OTEL_METRIC_EXPORT_INTERVAL.
+// Measurements keep exemplars according to cfg.ExemplarFilter. The readers
+// are added as they are, e.g. a manual reader for in-process inspection.
+// Callers should defer Shutdown on the returned provider.
+func NewMeterProvider(ctx context.Context, cfg telemetry.Config, readers ...sdkmetric.Reader) (*sdkmetric.MeterProvider, error) {
+	enableExemplars(cfg.ExemplarFilter)
+
+	res, err := telemetry.NewResource(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	popts := []sdkmetric.Option{sdkmetric.WithResource(res)}
+	// Without a collector to export to, or in builds without metrics, the
+	// instruments are kept but nothing reads them.
+	if cfg.ExportsToCollector() {
+		reader, err := newReader(ctx, cfg)
+		if err != nil {
+			return nil, err
+		}
+		if reader != nil {
+			popts = append(popts, sdkmetric.WithReader(reader))
+		}
+	}
+
+	for _, r := range readers {
+		popts = append(popts, sdkmetric.WithReader(r))
+	}
+	provider := sdkmetric.NewMeterProvider(popts...)
+	otel.SetMeterProvider(provider)
+	return provider, nil
+}
+
+// Shutdown exports the pending measurements and stops the provider. Failures
+// are logged rather than returned because it is meant to be deferred from main.
+func Shutdown(provider *sdkmetric.MeterProvider) {
+	ctx, cancel := context.WithTimeout(context.Background(), telemetry.ShutdownTimeout)
+	defer cancel()
+	if err := provider.Shutdown(ctx); err != nil {
+		log.Printf("failed to shutdown meter provider: %v", err)
+	}
+}
+
+// enableExemplars turns on the exemplar support of the SDK, still behind an
+// experimental switch in this version, with the given filter. Like the other
+// OTEL_* variables, the ones already set in the environment win.
+func enableExemplars(filter string) {
+	if filter == "" {
+		filter = "trace_based"
+	}
+	setenvDefault("OTEL_GO_X_EXEMPLAR", strconv.FormatBool(filter != "always_off"))
+	setenvDefault("OTEL_METRICS_EXEMPLAR_FILTER", filter)
+}
+
+func setenvDefault(key, value string) {
+	if _, ok := os.LookupEnv(key); !ok {
+		os.Setenv(key, value)
+	}
+}
@@ -0,0 +1,81 @@
+package telemetry
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// The OTLP gRPC endpoints of New Relic. License keys of EU accounts start
+// with "eu" and are only accepted by NewRelicEUEndpoint.
+const (
+	NewRelicEndpoint   = "https://otlp.nr-data.net:4317"
+	NewRelicEUEndpoint = "https://otlp.eu01.nr-data.net:4317"
+)
+
+// licenseKey is c.LicenseKey, falling back to NEW_RELIC_LICENSE_KEY.
+func (e Exporter) licenseKey() string {
+	if e.LicenseKey != "" {
+		return e.LicenseKey
+	}
+	return os.Getenv("NEW_RELIC_LICENSE_KEY")
+}
+
+// ExporterHeaders are the headers sent by the OTLP exporters of c: the
+// api-key of New Relic when its license key is known. When nil, the
+// exporters read OTEL_EXPORTER_OTLP_HEADERS.
+func (e Exporter) ExporterHeaders() map[string]string {
+	if e.backend() != NewRelic {
+		return nil
+	}
+	if key := e.licenseKey(); key != "" {
+		return map[string]string{"api-key": key}
+	}
+	return nil
+}
+
+// ValidateLicenseKey checks the format of a New Relic ingest license key:
+// 40 characters, either ending in NRAL or hexadecimal for older keys.
+func ValidateLicenseKey(key string) error {
+	if len(key) != 40 {
+		return fmt.Errorf("license key must be 40 characters long, got %d", len(key))
+	}
+	if strings.HasSuffix(key, "NRAL") {
+		return nil
+	}
+	if strings.Trim(strings.ToLower(key), "0123456789abcdef") != "" {
+		return errors.New("license key must end in NRAL or be hexadecimal")
+	}
+	return nil
+}
+
+// checkNewRelic reports what is missing or invalid for exporting to New
+// Relic, so the service fails at startup with the list instead of having its
+// exports rejected.
+func (e Exporter) checkNewRelic() error {
+	var errs []error
+	if key := e.licenseKey(); key != "" {
+		if err := ValidateLicenseKey(key); err != nil {
+			errs = append(errs, fmt.Errorf("new relic: %w", err))
+		}
+	} else if !envHeader("api-key") {
+		errs = append(errs, errors.New("new relic: no license key, set exporter.license_key, NEW_RELIC_LICENSE_KEY or api-key in OTEL_EXPORTER_OTLP_HEADERS"))
+	}
+	if strings.HasPrefix(e.Endpoint, "http://") {
+		errs = append(errs, fmt.Errorf("new relic: endpoint %s must use https", e.Endpoint))
+	}
+	return errors.Join(errs...)
+}
+
+// envHeader reports whether the OTLP headers variables set name.
+func envHeader(name string) bool {
+	for _, v := range []string{os.Getenv("OTEL_EXPORTER_OTLP_TRACES_HEADERS"), os.Getenv("OTEL_EXPORTER_OTLP_HEADERS")} {
+		for _, h := range strings.Split(v, ",") {
+			if k, _, ok := strings.Cut(h, "="); ok && strings.EqualFold(strings.TrimSpace(k), name) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,236 @@
+package telemetry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// OpsRampConfig holds the OAuth2 client credentials of an OpsRamp integration,
+// whose exports are authorized with the bearer tokens they are exchanged
+// for, and the IDs OpsRamp files the telemetry under.
+type OpsRampConfig struct {
+	// TokenURL is the token endpoint of the tenant, e.g.
+	// https://<tenant>.api.opsramp.com/auth/oauth/token. Defaults to
+	// OPSRAMP_TOKEN_URL.
+	TokenURL string
+	// ClientID and ClientSecret default to OPSRAMP_CLIENT_ID and
+	// OPSRAMP_CLIENT_SECRET.
+	ClientID     string
+	ClientSecret string
+	// TenantID and ResourceUUID are recorded on the resource, see
+	// OpsRampTenantIDKey and OpsRampResourceUUIDKey. They default to
+	// OPSRAMP_TENANT_ID and OPSRAMP_RESOURCE_UUID.
+	TenantID     string
+	ResourceUUID string
+}
+
+// The resource attributes identifying the OpsRamp tenant and resource of a
+// service.
+const (
+	OpsRampTenantIDKey     = attribute.Key("opsramp.tenant.id")
+	OpsRampResourceUUIDKey = attribute.Key("opsramp.resource.uuid")
+)
+
+// opsRampAttributes identify the tenant and resource of the service.
+func (e Exporter) opsRampAttributes() []attribute.KeyValue {
+	o := e.opsRamp()
+	var attrs []attribute.KeyValue
+	if o.TenantID != "" {
+		attrs = append(attrs, OpsRampTenantIDKey.String(o.TenantID))
+	}
+	if o.ResourceUUID != "" {
+		attrs = append(attrs, OpsRampResourceUUIDKey.String(o.ResourceUUID))
+	}
+	return attrs
+}
+
+// ValidateUUID checks that s is a UUID in its canonical textual form, e.g.
+// 0f6f3c6e-8a53-4c5c-9d8e-6a1b2c3d4e5f.
+func ValidateUUID(s string) error {
+	if len(s) != 36 {
+		return fmt.Errorf("%q is not a UUID", s)
+	}
+	for i, r := range s {
+		switch i {
+		case 8, 13, 18, 23:
+			if r != '-' {
+				return fmt.Errorf("%q is not a UUID", s)
+			}
+		default:
+			if !strings.ContainsRune("0123456789abcdefABCDEF", r) {
+				return fmt.Errorf("%q is not a UUID", s)
+			}
+		}
+	}
+	return nil
+}
+
+// tokenRefreshMargin is how long before its expiry a token is replaced, so
+// an export in flight is not rejected. Short lived tokens are replaced half
+// way through their lifetime instead.
+const tokenRefreshMargin = time.Minute
+
+// defaultTokenLifetime is the lifetime of a token whose response has no
+// expires_in.
+const defaultTokenLifetime = 5 * time.Minute
+
+// opsRamp is c.OpsRamp completed by the OPSRAMP_* variables.
+func (e Exporter) opsRamp() OpsRampConfig {
+	o := e.OpsRamp
+	for _, f := range []struct {
+		field *string
+		env   string
+	}{
+		{&o.TokenURL, "OPSRAMP_TOKEN_URL"},
+		{&o.ClientID, "OPSRAMP_CLIENT_ID"},
+		{&o.ClientSecret, "OPSRAMP_CLIENT_SECRET"},
+		{&o.TenantID, "OPSRAMP_TENANT_ID"},
+		{&o.ResourceUUID, "OPSRAMP_RESOURCE_UUID"},
+	} {
+		if *f.field == "" {
+			*f.field = os.Getenv(f.env)
+		}
+	}
+	return o
+}
+
+// checkOpsRamp lists the missing settings, so the service fails at startup
+// rather than on every export.
+func (e Exporter) checkOpsRamp() error {
+	o := e.opsRamp()
+	var errs []error
+	if o.TokenURL == "" {
+		errs = append(errs, errors.New("opsramp: no token URL, set exporter.opsramp.token_url or OPSRAMP_TOKEN_URL"))
+	} else if u, err := url.Parse(o.TokenURL); err != nil || u.Host == "" {
+		errs = append(errs, fmt.Errorf("opsramp: token URL %q is not an absolute URL", o.TokenURL))
+	}
+	if o.ClientID == "" {
+		errs = append(errs, errors.New("opsramp: no client ID, set exporter.opsramp.client_id or OPSRAMP_CLIENT_ID"))
+	}
+	if o.ClientSecret == "" {
+		errs = append(errs, errors.New("opsramp: no client secret, set exporter.opsramp.client_secret or OPSRAMP_CLIENT_SECRET"))
+	}
+	if o.TenantID == "" {
+		errs = append(errs, errors.New("opsramp: no tenant ID, set exporter.opsramp.tenant_id or OPSRAMP_TENANT_ID"))
+	}
+	if o.ResourceUUID == "" {
+		errs = append(errs, errors.New("opsramp: no resource UUID, set exporter.opsramp.resource_uuid or OPSRAMP_RESOURCE_UUID"))
+	} else if err := ValidateUUID(o.ResourceUUID); err != nil {
+		errs = append(errs, fmt.Errorf("opsramp: resource UUID: %w", err))
+	}
+	return errors.Join(errs...)
+}
+
+// tokenSources are shared by the exporters of a process, so the traces,
+// metrics and logs do not fetch a token each.
+var tokenSources sync.Map // tokenSourceKey -> *TokenSource
+
+type tokenSourceKey struct {
+	auth     OpsRampConfig
+	insecure bool
+}
+
+// TokenSource returns the source of the bearer tokens of the OpsRamp
+// exports of c, or nil for the other backends.
+func (e Exporter) TokenSource() *TokenSource {
+	if e.backend() != OpsRamp {
+		return nil
+	}
+	key := tokenSourceKey{
+		auth:     e.opsRamp(),
+		insecure: strings.HasPrefix(e.ExporterEndpoint(), "http://"),
+	}
+	ts, _ := tokenSources.LoadOrStore(key, &TokenSource{
+		auth:     key.auth,
+		insecure: key.insecure,
+		client:   &http.Client{Timeout: 10 * time.Second},
+	})
+	return ts.(*TokenSource)
+}
+
+// TokenSource exchanges OAuth2 client credentials for access tokens and
+// caches them until shortly before they expire. It is also a gRPC
+// credentials.PerRPCCredentials adding the token to every export.
+type TokenSource struct {
+	auth     OpsRampConfig
+	insecure bool
+	// client is not instrumented, tracing the token requests of the
+	// exporters would feed them spans of their own.
+	client *http.Client
+
+	mu    sync.Mutex
+	token string
+	// refresh is when token is replaced, tokenRefreshMargin before its
+	// expiry at the latest.
+	refresh time.Time
+}
+
+// Token returns a valid access token, fetching a new one when needed.
+func (ts *TokenSource) Token(ctx context.Context) (string, error) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	if ts.token != "" && time.Now().Before(ts.refresh) {
+		return ts.token, nil
+	}
+
+	form := url.Values{
+		"grant_type":    {"client_credentials"},
+		"client_id":     {ts.auth.ClientID},
+		"client_secret": {ts.auth.ClientSecret},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, ts.auth.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("opsramp token: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	resp, err := ts.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("opsramp token: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("opsramp token: %s from %s", resp.Status, ts.auth.TokenURL)
+	}
+
+	var body struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("opsramp token: decode response: %w", err)
+	}
+	if body.AccessToken == "" {
+		return "", errors.New("opsramp token: response has no access_token")
+	}
+	lifetime := time.Duration(body.ExpiresIn) * time.Second
+	if lifetime <= 0 {
+		lifetime = defaultTokenLifetime
+	}
+	ts.token = body.AccessToken
+	ts.refresh = time.Now().Add(lifetime - min(tokenRefreshMargin, lifetime/2))
+	return ts.token, nil
+}
+
+// GetRequestMetadata sets the Authorization header of a gRPC export.
+func (ts *TokenSource) GetRequestMetadata(ctx context.Context, _ ...string) (map[string]string, error) {
+	token, err := ts.Token(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]string{"authorization": "Bearer " + token}, nil
+}
+
+// RequireTransportSecurity keeps the token off plain connections, unless the
+// endpoint is explicitly an http:// one, e.g. a local collector.
+func (ts *TokenSource) RequireTransportSecurity() bool { return !ts.insecure }
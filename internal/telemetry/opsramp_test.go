@@ -0,0 +1,42 @@
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTokenSourceCachesToken(t *testing.T) {
+	tests := []struct {
+		name string
+		// body is the token response, its expires_in is the point
+		body string
+	}{
+		{"long lived", `{"access_token":"t","expires_in":3600}`},
+		{"short lived", `{"access_token":"t","expires_in":30}`},
+		{"zero expires_in", `{"access_token":"t","expires_in":0}`},
+		{"no expires_in", `{"access_token":"t"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var exchanges int
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				exchanges++
+				fmt.Fprint(w, tt.body)
+			}))
+			defer server.Close()
+
+			ts := &TokenSource{auth: OpsRampConfig{TokenURL: server.URL}, client: server.Client()}
+			for range 3 {
+				if _, err := ts.Token(context.Background()); err != nil {
+					t.Fatalf("Token: %v", err)
+				}
+			}
+			if exchanges != 1 {
+				t.Errorf("%d token exchanges, want 1", exchanges)
+			}
+		})
+	}
+}
@@ -0,0 +1,168 @@
+//go:build !notelemetry && !wasm
+
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// newFileExporter writes the spans as OTLP JSON, the format of the OTLP/HTTP
+// JSON encoding and of the collector file exporter, so the files can be sent
+// to a collector later, see cmd/otlp-replay.
+func newFileExporter(ctx context.Context, cfg FileConfig) (*otlptrace.Exporter, error) {
+	return otlptrace.New(ctx, &fileClient{cfg: cfg})
+}
+
+// fileClient is an otlptrace.Client appending to a rotating file.
+type fileClient struct {
+	cfg FileConfig
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+}
+
+func (c *fileClient) Start(context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.open()
+}
+
+func (c *fileClient) Stop(context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.file == nil {
+		return nil
+	}
+	err := c.file.Close()
+	c.file = nil
+	return err
+}
+
+func (c *fileClient) UploadTraces(_ context.Context, spans []*tracepb.ResourceSpans) error {
+	line, err := MarshalOTLPJSON(&coltracepb.ExportTraceServiceRequest{ResourceSpans: spans})
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.file == nil {
+		return fmt.Errorf("file exporter %s is stopped", c.cfg.path())
+	}
+	if c.size > 0 && c.size+int64(len(line)) > c.cfg.maxSize() {
+		if err := c.rotate(); err != nil {
+			return err
+		}
+	}
+	n, err := c.file.Write(line)
+	c.size += int64(n)
+	return err
+}
+
+func (c *fileClient) open() error {
+	f, err := os.OpenFile(c.cfg.path(), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	c.file, c.size = f, info.Size()
+	return nil
+}
+
+// rotate renames the full file after the current time and opens a new one.
+func (c *fileClient) rotate() error {
+	if err := c.file.Close(); err != nil {
+		return err
+	}
+	c.file = nil
+	path := c.cfg.path()
+	ext := filepath.Ext(path)
+	rotated := strings.TrimSuffix(path, ext) + "-" + time.Now().UTC().Format("20060102T150405.000") + ext
+	if err := os.Rename(path, rotated); err != nil {
+		return err
+	}
+	return c.open()
+}
+
+// otlpIDFields are the bytes fields OTLP JSON encodes as hex rather than the
+// base64 of the protobuf JSON mapping.
+var otlpIDFields = map[string]bool{"traceId": true, "spanId": true, "parentSpanId": true}
+
+// MarshalOTLPJSON encodes req as OTLP JSON on a single line: the protobuf
+// JSON mapping with enums as numbers and trace and span IDs in hex.
+func MarshalOTLPJSON(req *coltracepb.ExportTraceServiceRequest) ([]byte, error) {
+	data, err := protojson.MarshalOptions{UseEnumNumbers: true}.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	var v any
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	return json.Marshal(convertIDs(v, base64.StdEncoding.DecodeString, hex.EncodeToString))
+}
+
+// UnmarshalOTLPJSON decodes a line written by MarshalOTLPJSON, or any OTLP
+// JSON request.
+func UnmarshalOTLPJSON(data []byte) (*coltracepb.ExportTraceServiceRequest, error) {
+	var v any
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(convertIDs(v, hex.DecodeString, base64.StdEncoding.EncodeToString))
+	if err != nil {
+		return nil, err
+	}
+	req := &coltracepb.ExportTraceServiceRequest{}
+	if err := (protojson.UnmarshalOptions{DiscardUnknown: true}).Unmarshal(data, req); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+// convertIDs re-encodes the ID fields of v from one encoding to the other.
+// Values that do not decode are left as is.
+func convertIDs(v any, decode func(string) ([]byte, error), encode func([]byte) string) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, e := range v {
+			if s, ok := e.(string); ok && otlpIDFields[k] {
+				if b, err := decode(s); err == nil {
+					v[k] = encode(b)
+				}
+				continue
+			}
+			v[k] = convertIDs(e, decode, encode)
+		}
+	case []any:
+		for i, e := range v {
+			v[i] = convertIDs(e, decode, encode)
+		}
+	}
+	return v
+}
@@ -0,0 +1,167 @@
+package telemetry
+
+import (
+	"context"
+	"log"
+	"slices"
+	"sync"
+	"sync/atomic"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// pipelineMetrics instruments the export path of the spans, for alerting on
+// an unhealthy pipeline: telemetry.pipeline.spans.exported and
+// telemetry.pipeline.spans.failed count the spans of the successful and
+// failed exports, telemetry.pipeline.spans.dropped the spans dropped on a
+// full queue, and telemetry.pipeline.queue.depth is the number of spans
+// waiting for their export. Each is attributed to the exporter backend and
+// endpoint.
+type pipelineMetrics struct {
+	exported metric.Int64Counter
+	failed   metric.Int64Counter
+	dropped  metric.Int64Counter
+
+	mu     sync.Mutex
+	queues []*pipelineQueue
+}
+
+var (
+	pipeline     *pipelineMetrics
+	pipelineOnce sync.Once
+)
+
+// pipelineMetricsOf returns the instruments of the pipeline, shared by the
+// tracer providers of the process.
+func pipelineMetricsOf() *pipelineMetrics {
+	pipelineOnce.Do(func() {
+		meter := otel.Meter(instrumentationName)
+		p := &pipelineMetrics{}
+		var err error
+		if p.exported, err = meter.Int64Counter("telemetry.pipeline.spans.exported",
+			metric.WithDescription("Number of spans exported")); err != nil {
+			log.Printf("failed to create telemetry.pipeline.spans.exported counter: %v", err)
+		}
+		if p.failed, err = meter.Int64Counter("telemetry.pipeline.spans.failed",
+			metric.WithDescription("Number of spans whose export failed")); err != nil {
+			log.Printf("failed to create telemetry.pipeline.spans.failed counter: %v", err)
+		}
+		if p.dropped, err = meter.Int64Counter("telemetry.pipeline.spans.dropped",
+			metric.WithDescription("Number of spans dropped because the export queue was full")); err != nil {
+			log.Printf("failed to create telemetry.pipeline.spans.dropped counter: %v", err)
+		}
+		depth, err := meter.Int64ObservableGauge("telemetry.pipeline.queue.depth",
+			metric.WithDescription("Number of spans waiting for their export"))
+		if err != nil {
+			log.Printf("failed to create telemetry.pipeline.queue.depth gauge: %v", err)
+		} else if _, err = meter.RegisterCallback(p.observe(depth), depth); err != nil {
+			log.Printf("failed to register telemetry pipeline callback: %v", err)
+		}
+		pipeline = p
+	})
+	return pipeline
+}
+
+func (p *pipelineMetrics) observe(depth metric.Int64ObservableGauge) metric.Callback {
+	return func(_ context.Context, o metric.Observer) error {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		for _, q := range p.queues {
+			o.ObserveInt64(depth, q.depth.Load(), q.attrs)
+		}
+		return nil
+	}
+}
+
+// instrument counts the exports of exporter, the exporter of e. The spans
+// of a batching exporter are queued in the returned queue, see
+// pipelineQueue.batcher.
+func (p *pipelineMetrics) instrument(e Exporter, exporter sdktrace.SpanExporter) (sdktrace.SpanExporter, *pipelineQueue) {
+	q := &pipelineQueue{metrics: p, attrs: metric.WithAttributes(
+		attribute.String("exporter.backend", string(e.backend())),
+		attribute.String("exporter.endpoint", e.ExporterEndpoint()))}
+	return pipelineExporter{SpanExporter: exporter, queue: q}, q
+}
+
+// pipelineQueue tracks the spans ended but not yet exported by an exporter.
+// The batch span processor drops the spans past its queue without telling,
+// so the queue drops them first, at the same size: the processor never
+// gets more than it holds and every drop is counted.
+type pipelineQueue struct {
+	metrics *pipelineMetrics
+	attrs   metric.MeasurementOption
+	max     int64
+	depth   atomic.Int64
+}
+
+// batcher queues the spans of next, the batch span processor of the
+// exporter of q holding up to maxQueue spans, and reports the depth of q.
+func (q *pipelineQueue) batcher(next sdktrace.SpanProcessor, maxQueue int) sdktrace.SpanProcessor {
+	q.max = int64(maxQueue)
+	p := q.metrics
+	p.mu.Lock()
+	p.queues = append(p.queues, q)
+	p.mu.Unlock()
+	return pipelineBatcher{SpanProcessor: next, queue: q}
+}
+
+// remove stops reporting the depth of q.
+func (q *pipelineQueue) remove() {
+	p := q.metrics
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.queues = slices.DeleteFunc(p.queues, func(o *pipelineQueue) bool { return o == q })
+}
+
+// pipelineExporter counts the exported and failed spans.
+type pipelineExporter struct {
+	sdktrace.SpanExporter
+	queue *pipelineQueue
+}
+
+func (e pipelineExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	err := e.SpanExporter.ExportSpans(ctx, spans)
+	n := int64(len(spans))
+	if e.queue.max > 0 {
+		e.queue.depth.Add(-n)
+	}
+	m := e.queue.metrics
+	counter := m.exported
+	if err != nil {
+		counter = m.failed
+	}
+	if counter != nil {
+		counter.Add(context.Background(), n, e.queue.attrs)
+	}
+	return err
+}
+
+// pipelineBatcher queues the ended spans of a batch span processor in its
+// pipelineQueue.
+type pipelineBatcher struct {
+	sdktrace.SpanProcessor
+	queue *pipelineQueue
+}
+
+func (b pipelineBatcher) OnEnd(s sdktrace.ReadOnlySpan) {
+	if !s.SpanContext().IsSampled() {
+		return
+	}
+	q := b.queue
+	if q.depth.Add(1) > q.max {
+		q.depth.Add(-1)
+		if q.metrics.dropped != nil {
+			q.metrics.dropped.Add(context.Background(), 1, q.attrs)
+		}
+		return
+	}
+	b.SpanProcessor.OnEnd(s)
+}
+
+func (b pipelineBatcher) Shutdown(ctx context.Context) error {
+	b.queue.remove()
+	return b.SpanProcessor.Shutdown(ctx)
+}
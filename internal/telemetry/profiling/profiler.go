@@ -0,0 +1,33 @@
+//go:build !notelemetry && !wasm
+
+package profiling
+
+import (
+	"fmt"
+
+	otelpyroscope "github.com/grafana/otel-profiling-go"
+	"github.com/grafana/pyroscope-go"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// start pushes the CPU, allocation and heap profiles of the application
+// name to the server of cfg. Builds with the notelemetry tag and WASM builds
+// do not profile, see profiler_noop.go.
+func start(name string, tags map[string]string, cfg Config) (func() error, error) {
+	profiler, err := pyroscope.Start(pyroscope.Config{
+		ApplicationName: name,
+		Tags:            tags,
+		ServerAddress:   cfg.ServerAddress,
+		TenantID:        cfg.TenantID,
+		UploadRate:      cfg.UploadInterval,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("start profiler: %w", err)
+	}
+	return profiler.Stop, nil
+}
+
+// labelSpans wraps tp so its spans label the profile samples.
+func labelSpans(tp trace.TracerProvider) trace.TracerProvider {
+	return otelpyroscope.NewTracerProvider(tp)
+}
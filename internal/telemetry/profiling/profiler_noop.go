@@ -0,0 +1,13 @@
+//go:build notelemetry || wasm
+
+package profiling
+
+import "go.opentelemetry.io/otel/trace"
+
+// start leaves the profiler and its dependencies out of builds with the
+// notelemetry tag, and of WASM builds, which cannot take CPU profiles.
+func start(string, map[string]string, Config) (func() error, error) {
+	return nil, nil
+}
+
+func labelSpans(tp trace.TracerProvider) trace.TracerProvider { return tp }
@@ -0,0 +1,64 @@
+// Package profiling continuously profiles the demo services with Pyroscope
+// and ties the profiles to the traces: the samples taken while a span runs
+// are labeled with the ID and name of its local root span, which gets a
+// pyroscope.profile.id attribute, so a backend like Grafana links a slow
+// span to the CPU profile of its request. The samples of the child spans,
+// e.g. ServiceLayer, land in the profile of their root span.
+package profiling
+
+import (
+	"log"
+	"time"
+
+	"go.opentelemetry.io/otel"
+
+	"test-jaeger/internal/telemetry"
+)
+
+// Config locates the Pyroscope server the profiles are pushed to.
+type Config struct {
+	// ServerAddress is the URL of the Pyroscope server, e.g.
+	// http://localhost:4040. Profiling is off while it is empty.
+	ServerAddress string
+	// TenantID is the tenant of a multi-tenant server.
+	TenantID string
+	// UploadInterval is how often the profiles are pushed. Defaults to 15s.
+	UploadInterval time.Duration
+}
+
+// Profiler pushes the profiles of the service until Stop.
+type Profiler struct {
+	stop func() error
+}
+
+// Start profiles the service described by cfg, named after its service name
+// and tagged with its version, and wraps the global tracer provider so the
+// spans started from now on label the samples, see the package doc. It
+// returns a nil Profiler when pcfg has no server address, or in builds
+// without telemetry, see profiler_noop.go. Callers should defer Stop.
+func Start(cfg telemetry.Config, pcfg Config) (*Profiler, error) {
+	if pcfg.ServerAddress == "" {
+		return nil, nil
+	}
+	tags := map[string]string{}
+	if cfg.ServiceVersion != "" {
+		tags["service_version"] = cfg.ServiceVersion
+	}
+	stop, err := start(cfg.ServiceName, tags, pcfg)
+	if err != nil || stop == nil {
+		return nil, err
+	}
+	otel.SetTracerProvider(labelSpans(otel.GetTracerProvider()))
+	return &Profiler{stop: stop}, nil
+}
+
+// Stop pushes the last profiles and stops profiling. Failures are logged
+// rather than returned because it is meant to be deferred from main.
+func (p *Profiler) Stop() {
+	if p == nil {
+		return
+	}
+	if err := p.stop(); err != nil {
+		log.Printf("failed to stop profiler: %v", err)
+	}
+}
@@ -0,0 +1,30 @@
+package telemetry
+
+import (
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/contrib/propagators/aws/xray"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+
+	"test-jaeger/internal/telemetry/core"
+)
+
+// DefaultPropagators are used when Config.Propagators is empty.
+var DefaultPropagators = core.DefaultPropagators
+
+// newPropagator builds a composite from propagator names, see
+// core.NewPropagator, adding "xray" for the X-Amzn-Trace-Id header.
+func newPropagator(names []string) (propagation.TextMapPropagator, error) {
+	return core.NewPropagator(names, map[string]propagation.TextMapPropagator{"xray": xray.Propagator{}})
+}
+
+// ExtractContext is a Gin middleware that continues the caller's trace by
+// extracting its span context from the request headers with the global
+// propagator.
+func ExtractContext() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := otel.GetTextMapPropagator().Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
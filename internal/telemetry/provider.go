@@ -0,0 +1,427 @@
+// Package telemetry holds the OpenTelemetry setup shared by every demo
+// service, so backend specific behavior is maintained in one place.
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/contrib/propagators/aws/xray"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+
+	"test-jaeger/internal/masking"
+)
+
+// Backend selects where spans are exported to.
+type Backend string
+
+const (
+	Jaeger   Backend = "jaeger"
+	NewRelic Backend = "newrelic"
+	OpsRamp  Backend = "opsramp"
+	// Stdout pretty prints the spans to stdout instead of exporting them,
+	// for local development without a collector. Metrics and logs are not
+	// exported.
+	Stdout Backend = "stdout"
+	// File writes the spans to a rotating OTLP JSON file, see FileConfig,
+	// e.g. to capture traces in an air-gapped environment. Metrics and logs
+	// are not exported.
+	File Backend = "file"
+)
+
+// DefaultEndpoint is the OTLP gRPC endpoint of the local Jaeger collector.
+const DefaultEndpoint = "http://localhost:4317"
+
+// ShutdownTimeout bounds how long Shutdown waits for buffered spans to be exported.
+const ShutdownTimeout = 5 * time.Second
+
+// Config describes the tracer provider of a single service.
+type Config struct {
+	// ServiceName is recorded as the service.name resource attribute.
+	ServiceName string
+	// ServiceVersion is recorded as service.version when set.
+	ServiceVersion string
+	// Exporter is the backend of the spans, metrics and logs.
+	Exporter
+	// Exporters are more backends the spans are exported to, e.g. to
+	// compare them side by side. The OTEL_* variables only apply to
+	// Exporter.
+	Exporters []Exporter
+	// Sampler names the sampling strategy: "always_on", "always_off",
+	// "traceidratio" or "ratelimiting", or one of them prefixed with
+	// "parentbased_" to follow the parent and only sample the root spans with
+	// it. Defaults to "parentbased_always_on".
+	Sampler string
+	// SamplerArg is the sampling ratio used by the "traceidratio" samplers,
+	// and the spans per second of the "ratelimiting" ones. Nil defaults to
+	// DefaultSamplerArg.
+	SamplerArg *float64
+	// SamplerSwitch, when set, lets the sampler be swapped while the
+	// service runs, starting with Sampler.
+	SamplerSwitch *SamplerSwitch
+	// SamplingReportInterval enables a periodic report of the sampling
+	// decisions per route when non-zero.
+	SamplingReportInterval time.Duration
+	// Propagators names the context propagation formats, see newPropagator.
+	// Defaults to DefaultPropagators.
+	Propagators []string
+	// ExemplarFilter selects the measurements that keep the trace and span
+	// IDs of their context as an exemplar: "trace_based" (the default, only
+	// inside sampled spans), "always_on" or "always_off". Used by the meter
+	// provider, see package metrics.
+	ExemplarFilter string
+	// ResourceAttributes are added to the resource of the service.
+	ResourceAttributes []attribute.KeyValue
+	// XRay makes the trace IDs compatible with AWS X-Ray, their first 4
+	// bytes being the start time in seconds, and adds the "xray" propagator
+	// (X-Amzn-Trace-Id), so the spans can be fed to X-Ray through an ADOT
+	// collector.
+	XRay bool
+	// Masker masks the string attributes of the exported spans. Defaults to
+	// masking.Default().
+	Masker *masking.Masker
+	// DropSpanTargets are the paths and gRPC methods, e.g. "/healthz" or
+	// "/grpc.health.v1.Health/Check", whose spans are not exported, see
+	// spanFilter.
+	DropSpanTargets []string
+	// SanitizeURLs strips the query strings and path IDs from the URL
+	// attributes of the exported spans, see urlSanitizer.
+	SanitizeURLs bool
+	// TraceURL is the template of the links to the traces in the backend UI,
+	// see TraceLink.
+	TraceURL string
+	// SpanCapture, when set, keeps the last spans as exported.
+	SpanCapture *SpanCapture
+	// CostEstimator, when set, estimates the volume of the spans, metrics
+	// and logs as exported.
+	CostEstimator *CostEstimator
+	// Batch tunes the batching of the spans of every exporter.
+	Batch BatchConfig
+	// SyncExport exports each span as it ends, blocking the code ending it,
+	// so the spans show in the backend right away while debugging locally.
+	// Batch is ignored. Not for production: every span costs an export.
+	SyncExport bool
+}
+
+// BatchConfig tunes the batch span processors, trading export latency for
+// throughput. Zero fields keep the SDK defaults, themselves overridden by the
+// OTEL_BSP_* environment variables.
+type BatchConfig struct {
+	// MaxExportBatchSize is the most spans sent in one export. SDK default
+	// 512.
+	MaxExportBatchSize int
+	// MaxQueueSize is the most spans waiting for export, the spans ending
+	// while it is full are dropped. SDK default 2048.
+	MaxQueueSize int
+	// ScheduleDelay is the longest a span waits before a batch is exported.
+	// SDK default 5s.
+	ScheduleDelay time.Duration
+	// ExportTimeout bounds an export. SDK default 30s.
+	ExportTimeout time.Duration
+}
+
+// options returns the batch span processor options of the set fields.
+func (b BatchConfig) options() []sdktrace.BatchSpanProcessorOption {
+	var opts []sdktrace.BatchSpanProcessorOption
+	if b.MaxExportBatchSize > 0 {
+		opts = append(opts, sdktrace.WithMaxExportBatchSize(b.MaxExportBatchSize))
+	}
+	if b.MaxQueueSize > 0 {
+		opts = append(opts, sdktrace.WithMaxQueueSize(b.MaxQueueSize))
+	}
+	if b.ScheduleDelay > 0 {
+		opts = append(opts, sdktrace.WithBatchTimeout(b.ScheduleDelay))
+	}
+	if b.ExportTimeout > 0 {
+		opts = append(opts, sdktrace.WithExportTimeout(b.ExportTimeout))
+	}
+	return opts
+}
+
+func (b BatchConfig) maxQueueSize() int {
+	if b.MaxQueueSize > 0 {
+		return b.MaxQueueSize
+	}
+	return sdktrace.DefaultMaxQueueSize
+}
+
+// Exporter describes a backend telemetry is exported to.
+type Exporter struct {
+	// Backend defaults to Jaeger.
+	Backend Backend
+	// Endpoint is the OTLP endpoint URL. Defaults to DefaultEndpoint for
+	// Jaeger and OpsRamp and to NewRelicEndpoint, or NewRelicEUEndpoint for
+	// an EU license key, for New Relic.
+	Endpoint string
+	// LicenseKey is the New Relic license key, sent in the api-key header.
+	// Defaults to NEW_RELIC_LICENSE_KEY.
+	LicenseKey string
+	// OpsRamp authorizes the OpsRamp exports and identifies the service in
+	// OpsRamp, see OpsRampConfig.
+	OpsRamp OpsRampConfig
+	// File is where the File backend writes.
+	File FileConfig
+	// Keepalive keeps the connections to the endpoint alive.
+	Keepalive KeepaliveConfig
+	// TLS adds a client certificate and a CA bundle to the connections to an
+	// https endpoint.
+	TLS TLSConfig
+	// Retry is how failed exports are retried.
+	Retry RetryPolicy
+	// FailoverEndpoints are OTLP endpoints the traces are exported to, in
+	// turn, while the endpoint keeps failing, see failoverClient. They share
+	// its settings.
+	FailoverEndpoints []string
+	// Spool keeps the spans of the exports failing after their retries on
+	// disk until the endpoint recovers.
+	Spool SpoolConfig
+	// Compression is "gzip" to compress the exports, trading CPU for egress
+	// bandwidth, or empty or "none" to send them as is.
+	Compression string
+}
+
+// Compressions lists the values of Exporter.Compression.
+var Compressions = []string{"", "none", "gzip"}
+
+// Gzip reports whether e compresses its exports.
+func (e Exporter) Gzip() bool { return e.Compression == "gzip" }
+
+// KeepaliveConfig keeps the exporter connections alive, e.g. behind a load
+// balancer dropping idle connections, see keepalive.ClientParameters.
+type KeepaliveConfig struct {
+	// Time is how long a connection stays idle before it is pinged. Zero
+	// disables the keepalive pings.
+	Time time.Duration
+	// Timeout is how long a ping waits for its answer before the connection
+	// is closed. Defaults to 20s.
+	Timeout time.Duration
+	// PermitWithoutStream also pings connections without an export in
+	// flight, which is most of the time for a batching exporter.
+	PermitWithoutStream bool
+}
+
+// NewTracerProvider creates the exporters for cfg.Exporter and
+// cfg.Exporters, builds a tracer provider batching the spans to each of them
+// and installs both the provider and the configured propagators globally.
+// The standard OTEL_* environment variables take precedence over cfg, see
+// withEnv. Callers should defer Shutdown on the returned provider.
+//
+// Only invalid configuration is returned as an error. Exporter failures, at
+// startup or later, are logged by the OpenTelemetry error handler and the
+// service keeps running without exporting spans.
+func NewTracerProvider(ctx context.Context, cfg Config) (*sdktrace.TracerProvider, error) {
+	setErrorHandler()
+
+	cfg, err := cfg.Resolved()
+	if err != nil {
+		return nil, err
+	}
+	exporters := append([]Exporter{cfg.Exporter}, cfg.Exporters...)
+	for _, e := range exporters {
+		if err := e.check(); err != nil {
+			return nil, err
+		}
+	}
+	sampler, err := newSampler(cfg.Sampler, *cfg.SamplerArg)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.SamplerSwitch != nil {
+		cfg.SamplerSwitch.install(cfg.Sampler, *cfg.SamplerArg, sampler)
+		sampler = cfg.SamplerSwitch
+	}
+	sampler = alwaysSampleRule{sampler: sampler}
+	propagator, err := newPropagator(cfg.Propagators)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := NewResource(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := []sdktrace.TracerProviderOption{
+		sdktrace.WithSpanProcessor(shadowProcessor{}),
+		sdktrace.WithResource(res),
+	}
+
+	// A broken exporter must not take the service down: without it spans are
+	// still created and propagated, they are just not exported. Each
+	// exporter has its own batcher, so a slow backend does not hold back the
+	// others.
+	var batchers []sdktrace.SpanProcessor
+	for _, e := range exporters {
+		exporter, err := newExporter(ctx, e)
+		if err != nil {
+			otel.Handle(fmt.Errorf("create %s exporter, spans will not be exported to it: %w", e.backend(), err))
+			continue
+		}
+		exporter, queue := pipelineMetricsOf().instrument(e, exporter)
+		if cfg.SyncExport {
+			batchers = append(batchers, sdktrace.NewSimpleSpanProcessor(exporter))
+		} else {
+			batchers = append(batchers, queue.batcher(
+				sdktrace.NewBatchSpanProcessor(exporter, cfg.Batch.options()...), cfg.Batch.maxQueueSize()))
+		}
+		probeCollector(e.ExporterEndpoint())
+	}
+	masker := cfg.Masker
+	if masker == nil {
+		masker = masking.Default()
+	}
+	if cfg.CostEstimator != nil && len(batchers) > 0 {
+		batchers = append(batchers, cfg.CostEstimator)
+	}
+	if cfg.SpanCapture != nil {
+		batchers = append(batchers, cfg.SpanCapture)
+	}
+	if len(cfg.DropSpanTargets) > 0 && len(batchers) > 0 {
+		batchers = []sdktrace.SpanProcessor{newSpanFilter(cfg.DropSpanTargets, batchers...)}
+	}
+	if cfg.SanitizeURLs && len(batchers) > 0 {
+		batchers = []sdktrace.SpanProcessor{newURLSanitizer(batchers...)}
+	}
+	// Installed even without batchers, the counters of Counter.Add are
+	// released when their spans end.
+	opts = append(opts, sdktrace.WithSpanProcessor(newGCPauseTagger(newDeferredAttributes(newRedactor(masker, batchers...)))))
+
+	if cfg.XRay {
+		opts = append(opts, sdktrace.WithIDGenerator(xray.NewIDGenerator()))
+	}
+
+	if cfg.SamplingReportInterval > 0 {
+		audit := newSamplingAudit(sampler, cfg.SamplingReportInterval)
+		opts = append(opts, sdktrace.WithSampler(audit), sdktrace.WithSpanProcessor(audit))
+	} else {
+		opts = append(opts, sdktrace.WithSampler(sampler))
+	}
+
+	provider := sdktrace.NewTracerProvider(opts...)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagator)
+
+	return provider, nil
+}
+
+// Resolved returns c as NewTracerProvider applies it: overridden by the
+// environment, see withEnv, with the backends, endpoints, sampler and
+// propagators defaulted, and the "xray" propagator added for XRay.
+func (c Config) Resolved() (Config, error) {
+	c, err := c.withEnv()
+	if err != nil {
+		return c, err
+	}
+	c.Exporter = c.Exporter.resolved()
+	c.Exporters = slices.Clone(c.Exporters)
+	for i, e := range c.Exporters {
+		c.Exporters[i] = e.resolved()
+	}
+	if c.Sampler == "" {
+		c.Sampler = "parentbased_always_on"
+	}
+	if c.SamplerArg == nil {
+		arg := DefaultSamplerArg(c.Sampler)
+		c.SamplerArg = &arg
+	}
+	if len(c.Propagators) == 0 {
+		c.Propagators = DefaultPropagators
+	}
+	if c.XRay && !slices.Contains(c.Propagators, "xray") {
+		c.Propagators = append(slices.Clip(c.Propagators), "xray")
+	}
+	return c, nil
+}
+
+// NewResource describes the service: service.name, service.version when set,
+// the OpsRamp tenant and resource for OpsRamp, cfg.ResourceAttributes and
+// OTEL_RESOURCE_ATTRIBUTES.
+func NewResource(ctx context.Context, cfg Config) (*resource.Resource, error) {
+	attrs := []attribute.KeyValue{semconv.ServiceNameKey.String(cfg.ServiceName)}
+	if cfg.ServiceVersion != "" {
+		attrs = append(attrs, semconv.ServiceVersionKey.String(cfg.ServiceVersion))
+	}
+	for _, e := range append([]Exporter{cfg.Exporter}, cfg.Exporters...) {
+		if e.backend() == OpsRamp {
+			attrs = append(attrs, e.opsRampAttributes()...)
+			break
+		}
+	}
+	attrs = append(attrs, cfg.ResourceAttributes...)
+	res, err := resource.New(ctx, resource.WithSchemaURL(SchemaURL), resource.WithAttributes(attrs...), resource.WithFromEnv())
+	if err != nil {
+		return nil, fmt.Errorf("build resource: %w", err)
+	}
+	return res, nil
+}
+
+// Shutdown flushes any buffered spans and stops the provider. Failures are
+// logged rather than returned because it is meant to be deferred from main.
+func Shutdown(provider *sdktrace.TracerProvider) {
+	ctx, cancel := context.WithTimeout(context.Background(), ShutdownTimeout)
+	defer cancel()
+	if err := provider.Shutdown(ctx); err != nil {
+		log.Printf("failed to shutdown tracer provider: %v", err)
+	}
+}
+
+func (e Exporter) backend() Backend {
+	if e.Backend == "" {
+		return Jaeger
+	}
+	return e.Backend
+}
+
+func (e Exporter) resolved() Exporter {
+	e.Endpoint = e.ExporterEndpoint()
+	e.Backend = e.backend()
+	return e
+}
+
+// ExportsToCollector reports whether e sends telemetry to an OTLP endpoint,
+// as opposed to writing it locally like Stdout and File. Metrics and logs
+// are only exported to a collector.
+func (e Exporter) ExportsToCollector() bool {
+	return e.backend() != Stdout && e.backend() != File
+}
+
+// ExporterEndpoint is the OTLP endpoint URL of c, defaulted per backend.
+// Empty for Stdout and File.
+func (e Exporter) ExporterEndpoint() string {
+	switch {
+	case !e.ExportsToCollector():
+		return ""
+	case e.Endpoint != "":
+		return e.Endpoint
+	case e.backend() != NewRelic:
+		return DefaultEndpoint
+	case strings.HasPrefix(e.licenseKey(), "eu"):
+		return NewRelicEUEndpoint
+	default:
+		return NewRelicEndpoint
+	}
+}
+
+// check reports the invalid or missing settings of the backend.
+func (e Exporter) check() error {
+	switch e.backend() {
+	case NewRelic:
+		return errors.Join(e.checkNewRelic(), e.checkTLS())
+	case OpsRamp:
+		return errors.Join(e.checkOpsRamp(), e.checkTLS())
+	case File:
+		return e.checkFile()
+	case Jaeger:
+		return e.checkTLS()
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package telemetry
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// rateLimitingSampler samples at most perSecond spans a second, e.g. to keep
+// a load test within the ingest quota of the backend, and drops the rest. The
+// budget refills continuously, with bursts of up to a second's worth. Wrapped
+// in ParentBased, as "parentbased_ratelimiting", it only limits the new traces
+// and keeps the decision of the callers, so every trace is complete.
+type rateLimitingSampler struct {
+	perSecond float64
+
+	mu      sync.Mutex
+	balance float64
+	last    time.Time
+}
+
+func newRateLimitingSampler(perSecond float64) *rateLimitingSampler {
+	return &rateLimitingSampler{perSecond: perSecond, balance: math.Max(perSecond, 1), last: time.Now()}
+}
+
+func (s *rateLimitingSampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	decision := sdktrace.Drop
+	if s.take() {
+		decision = sdktrace.RecordAndSample
+	}
+	return sdktrace.SamplingResult{
+		Decision:   decision,
+		Tracestate: trace.SpanContextFromContext(p.ParentContext).TraceState(),
+	}
+}
+
+// take spends one span of the budget, if there is one left.
+func (s *rateLimitingSampler) take() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	s.balance = math.Min(s.balance+now.Sub(s.last).Seconds()*s.perSecond, math.Max(s.perSecond, 1))
+	s.last = now
+	if s.balance < 1 {
+		return false
+	}
+	s.balance--
+	return true
+}
+
+func (s *rateLimitingSampler) Description() string {
+	return fmt.Sprintf("RateLimitingSampler{%g}", s.perSecond)
+}
@@ -0,0 +1,66 @@
+package telemetry
+
+import (
+	"fmt"
+	"log"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Recover is a Gin middleware answering 500 to a request whose handler
+// panicked, in place of gin.Recovery. The panic is recorded with its stack
+// trace as an exception on the span of the request, which is marked as an
+// Error, logged, and counted by http.server.panics. Spans ended by
+// FinishSpan on the way up record it as well.
+func Recover() gin.HandlerFunc {
+	panics, err := otel.Meter(instrumentationName).Int64Counter("http.server.panics",
+		metric.WithDescription("Number of requests whose handler panicked"))
+	if err != nil {
+		log.Printf("failed to create http.server.panics counter: %v", err)
+	}
+
+	return func(c *gin.Context) {
+		defer func() {
+			r := recover()
+			if r == nil {
+				return
+			}
+			// net/http aborts the response silently on ErrAbortHandler
+			if r == http.ErrAbortHandler {
+				panic(r)
+			}
+			ctx := c.Request.Context()
+			err := RecordPanic(trace.SpanFromContext(ctx), r)
+			if panics != nil {
+				panics.Add(ctx, 1, metric.WithAttributes(
+					semconv.HTTPRouteKey.String(c.FullPath()), semconv.HTTPRequestMethodKey.String(c.Request.Method)))
+			}
+			slog.ErrorContext(ctx, "recovered from a panic", "error", err, "stack", string(debug.Stack()))
+			c.AbortWithStatus(http.StatusInternalServerError)
+		}()
+		c.Next()
+	}
+}
+
+// RecordPanic records the value r recovered from a panic as an exception on
+// span, with the stack trace of the panic when called from the deferred
+// function that recovered it, marks the span as an Error and returns r as an
+// error.
+func RecordPanic(span trace.Span, r any) error {
+	err, ok := r.(error)
+	if !ok {
+		err = fmt.Errorf("%v", r)
+	}
+	span.RecordError(err, trace.WithStackTrace(true), trace.WithAttributes(semconv.ExceptionEscaped(true)))
+	err = fmt.Errorf("panic: %w", err)
+	span.SetStatus(codes.Error, err.Error())
+	return err
+}
@@ -0,0 +1,89 @@
+package telemetry
+
+import (
+	"context"
+	"errors"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+
+	"test-jaeger/internal/masking"
+)
+
+// redactor masks the string attributes of the spans and span events given
+// to the exporting processors, so emails or tokens caught in an attribute,
+// e.g. a URL or an error message, do not leave the service. Like
+// gcPauseTagger, it hands a masked view of the ended span to next.
+type redactor struct {
+	masker *masking.Masker
+	next   []sdktrace.SpanProcessor
+}
+
+func newRedactor(masker *masking.Masker, next ...sdktrace.SpanProcessor) *redactor {
+	return &redactor{masker: masker, next: next}
+}
+
+func (r *redactor) OnStart(parent context.Context, s sdktrace.ReadWriteSpan) {
+	for _, p := range r.next {
+		p.OnStart(parent, s)
+	}
+}
+
+func (r *redactor) OnEnd(s sdktrace.ReadOnlySpan) {
+	if s.SpanContext().IsSampled() {
+		s = r.mask(s)
+	}
+	for _, p := range r.next {
+		p.OnEnd(s)
+	}
+}
+
+func (r *redactor) Shutdown(ctx context.Context) error {
+	var errs []error
+	for _, p := range r.next {
+		errs = append(errs, p.Shutdown(ctx))
+	}
+	return errors.Join(errs...)
+}
+
+func (r *redactor) ForceFlush(ctx context.Context) error {
+	var errs []error
+	for _, p := range r.next {
+		errs = append(errs, p.ForceFlush(ctx))
+	}
+	return errors.Join(errs...)
+}
+
+// mask returns s, or a masked view of it when a value had to be masked.
+func (r *redactor) mask(s sdktrace.ReadOnlySpan) sdktrace.ReadOnlySpan {
+	attrs, changed := r.masker.Attributes(s.Attributes())
+	events := s.Events()
+	var maskedEvents []sdktrace.Event
+	for i, e := range events {
+		eventAttrs, ok := r.masker.Attributes(e.Attributes)
+		if !ok {
+			continue
+		}
+		if maskedEvents == nil {
+			maskedEvents = append([]sdktrace.Event(nil), events...)
+		}
+		maskedEvents[i].Attributes = eventAttrs
+	}
+	if !changed && maskedEvents == nil {
+		return s
+	}
+	if maskedEvents == nil {
+		maskedEvents = events
+	}
+	return maskedSpan{ReadOnlySpan: s, attrs: attrs, events: maskedEvents}
+}
+
+// maskedSpan is an ended span with masked attributes and events.
+type maskedSpan struct {
+	sdktrace.ReadOnlySpan
+	attrs  []attribute.KeyValue
+	events []sdktrace.Event
+}
+
+func (s maskedSpan) Attributes() []attribute.KeyValue { return s.attrs }
+func (s maskedSpan) Events() []sdktrace.Event         { return s.events }
@@ -0,0 +1,53 @@
+package telemetry
+
+import "time"
+
+// Default retry policy of the OTLP exporters.
+const (
+	DefaultRetryInitialInterval = 5 * time.Second
+	DefaultRetryMaxInterval     = 30 * time.Second
+	DefaultRetryMaxElapsedTime  = time.Minute
+)
+
+// RetryPolicy is how the OTLP exporters retry a failed export, backing off
+// exponentially from InitialInterval up to MaxInterval between attempts, and
+// dropping the batch once MaxElapsedTime has passed. Zero durations keep the
+// defaults. A collector down for longer than MaxElapsedTime loses the spans
+// of the meantime: raise it to ride out longer outages, at the cost of a
+// fuller queue, see BatchConfig.MaxQueueSize.
+type RetryPolicy struct {
+	// Disabled drops a batch at its first failure.
+	Disabled        bool
+	InitialInterval time.Duration
+	MaxInterval     time.Duration
+	MaxElapsedTime  time.Duration
+}
+
+// RetryConfig is the retry configuration of the OTLP exporters, convertible
+// to their RetryConfig types, e.g. otlptracegrpc.RetryConfig(c).
+type RetryConfig struct {
+	Enabled         bool
+	InitialInterval time.Duration
+	MaxInterval     time.Duration
+	MaxElapsedTime  time.Duration
+}
+
+// Config returns the exporter configuration of p, with the defaults applied.
+func (p RetryPolicy) Config() RetryConfig {
+	c := RetryConfig{
+		Enabled:         !p.Disabled,
+		InitialInterval: p.InitialInterval,
+		MaxInterval:     p.MaxInterval,
+		MaxElapsedTime:  p.MaxElapsedTime,
+	}
+	if c.InitialInterval <= 0 {
+		c.InitialInterval = DefaultRetryInitialInterval
+	}
+	if c.MaxInterval <= 0 {
+		c.MaxInterval = DefaultRetryMaxInterval
+	}
+	if c.MaxElapsedTime <= 0 {
+		c.MaxElapsedTime = DefaultRetryMaxElapsedTime
+	}
+	return c
+}
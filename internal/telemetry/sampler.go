@@ -0,0 +1,159 @@
+package telemetry
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// newSampler returns the sampler named like OTEL_TRACES_SAMPLER. An empty name
+// keeps the SDK default (parent based, always on). The parentbased_ variants
+// follow the sampling decision of the caller and only apply the named sampler
+// to the root spans, so a trace is never cut in the middle.
+func newSampler(name string, arg float64) (sdktrace.Sampler, error) {
+	if root, ok := strings.CutPrefix(name, "parentbased_"); ok {
+		if root == "" || strings.HasPrefix(root, "parentbased_") {
+			return nil, fmt.Errorf("unknown sampler %q", name)
+		}
+		sampler, err := newSampler(root, arg)
+		if err != nil {
+			return nil, err
+		}
+		return sdktrace.ParentBased(sampler), nil
+	}
+	switch name {
+	case "":
+		return sdktrace.ParentBased(sdktrace.AlwaysSample()), nil
+	case "always_on":
+		return sdktrace.AlwaysSample(), nil
+	case "always_off":
+		return sdktrace.NeverSample(), nil
+	case "traceidratio":
+		if arg < 0 || arg > 1 {
+			return nil, fmt.Errorf("traceidratio needs a ratio between 0 and 1, got %v", arg)
+		}
+		return sdktrace.TraceIDRatioBased(arg), nil
+	case "ratelimiting":
+		if arg <= 0 {
+			return nil, fmt.Errorf("ratelimiting needs a number of spans per second above 0, got %v", arg)
+		}
+		return newRateLimitingSampler(arg), nil
+	default:
+		return nil, fmt.Errorf("unknown sampler %q", name)
+	}
+}
+
+// DefaultSamplerArg is the arg of the sampler named name when none is set: a
+// ratio of 1 for the traceidratio samplers, as the specification defaults
+// OTEL_TRACES_SAMPLER_ARG to, so that only an explicit 0 drops every trace.
+func DefaultSamplerArg(name string) float64 {
+	if strings.TrimPrefix(name, "parentbased_") == "traceidratio" {
+		return 1
+	}
+	return 0
+}
+
+// ValidateSampler reports whether name and arg describe a supported sampler.
+func ValidateSampler(name string, arg float64) error {
+	_, err := newSampler(name, arg)
+	return err
+}
+
+// SamplerSwitch is a sampler delegating to one that can be swapped while the
+// service runs, e.g. to sample more of a misbehaving service for a while.
+// Set it as Config.SamplerSwitch: the tracer provider then starts with the
+// configured sampler in it and samples through it.
+type SamplerSwitch struct {
+	active  atomic.Pointer[switchedSampler]
+	initial atomic.Pointer[switchedSampler]
+}
+
+type switchedSampler struct {
+	name    string
+	arg     float64
+	sampler sdktrace.Sampler
+}
+
+// NewSamplerSwitch returns a switch holding the SDK default sampler until
+// the tracer provider installs the configured one.
+func NewSamplerSwitch() *SamplerSwitch {
+	s := &SamplerSwitch{}
+	s.install("parentbased_always_on", 0, sdktrace.ParentBased(sdktrace.AlwaysSample()))
+	return s
+}
+
+// install makes sampler, named name with arg, both the active and the
+// configured sampler.
+func (s *SamplerSwitch) install(name string, arg float64, sampler sdktrace.Sampler) {
+	sw := &switchedSampler{name: name, arg: arg, sampler: sampler}
+	s.initial.Store(sw)
+	s.active.Store(sw)
+}
+
+// Set swaps the active sampler for the one named name with arg, see
+// Config.Sampler.
+func (s *SamplerSwitch) Set(name string, arg float64) error {
+	sampler, err := newSampler(name, arg)
+	if err != nil {
+		return err
+	}
+	s.active.Store(&switchedSampler{name: name, arg: arg, sampler: sampler})
+	return nil
+}
+
+// Reset goes back to the configured sampler.
+func (s *SamplerSwitch) Reset() { s.active.Store(s.initial.Load()) }
+
+// Active returns the name and argument of the active sampler, and whether
+// it is the configured one.
+func (s *SamplerSwitch) Active() (name string, arg float64, configured bool) {
+	sw := s.active.Load()
+	return sw.name, sw.arg, sw == s.initial.Load()
+}
+
+// ShouldSample delegates to the active sampler.
+func (s *SamplerSwitch) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	return s.active.Load().sampler.ShouldSample(p)
+}
+
+// Description describes the active sampler.
+func (s *SamplerSwitch) Description() string {
+	return "SamplerSwitch{" + s.active.Load().sampler.Description() + "}"
+}
+
+// AlwaysSampleKey marks the root spans sampled whatever the configured
+// sampler, see AlwaysSample.
+const AlwaysSampleKey = attribute.Key("sampling.always")
+
+// AlwaysSample is a span start option sampling a root span whatever the
+// configured sampler, for the spans whose absence means something, e.g. the
+// heartbeats: a ratio or a rate limit would drop most of them.
+func AlwaysSample() trace.SpanStartOption {
+	return trace.WithAttributes(AlwaysSampleKey.Bool(true))
+}
+
+// alwaysSampleRule samples the root spans started with AlwaysSample and
+// leaves the others to sampler.
+type alwaysSampleRule struct {
+	sampler sdktrace.Sampler
+}
+
+func (r alwaysSampleRule) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	parent := trace.SpanContextFromContext(p.ParentContext)
+	if !parent.IsValid() {
+		for _, kv := range p.Attributes {
+			if kv.Key == AlwaysSampleKey && kv.Value.AsBool() {
+				return sdktrace.SamplingResult{Decision: sdktrace.RecordAndSample, Tracestate: parent.TraceState()}
+			}
+		}
+	}
+	return r.sampler.ShouldSample(p)
+}
+
+func (r alwaysSampleRule) Description() string {
+	return "AlwaysSampleRule{" + r.sampler.Description() + "}"
+}
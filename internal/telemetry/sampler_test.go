@@ -0,0 +1,57 @@
+package telemetry
+
+import (
+	"context"
+	"testing"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestAlwaysSample(t *testing.T) {
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSampler(alwaysSampleRule{sampler: sdktrace.TraceIDRatioBased(0)}))
+	tracer := provider.Tracer("test")
+
+	ctx, forced := tracer.Start(context.Background(), "heartbeat", AlwaysSample())
+	if !forced.SpanContext().IsSampled() {
+		t.Error("root span started with AlwaysSample was dropped")
+	}
+	_, child := tracer.Start(ctx, "child", AlwaysSample())
+	if child.SpanContext().IsSampled() {
+		t.Error("AlwaysSample applied to a child span, want the sampler to decide")
+	}
+	_, other := tracer.Start(context.Background(), "request", trace.WithSpanKind(trace.SpanKindServer))
+	if other.SpanContext().IsSampled() {
+		t.Error("span without AlwaysSample was sampled by a ratio of 0")
+	}
+}
+
+func TestResolvedSamplerArg(t *testing.T) {
+	zero := 0.0
+	tests := []struct {
+		name    string
+		sampler string
+		arg     *float64
+		env     string
+		want    float64
+	}{
+		{"ratio unset", "traceidratio", nil, "", 1},
+		{"parent based ratio unset", "parentbased_traceidratio", nil, "", 1},
+		{"ratio set to 0", "traceidratio", &zero, "", 0},
+		{"ratio set to 0 by env", "parentbased_traceidratio", nil, "0", 0},
+		{"other sampler", "always_on", nil, "", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("OTEL_TRACES_SAMPLER", "")
+			t.Setenv("OTEL_TRACES_SAMPLER_ARG", tt.env)
+			cfg, err := Config{Sampler: tt.sampler, SamplerArg: tt.arg}.Resolved()
+			if err != nil {
+				t.Fatalf("Resolved: %v", err)
+			}
+			if *cfg.SamplerArg != tt.want {
+				t.Errorf("SamplerArg = %v, want %v", *cfg.SamplerArg, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,182 @@
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "test-jaeger/internal/telemetry"
+
+type auditKey struct {
+	route string
+	rule  string
+}
+
+type auditCounts struct {
+	started, sampled, dropped int64
+}
+
+// samplingAudit wraps the configured sampler and summarizes its decisions,
+// per route and per rule, as the sampling.decisions counter and as a log
+// report every interval. It is also registered as a span processor so that
+// shutting down the provider stops the report loop.
+type samplingAudit struct {
+	sampler  sdktrace.Sampler
+	counter  metric.Int64Counter
+	interval time.Duration
+
+	mu     sync.Mutex
+	counts map[auditKey]*auditCounts
+
+	stop chan struct{}
+	done chan struct{}
+	once sync.Once
+}
+
+func newSamplingAudit(sampler sdktrace.Sampler, interval time.Duration) *samplingAudit {
+	a := &samplingAudit{
+		sampler:  sampler,
+		interval: interval,
+		counts:   map[auditKey]*auditCounts{},
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	var err error
+	if a.counter, err = otel.Meter(instrumentationName).Int64Counter("sampling.decisions",
+		metric.WithDescription("Sampling decisions by route, rule and decision")); err != nil {
+		log.Printf("failed to create sampling.decisions counter: %v", err)
+	}
+	go a.run()
+	return a
+}
+
+// ShouldSample delegates to the wrapped sampler and records the decision.
+func (a *samplingAudit) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	result := a.sampler.ShouldSample(p)
+
+	route := p.Name
+	for _, kv := range p.Attributes {
+		if kv.Key == "http.route" {
+			route = kv.Value.AsString()
+		}
+	}
+	rule := samplingRule(trace.SpanContextFromContext(p.ParentContext))
+	sampled := result.Decision == sdktrace.RecordAndSample
+
+	a.mu.Lock()
+	c, ok := a.counts[auditKey{route, rule}]
+	if !ok {
+		c = &auditCounts{}
+		a.counts[auditKey{route, rule}] = c
+	}
+	c.started++
+	if sampled {
+		c.sampled++
+	} else {
+		c.dropped++
+	}
+	a.mu.Unlock()
+
+	if a.counter != nil {
+		decision := "drop"
+		if sampled {
+			decision = "sample"
+		}
+		a.counter.Add(p.ParentContext, 1, metric.WithAttributes(
+			attribute.String("route", route),
+			attribute.String("rule", rule),
+			attribute.String("decision", decision)))
+	}
+	return result
+}
+
+func (a *samplingAudit) Description() string { return a.sampler.Description() }
+
+// samplingRule names the branch of a parent based sampler that applies to a
+// span with the given parent.
+func samplingRule(parent trace.SpanContext) string {
+	switch {
+	case !parent.IsValid():
+		return "root"
+	case parent.IsRemote() && parent.IsSampled():
+		return "remote_parent_sampled"
+	case parent.IsRemote():
+		return "remote_parent_not_sampled"
+	case parent.IsSampled():
+		return "local_parent_sampled"
+	default:
+		return "local_parent_not_sampled"
+	}
+}
+
+func (a *samplingAudit) run() {
+	defer close(a.done)
+	ticker := time.NewTicker(a.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			a.report()
+		case <-a.stop:
+			a.report()
+			return
+		}
+	}
+}
+
+// report logs the decisions since the previous report and resets them.
+func (a *samplingAudit) report() {
+	a.mu.Lock()
+	counts := a.counts
+	a.counts = map[auditKey]*auditCounts{}
+	a.mu.Unlock()
+	if len(counts) == 0 {
+		return
+	}
+
+	keys := make([]auditKey, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].route != keys[j].route {
+			return keys[i].route < keys[j].route
+		}
+		return keys[i].rule < keys[j].rule
+	})
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "sampling report for the last %s (sampler %s):", a.interval, a.sampler.Description())
+	for _, k := range keys {
+		c := counts[k]
+		fmt.Fprintf(&b, "\n  route=%q rule=%q started=%d sampled=%d dropped=%d",
+			k.route, k.rule, c.started, c.sampled, c.dropped)
+	}
+	log.Print(b.String())
+}
+
+func (a *samplingAudit) OnStart(context.Context, sdktrace.ReadWriteSpan) {}
+func (a *samplingAudit) OnEnd(sdktrace.ReadOnlySpan)                     {}
+func (a *samplingAudit) ForceFlush(context.Context) error                { return nil }
+
+// Shutdown stops the report loop after logging a final report.
+func (a *samplingAudit) Shutdown(ctx context.Context) error {
+	a.once.Do(func() { close(a.stop) })
+	select {
+	case <-a.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
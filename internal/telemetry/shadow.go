@@ -0,0 +1,48 @@
+package telemetry
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/baggage"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// ShadowKey marks spans produced by mirrored (shadow) traffic. SLO queries
+// should filter on traffic.shadow != true.
+const ShadowKey = attribute.Key("traffic.shadow")
+
+// shadowBaggageKey carries the shadow marker across services.
+const shadowBaggageKey = "traffic.shadow"
+
+// WithShadow marks ctx, and every request propagated from it, as shadow traffic.
+func WithShadow(ctx context.Context) context.Context {
+	member, err := baggage.NewMember(shadowBaggageKey, "true")
+	if err != nil {
+		return ctx
+	}
+	bag, err := baggage.FromContext(ctx).SetMember(member)
+	if err != nil {
+		return ctx
+	}
+	return baggage.ContextWithBaggage(ctx, bag)
+}
+
+// IsShadow reports whether ctx belongs to shadow traffic. Metrics that feed
+// SLOs skip shadow requests.
+func IsShadow(ctx context.Context) bool {
+	return baggage.FromContext(ctx).Member(shadowBaggageKey).Value() == "true"
+}
+
+// shadowProcessor stamps ShadowKey on every span started in a shadow context.
+type shadowProcessor struct{}
+
+func (shadowProcessor) OnStart(ctx context.Context, s sdktrace.ReadWriteSpan) {
+	if IsShadow(ctx) {
+		s.SetAttributes(ShadowKey.Bool(true))
+	}
+}
+
+func (shadowProcessor) OnEnd(sdktrace.ReadOnlySpan)      {}
+func (shadowProcessor) Shutdown(context.Context) error   { return nil }
+func (shadowProcessor) ForceFlush(context.Context) error { return nil }
@@ -0,0 +1,108 @@
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// spanFilter keeps the spans of high frequency probes, e.g. /healthz or
+// /metrics, from the exporting processors, so they do not drown the traces
+// worth looking at. A span is dropped when its http.target or url.path,
+// without the query, or its gRPC method as "/<rpc.service>/<rpc.method>",
+// matches one of the targets: exactly, or by prefix for a target ending in
+// "*". The children of a dropped span are kept.
+type spanFilter struct {
+	exact    map[string]bool
+	prefixes []string
+	next     []sdktrace.SpanProcessor
+}
+
+func newSpanFilter(targets []string, next ...sdktrace.SpanProcessor) *spanFilter {
+	f := &spanFilter{exact: make(map[string]bool), next: next}
+	for _, t := range targets {
+		if prefix, ok := strings.CutSuffix(t, "*"); ok {
+			f.prefixes = append(f.prefixes, prefix)
+		} else {
+			f.exact[t] = true
+		}
+	}
+	return f
+}
+
+// ValidateSpanFilter reports the targets of a span filter that can never
+// match.
+func ValidateSpanFilter(targets []string) error {
+	var errs []error
+	for _, t := range targets {
+		if !strings.HasPrefix(t, "/") {
+			errs = append(errs, fmt.Errorf("target %q does not start with /", t))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (f *spanFilter) OnStart(parent context.Context, s sdktrace.ReadWriteSpan) {
+	for _, p := range f.next {
+		p.OnStart(parent, s)
+	}
+}
+
+func (f *spanFilter) OnEnd(s sdktrace.ReadOnlySpan) {
+	if f.drop(s.Attributes()) {
+		return
+	}
+	for _, p := range f.next {
+		p.OnEnd(s)
+	}
+}
+
+func (f *spanFilter) Shutdown(ctx context.Context) error {
+	var errs []error
+	for _, p := range f.next {
+		errs = append(errs, p.Shutdown(ctx))
+	}
+	return errors.Join(errs...)
+}
+
+func (f *spanFilter) ForceFlush(ctx context.Context) error {
+	var errs []error
+	for _, p := range f.next {
+		errs = append(errs, p.ForceFlush(ctx))
+	}
+	return errors.Join(errs...)
+}
+
+func (f *spanFilter) drop(attrs []attribute.KeyValue) bool {
+	var service, method string
+	for _, kv := range attrs {
+		switch kv.Key {
+		case "http.target", "url.path":
+			path, _, _ := strings.Cut(kv.Value.AsString(), "?")
+			if f.match(path) {
+				return true
+			}
+		case "rpc.service":
+			service = kv.Value.AsString()
+		case "rpc.method":
+			method = kv.Value.AsString()
+		}
+	}
+	return service != "" && f.match("/"+service+"/"+method)
+}
+
+func (f *spanFilter) match(target string) bool {
+	if f.exact[target] {
+		return true
+	}
+	for _, prefix := range f.prefixes {
+		if strings.HasPrefix(target, prefix) {
+			return true
+		}
+	}
+	return false
+}
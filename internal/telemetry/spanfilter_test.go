@@ -0,0 +1,47 @@
+package telemetry
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestSpanFilterDropsServerSpans(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	recorder := tracetest.NewSpanRecorder()
+	filter := newSpanFilter([]string{"/metrics", "/debug/*"}, recorder)
+	tracer := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(filter)).Tracer("test")
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		_, span := StartServerSpan(c, tracer)
+		defer FinishSpan(c, span)
+		c.Next()
+	})
+	for _, route := range []string{"/metrics", "/debug/traces", "/users/:id"} {
+		router.GET(route, func(c *gin.Context) { c.Status(http.StatusOK) })
+	}
+
+	tests := []struct {
+		target string
+		kept   bool
+	}{
+		{"/metrics", false},
+		{"/metrics?format=prometheus", false},
+		{"/debug/traces", false},
+		{"/users/42", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			before := len(recorder.Ended())
+			router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, tt.target, nil))
+			if kept := len(recorder.Ended()) > before; kept != tt.kept {
+				t.Errorf("span of %s kept = %v, want %v", tt.target, kept, tt.kept)
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package telemetry
+
+import (
+	"context"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ServerSpanName names a server span after the method and the route template
+// of the request, e.g. "GET /users/:id", as the HTTP semantic conventions do.
+// The raw path would make a name per user; without a route, e.g. on a 404,
+// the span is named after the method alone.
+func ServerSpanName(method, route string) string {
+	if route == "" {
+		return method
+	}
+	return method + " " + route
+}
+
+// ClientSpanName names a client span after the method and the peer it calls,
+// e.g. "GET localhost:5001".
+func ClientSpanName(method, peer string) string {
+	if peer == "" {
+		return method
+	}
+	return method + " " + peer
+}
+
+// StartServerSpan starts the top span of the request handled by c, a server
+// span named after its route with the url.path of the request, the span
+// filter of Config.DropSpanTargets matches on, and hands it to the rest of
+// the chain through c.Request. End it with FinishSpan, TraceRequests does
+// both for every request.
+func StartServerSpan(c *gin.Context, tracer trace.Tracer, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	opts = append([]trace.SpanStartOption{
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(semconv.URLPath(c.Request.URL.Path)),
+	}, opts...)
+	ctx, span := tracer.Start(c.Request.Context(), ServerSpanName(c.Request.Method, c.FullPath()), opts...)
+	c.Request = c.Request.WithContext(ctx)
+	return ctx, span
+}
+
+// TraceRequests is a Gin middleware tracing every request in a server span,
+// see StartServerSpan, described from the response the handlers actually
+// wrote once they are done, see FinishSpan. Handlers add to it through
+// trace.SpanFromContext(c.Request.Context()) and report failures with
+// c.Error. It goes after ExtractContext and CollectAttributes.
+func TraceRequests() gin.HandlerFunc {
+	tracer := otel.Tracer(instrumentationName)
+	return func(c *gin.Context) {
+		_, span := StartServerSpan(c, tracer)
+		defer FinishSpan(c, span)
+		c.Next()
+	}
+}
+
+// RecordRoute is a Gin middleware setting http.route on the top span of the
+// request to the template of its route, e.g. "/users/:id", so latencies
+// group by route rather than by URL. It goes after CollectAttributes, and
+// requests matching no route are left without one.
+func RecordRoute() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if route := c.FullPath(); route != "" {
+			AddAttributes(c.Request.Context(), string(semconv.HTTPRouteKey), route)
+		}
+		c.Next()
+	}
+}
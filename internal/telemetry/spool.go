@@ -0,0 +1,22 @@
+package telemetry
+
+// DefaultSpoolMaxSize is the size of the spool of an exporter by default.
+const DefaultSpoolMaxSize = 100 << 20
+
+// SpoolConfig keeps the spans an exporter fails to send on disk until its
+// endpoint recovers, see spoolClient.
+type SpoolConfig struct {
+	// Dir holds the spooled batches, one file each. Spooling is off while
+	// it is empty. Each exporter needs its own.
+	Dir string
+	// MaxSize is the size in bytes of the spooled batches past which the
+	// oldest are deleted. Defaults to DefaultSpoolMaxSize.
+	MaxSize int64
+}
+
+func (c SpoolConfig) maxSize() int64 {
+	if c.MaxSize <= 0 {
+		return DefaultSpoolMaxSize
+	}
+	return c.MaxSize
+}
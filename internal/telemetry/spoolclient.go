@@ -0,0 +1,204 @@
+//go:build !notelemetry && !wasm
+
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+)
+
+// spoolClient is an otlptrace.Client writing the batches its client fails
+// to upload, retries included, to files in a directory, and uploading them
+// again, oldest first, after the next upload that succeeds. A collector down
+// for a while thereby loses no spans, as long as the spool stays within its
+// size: past it, the oldest batches are deleted. The files are OTLP JSON,
+// see MarshalOTLPJSON, and the spool of a previous run is uploaded in the
+// background at start.
+type spoolClient struct {
+	next otlptrace.Client
+	cfg  SpoolConfig
+
+	mu      sync.Mutex
+	size    int64
+	seq     int
+	spooled bool // an outage is in progress
+}
+
+func newSpoolClient(next otlptrace.Client, cfg SpoolConfig) *spoolClient {
+	return &spoolClient{next: next, cfg: cfg}
+}
+
+func (c *spoolClient) Start(ctx context.Context) error {
+	if err := os.MkdirAll(c.cfg.Dir, 0o700); err != nil {
+		return fmt.Errorf("create spool directory: %w", err)
+	}
+	if err := c.next.Start(ctx); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	files, err := c.files()
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		c.size += f.size
+	}
+	if len(files) > 0 {
+		log.Printf("uploading %d batches spooled to %s by a previous run", len(files), c.cfg.Dir)
+		c.spooled = true
+		// Each upload is retried, a collector still down would hold the
+		// service back from listening, see spoolStartTimeout
+		go c.drainAtStart()
+	}
+	return nil
+}
+
+// spoolStartTimeout bounds the upload of the spool of a previous run, in
+// the background of the start. What is left is uploaded after the next
+// upload that succeeds.
+const spoolStartTimeout = 10 * time.Second
+
+func (c *spoolClient) drainAtStart() {
+	ctx, cancel := context.WithTimeout(context.Background(), spoolStartTimeout)
+	defer cancel()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.spooled {
+		c.drain(ctx)
+	}
+}
+
+func (c *spoolClient) Stop(ctx context.Context) error {
+	return c.next.Stop(ctx)
+}
+
+func (c *spoolClient) UploadTraces(ctx context.Context, spans []*tracepb.ResourceSpans) error {
+	err := c.next.UploadTraces(ctx, spans)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err == nil {
+		if c.spooled {
+			c.drain(ctx)
+		}
+		return nil
+	}
+	if serr := c.spool(spans); serr != nil {
+		return fmt.Errorf("%w, and failed to spool the batch: %v", err, serr)
+	}
+	if !c.spooled {
+		log.Printf("otlp endpoint unreachable, spooling the spans to %s until it recovers: %v", c.cfg.Dir, err)
+		c.spooled = true
+	}
+	return nil
+}
+
+// spool writes spans to a new file, deleting the oldest ones to make room.
+func (c *spoolClient) spool(spans []*tracepb.ResourceSpans) error {
+	data, err := MarshalOTLPJSON(&coltracepb.ExportTraceServiceRequest{ResourceSpans: spans})
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	if int64(len(data)) > c.cfg.maxSize() {
+		return fmt.Errorf("batch of %d bytes exceeds the spool size", len(data))
+	}
+	if c.size+int64(len(data)) > c.cfg.maxSize() {
+		files, err := c.files()
+		if err != nil {
+			return err
+		}
+		var dropped int
+		for _, f := range files {
+			if c.size+int64(len(data)) <= c.cfg.maxSize() {
+				break
+			}
+			if err := os.Remove(f.path); err != nil {
+				return err
+			}
+			c.size -= f.size
+			dropped++
+		}
+		log.Printf("spool %s is full, dropped its %d oldest batches", c.cfg.Dir, dropped)
+	}
+
+	c.seq++
+	name := filepath.Join(c.cfg.Dir, fmt.Sprintf("%020d-%06d%s", time.Now().UnixNano(), c.seq%1000000, spoolExt))
+	if err := os.WriteFile(name, data, 0o600); err != nil {
+		return err
+	}
+	c.size += int64(len(data))
+	return nil
+}
+
+// drain uploads the spooled batches, oldest first, until one fails.
+func (c *spoolClient) drain(ctx context.Context) {
+	files, err := c.files()
+	if err != nil {
+		log.Printf("failed to list spool %s: %v", c.cfg.Dir, err)
+		return
+	}
+	for _, f := range files {
+		data, err := os.ReadFile(f.path)
+		if err != nil {
+			log.Printf("failed to read spooled batch %s: %v", f.path, err)
+			return
+		}
+		req, err := UnmarshalOTLPJSON(data)
+		if err == nil {
+			if err = c.next.UploadTraces(ctx, req.ResourceSpans); err != nil {
+				// Still down, the next successful upload resumes the drain
+				return
+			}
+		} else {
+			log.Printf("dropping unreadable spooled batch %s: %v", f.path, err)
+		}
+		if err := os.Remove(f.path); err != nil {
+			log.Printf("failed to remove spooled batch %s: %v", f.path, err)
+			return
+		}
+		c.size -= f.size
+	}
+	log.Printf("otlp endpoint recovered, the spool %s is uploaded", c.cfg.Dir)
+	c.spooled = false
+}
+
+// spoolExt is the extension of the spool files.
+const spoolExt = ".jsonl"
+
+type spoolFile struct {
+	path string
+	size int64
+}
+
+// files lists the spooled batches, oldest first.
+func (c *spoolClient) files() ([]spoolFile, error) {
+	entries, err := os.ReadDir(c.cfg.Dir)
+	if err != nil {
+		return nil, err
+	}
+	var files []spoolFile
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), spoolExt) {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, spoolFile{path: filepath.Join(c.cfg.Dir, e.Name()), size: info.Size()})
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].path < files[j].path })
+	return files, nil
+}
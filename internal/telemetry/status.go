@@ -0,0 +1,53 @@
+package telemetry
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// SetStatus applies the span status policy of the services: a span is marked
+// as Error when err is set or the HTTP status code is a server error, and is
+// left Unset otherwise. Ok is never set, it is final and would hide an error
+// recorded later by an outer layer.
+func SetStatus(span trace.Span, statusCode int, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return
+	}
+	if statusCode == 0 {
+		return
+	}
+	span.SetStatus(HTTPSpanStatus(statusCode, trace.SpanKindServer))
+}
+
+// FinishSpan describes the response on the server span of a gin request,
+// http.response.status_code and http.response.body.size, sets its status
+// from the response and the last error attached with c.Error, then ends it.
+// TraceRequests calls it for every request.
+//
+// A panic going through is recorded on the span, described as a 500, and goes
+// on up to Recover.
+func FinishSpan(c *gin.Context, span trace.Span) {
+	if r := recover(); r != nil {
+		RecordPanic(span, r)
+		span.SetAttributes(semconv.HTTPResponseStatusCode(http.StatusInternalServerError))
+		span.End()
+		panic(r)
+	}
+	var err error
+	if last := c.Errors.Last(); last != nil {
+		err = last.Err
+	}
+	size := int64(c.Writer.Size())
+	if size < 0 {
+		size = 0
+	}
+	span.SetAttributes(ResponseAttributes(c.Writer.Status(), size)...)
+	SetStatus(span, c.Writer.Status(), err)
+	span.End()
+}
@@ -0,0 +1,128 @@
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestSetStatus(t *testing.T) {
+	tests := []struct {
+		name       string
+		statusCode int
+		err        error
+		want       codes.Code
+		wantDesc   string
+	}{
+		{"ok", http.StatusOK, nil, codes.Unset, ""},
+		{"no response", 0, nil, codes.Unset, ""},
+		{"error beats a success", http.StatusOK, errors.New("store down"), codes.Error, "store down"},
+		{"error beats a client error", http.StatusNotFound, errors.New("no such user"), codes.Error, "no such user"},
+		{"client error", http.StatusNotFound, nil, codes.Unset, ""},
+		{"server error", http.StatusServiceUnavailable, nil, codes.Error, ""},
+		{"invalid status code", 999, nil, codes.Error, "invalid HTTP status code 999"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := tracetest.NewSpanRecorder()
+			tracer := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test")
+			_, span := tracer.Start(context.Background(), "span", trace.WithSpanKind(trace.SpanKindServer))
+			SetStatus(span, tt.statusCode, tt.err)
+			span.End()
+
+			got := recorder.Ended()[0]
+			if got.Status().Code != tt.want || got.Status().Description != tt.wantDesc {
+				t.Errorf("status = %v %q, want %v %q", got.Status().Code, got.Status().Description, tt.want, tt.wantDesc)
+			}
+			if recorded := hasException(got); recorded != (tt.err != nil) {
+				t.Errorf("exception recorded = %v, want %v", recorded, tt.err != nil)
+			}
+		})
+	}
+}
+
+func TestFinishSpan(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		name       string
+		handler    gin.HandlerFunc
+		want       codes.Code
+		wantStatus int64
+		// exception is whether an exception event is recorded
+		exception bool
+	}{
+		{"ok", func(c *gin.Context) { c.Status(http.StatusOK) }, codes.Unset, http.StatusOK, false},
+		{"client error", func(c *gin.Context) { c.Status(http.StatusNotFound) }, codes.Unset, http.StatusNotFound, false},
+		{"server error", func(c *gin.Context) { c.Status(http.StatusBadGateway) }, codes.Error, http.StatusBadGateway, false},
+		{"error attached", func(c *gin.Context) {
+			c.Error(errors.New("invalid id"))
+			c.Status(http.StatusBadRequest)
+		}, codes.Error, http.StatusBadRequest, true},
+		{"panic", func(*gin.Context) { panic("boom") }, codes.Error, http.StatusInternalServerError, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := tracetest.NewSpanRecorder()
+			tracer := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test")
+			router := gin.New()
+			router.Use(func(c *gin.Context) {
+				defer func() {
+					if recover() != nil {
+						c.AbortWithStatus(http.StatusInternalServerError)
+					}
+				}()
+				c.Next()
+			})
+			router.Use(func(c *gin.Context) {
+				_, span := StartServerSpan(c, tracer)
+				defer FinishSpan(c, span)
+				c.Next()
+			})
+			router.GET("/", tt.handler)
+			router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+			spans := recorder.Ended()
+			if len(spans) != 1 {
+				t.Fatalf("%d spans ended, want 1", len(spans))
+			}
+			got := spans[0]
+			if got.Status().Code != tt.want {
+				t.Errorf("status = %v %q, want %v", got.Status().Code, got.Status().Description, tt.want)
+			}
+			if v, ok := attributeOf(got, semconv.HTTPResponseStatusCodeKey); !ok || v.AsInt64() != tt.wantStatus {
+				t.Errorf("http.response.status_code = %v, want %d", v.Emit(), tt.wantStatus)
+			}
+			if hasException(got) != tt.exception {
+				t.Errorf("exception recorded = %v, want %v", hasException(got), tt.exception)
+			}
+		})
+	}
+}
+
+func hasException(s sdktrace.ReadOnlySpan) bool {
+	for _, ev := range s.Events() {
+		if ev.Name == semconv.ExceptionEventName {
+			return true
+		}
+	}
+	return false
+}
+
+func attributeOf(s sdktrace.ReadOnlySpan, key attribute.Key) (attribute.Value, bool) {
+	for _, kv := range s.Attributes() {
+		if kv.Key == key {
+			return kv.Value, true
+		}
+	}
+	return attribute.Value{}, false
+}
@@ -0,0 +1,70 @@
+package telemetry
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// TLSConfig secures the connections to an https endpoint beyond the system
+// defaults, e.g. for a hardened collector: CertFile and KeyFile, PEM files
+// set together, authenticate the service with a client certificate (mutual
+// TLS), and CAFile, a PEM bundle, replaces the system roots the collector
+// certificate is verified with.
+type TLSConfig struct {
+	CertFile string
+	KeyFile  string
+	CAFile   string
+}
+
+// IsZero reports whether t keeps the system defaults.
+func (t TLSConfig) IsZero() bool { return t == TLSConfig{} }
+
+// Config loads the files of t into a TLS client configuration.
+func (t TLSConfig) Config() (*tls.Config, error) {
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+	if t.CertFile != "" || t.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("load client certificate: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	if t.CAFile != "" {
+		pem, err := os.ReadFile(t.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("read CA bundle: %w", err)
+		}
+		roots := x509.NewCertPool()
+		if !roots.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("CA bundle %s holds no PEM certificate", t.CAFile)
+		}
+		cfg.RootCAs = roots
+	}
+	return cfg, nil
+}
+
+// checkTLS reports TLS settings that cannot be used with the endpoint of e.
+func (e Exporter) checkTLS() error {
+	if e.TLS.IsZero() {
+		return nil
+	}
+	var errs []error
+	if (e.TLS.CertFile == "") != (e.TLS.KeyFile == "") {
+		errs = append(errs, errors.New("tls: cert file and key file must be set together"))
+	}
+	for _, endpoint := range append([]string{e.ExporterEndpoint()}, e.FailoverEndpoints...) {
+		if strings.HasPrefix(endpoint, "http://") {
+			errs = append(errs, fmt.Errorf("tls: endpoint %s must use https", endpoint))
+		}
+	}
+	if len(errs) == 0 {
+		if _, err := e.TLS.Config(); err != nil {
+			errs = append(errs, fmt.Errorf("tls: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
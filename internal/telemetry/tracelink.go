@@ -0,0 +1,97 @@
+package telemetry
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TraceURLHeader carries the deep link to the trace of a failed request, see
+// TraceLink.Middleware.
+const TraceURLHeader = "X-Trace-URL"
+
+// traceURLPlaceholders are replaced in the trace URL templates.
+var traceURLPlaceholders = []string{"{trace_id}", "{span_id}", "{service}", "{tenant_id}"}
+
+// TraceLink renders the deep link to a trace in the UI of the backend from a
+// URL template, e.g. "http://jaeger:16686/trace/{trace_id}", so engineers can
+// click from a failure straight to its trace. {trace_id} and {span_id} are
+// replaced by the IDs in hex, {service} by the service name and {tenant_id} by
+// the OpsRamp tenant, as found in the URL of a trace in each backend UI.
+// The zero TraceLink renders no link.
+type TraceLink struct {
+	template string
+}
+
+// NewTraceLink returns the link of cfg.TraceURL. Without one, Jaeger
+// exporters link to the Jaeger UI on the host of the collector, other backends
+// get no link.
+func NewTraceLink(cfg Config) TraceLink {
+	if resolved, err := cfg.Resolved(); err == nil {
+		cfg = resolved
+	}
+	tmpl := cfg.TraceURL
+	if tmpl == "" && cfg.Exporter.backend() == Jaeger {
+		if u, err := url.Parse(cfg.Exporter.ExporterEndpoint()); err == nil && u.Hostname() != "" {
+			tmpl = "http://" + net.JoinHostPort(u.Hostname(), "16686") + "/trace/{trace_id}"
+		}
+	}
+	r := strings.NewReplacer("{service}", url.PathEscape(cfg.ServiceName),
+		"{tenant_id}", url.PathEscape(cfg.Exporter.opsRamp().TenantID))
+	return TraceLink{template: r.Replace(tmpl)}
+}
+
+// ValidateTraceURL reports a template that cannot render a link to a trace.
+func ValidateTraceURL(tmpl string) error {
+	if !strings.Contains(tmpl, "{trace_id}") {
+		return fmt.Errorf("%q has no {trace_id}", tmpl)
+	}
+	bare := tmpl
+	for _, p := range traceURLPlaceholders {
+		bare = strings.ReplaceAll(bare, p, "x")
+	}
+	if u, err := url.Parse(bare); err != nil || u.Host == "" {
+		return fmt.Errorf("%q is not an absolute URL", tmpl)
+	}
+	return nil
+}
+
+// URL renders the link to the trace of sc, empty without a template or a
+// valid sc.
+func (l TraceLink) URL(sc trace.SpanContext) string {
+	if l.template == "" || !sc.IsValid() {
+		return ""
+	}
+	return strings.NewReplacer("{trace_id}", sc.TraceID().String(), "{span_id}", sc.SpanID().String()).Replace(l.template)
+}
+
+// Middleware adds the link to the trace of the request to the server error
+// responses in TraceURLHeader. Meant for gin's debug mode only: the link
+// tells the caller where the traces of the service are.
+func (l TraceLink) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Writer = &linkWriter{ResponseWriter: c.Writer, c: c, link: l}
+		c.Next()
+	}
+}
+
+// linkWriter adds the link when the status is set, the handlers having
+// replaced the request context with the one of their span by then.
+type linkWriter struct {
+	gin.ResponseWriter
+	c    *gin.Context
+	link TraceLink
+}
+
+func (w *linkWriter) WriteHeader(code int) {
+	if code >= 500 && !w.Written() {
+		if u := w.link.URL(trace.SpanContextFromContext(w.c.Request.Context())); u != "" {
+			w.Header().Set(TraceURLHeader, u)
+		}
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
@@ -0,0 +1,129 @@
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// idSegment matches the path segments taken as IDs: numbers, UUIDs and long
+// hexadecimal strings such as the IDs of the store.
+var idSegment = regexp.MustCompile(`^(\d+|[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}|[0-9a-fA-F]{16,})$`)
+
+// urlSanitizer strips the query strings and the IDs from the URLs of the
+// spans given to the exporting processors: http.url and url.full lose their
+// query, fragment and user info, http.target and url.path their query, IDs
+// in paths become "{id}", e.g. /users/123 → /users/{id}, and url.query is
+// dropped. The values of the URL attributes stay bounded, and secrets passed
+// in query strings never reach the backend. Like redactor, it hands a
+// sanitized view of the ended span to next.
+type urlSanitizer struct {
+	next []sdktrace.SpanProcessor
+}
+
+func newURLSanitizer(next ...sdktrace.SpanProcessor) *urlSanitizer {
+	return &urlSanitizer{next: next}
+}
+
+func (u *urlSanitizer) OnStart(parent context.Context, s sdktrace.ReadWriteSpan) {
+	for _, p := range u.next {
+		p.OnStart(parent, s)
+	}
+}
+
+func (u *urlSanitizer) OnEnd(s sdktrace.ReadOnlySpan) {
+	if attrs, changed := sanitizeURLAttributes(s.Attributes()); changed {
+		s = maskedSpan{ReadOnlySpan: s, attrs: attrs, events: s.Events()}
+	}
+	for _, p := range u.next {
+		p.OnEnd(s)
+	}
+}
+
+func (u *urlSanitizer) Shutdown(ctx context.Context) error {
+	var errs []error
+	for _, p := range u.next {
+		errs = append(errs, p.Shutdown(ctx))
+	}
+	return errors.Join(errs...)
+}
+
+func (u *urlSanitizer) ForceFlush(ctx context.Context) error {
+	var errs []error
+	for _, p := range u.next {
+		errs = append(errs, p.ForceFlush(ctx))
+	}
+	return errors.Join(errs...)
+}
+
+// sanitizeURLAttributes returns attrs with the URL attributes sanitized, and
+// whether any changed.
+func sanitizeURLAttributes(attrs []attribute.KeyValue) ([]attribute.KeyValue, bool) {
+	var sanitized []attribute.KeyValue
+	for i, kv := range attrs {
+		v, keep := kv.Value.AsString(), true
+		switch kv.Key {
+		case "http.url", "url.full":
+			v = SanitizeURL(v)
+		case "http.target", "url.path":
+			v = SanitizePath(v)
+		case "url.query":
+			keep = false
+		default:
+			if sanitized != nil {
+				sanitized = append(sanitized, kv)
+			}
+			continue
+		}
+		if keep && v == kv.Value.AsString() {
+			if sanitized != nil {
+				sanitized = append(sanitized, kv)
+			}
+			continue
+		}
+		if sanitized == nil {
+			sanitized = append(make([]attribute.KeyValue, 0, len(attrs)), attrs[:i]...)
+		}
+		if keep {
+			sanitized = append(sanitized, attribute.String(string(kv.Key), v))
+		}
+	}
+	if sanitized == nil {
+		return attrs, false
+	}
+	return sanitized, true
+}
+
+// SanitizeURL returns raw without its user info, query and fragment, and
+// with the IDs of its path replaced by "{id}". A value that does not parse is
+// cut at the first "?" or "#".
+func SanitizeURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		cut, _, _ := strings.Cut(raw, "?")
+		cut, _, _ = strings.Cut(cut, "#")
+		return cut
+	}
+	u.User, u.RawQuery, u.ForceQuery, u.Fragment, u.RawFragment = nil, "", false, "", ""
+	u.Path, u.RawPath = SanitizePath(u.Path), ""
+	// The placeholder is kept readable rather than escaped
+	return strings.ReplaceAll(u.String(), "%7Bid%7D", "{id}")
+}
+
+// SanitizePath returns the path of a request target without its query, and
+// with its IDs replaced by "{id}".
+func SanitizePath(target string) string {
+	path, _, _ := strings.Cut(target, "?")
+	segments := strings.Split(path, "/")
+	for i, s := range segments {
+		if idSegment.MatchString(s) {
+			segments[i] = "{id}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
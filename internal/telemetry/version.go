@@ -0,0 +1,21 @@
+package telemetry
+
+import (
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// VersionHeader carries the service.version of the service that answered a
+// request, so callers can tell blue/green or canary cohorts apart.
+const VersionHeader = "X-Service-Version"
+
+// PeerVersionKey records the VersionHeader of a response on the client span.
+const PeerVersionKey = attribute.Key("peer.service.version")
+
+// AdvertiseVersion is a Gin middleware setting VersionHeader on every response.
+func AdvertiseVersion(version string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header(VersionHeader, version)
+		c.Next()
+	}
+}
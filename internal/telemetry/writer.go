@@ -0,0 +1,61 @@
+package telemetry
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+// ResponseWriterWrapper records the status code and the number of body bytes
+// sent by the handlers that run after it is installed, so that a middleware
+// can describe the response once the handlers return:
+//
+//	w := telemetry.NewResponseWriterWrapper(c.Writer)
+//	c.Writer = w
+//	c.Next()
+//	span.SetAttributes(telemetry.ResponseAttributes(w.StatusCode(), w.BytesWritten())...)
+type ResponseWriterWrapper struct {
+	gin.ResponseWriter
+	statusCode int
+	written    int64
+}
+
+// NewResponseWriterWrapper wraps w. The status code defaults to 200 OK, as for
+// a handler that writes a body without calling WriteHeader.
+func NewResponseWriterWrapper(w gin.ResponseWriter) *ResponseWriterWrapper {
+	return &ResponseWriterWrapper{ResponseWriter: w, statusCode: http.StatusOK}
+}
+
+func (w *ResponseWriterWrapper) WriteHeader(code int) {
+	w.statusCode = code
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *ResponseWriterWrapper) Write(b []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(b)
+	w.written += int64(n)
+	return n, err
+}
+
+func (w *ResponseWriterWrapper) WriteString(s string) (int, error) {
+	n, err := w.ResponseWriter.WriteString(s)
+	w.written += int64(n)
+	return n, err
+}
+
+// StatusCode is the status code of the response.
+func (w *ResponseWriterWrapper) StatusCode() int { return w.statusCode }
+
+// BytesWritten is the size of the response body sent so far.
+func (w *ResponseWriterWrapper) BytesWritten() int64 { return w.written }
+
+// ResponseAttributes describes a response as http.response.status_code and
+// http.response.body.size.
+func ResponseAttributes(statusCode int, size int64) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		semconv.HTTPResponseStatusCodeKey.Int(statusCode),
+		semconv.HTTPResponseBodySizeKey.Int64(size),
+	}
+}
@@ -0,0 +1,27 @@
+package models
+
+import "go.opentelemetry.io/otel/attribute"
+
+// UsersAttributes describes a list of users on a span as user.count.
+func UsersAttributes(users []User) []attribute.KeyValue {
+	return []attribute.KeyValue{attribute.Int("user.count", len(users))}
+}
+
+// Attributes describes the order on a span without its items.
+func (o Order) Attributes() []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.String("order.id", o.ID),
+		attribute.String("user.id", o.UserID),
+		attribute.Int("order.item_count", len(o.Items)),
+		attribute.Float64("order.total", o.Total()),
+	}
+}
+
+// Attributes describes the authentication result on a span.
+func (a AuthResult) Attributes() []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.String("user.id", a.UserID),
+		attribute.Bool("auth.authenticated", a.Authenticated),
+		attribute.StringSlice("auth.roles", a.Roles),
+	}
+}
@@ -0,0 +1,44 @@
+// Package models holds the domain types exchanged by the demo services and
+// their clients. The binding tags are checked by gin when a request body is
+// bound, e.g. with c.ShouldBindJSON.
+package models
+
+import "time"
+
+// User is a user of the demo services.
+type User struct {
+	ID    string `json:"id" bson:"_id"`
+	Name  string `json:"name" bson:"name" binding:"required"`
+	Email string `json:"email" bson:"email" binding:"required,email"`
+}
+
+// Order is a purchase made by a user.
+type Order struct {
+	ID     string      `json:"id" bson:"_id"`
+	UserID string      `json:"user_id" bson:"user_id" binding:"required"`
+	Items  []OrderItem `json:"items" bson:"items" binding:"required,min=1,dive"`
+}
+
+// OrderItem is a line of an order.
+type OrderItem struct {
+	SKU       string  `json:"sku" bson:"sku" binding:"required"`
+	Quantity  int     `json:"quantity" bson:"quantity" binding:"required,min=1"`
+	UnitPrice float64 `json:"unit_price" bson:"unit_price" binding:"min=0"`
+}
+
+// Total is the sum of the item prices.
+func (o Order) Total() float64 {
+	var total float64
+	for _, item := range o.Items {
+		total += float64(item.Quantity) * item.UnitPrice
+	}
+	return total
+}
+
+// AuthResult is the answer of the authentication service for a user.
+type AuthResult struct {
+	UserID        string    `json:"user_id"`
+	Authenticated bool      `json:"authenticated"`
+	Roles         []string  `json:"roles,omitempty"`
+	ExpiresAt     time.Time `json:"expires_at,omitempty"`
+}
@@ -0,0 +1,143 @@
+//go:build !notelemetry && !wasm
+
+package main
+
+import (
+	"sync"
+	"time"
+
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+)
+
+// policy decides which complete traces are forwarded.
+type policy struct {
+	// latency forwards the traces lasting longer, from the start of their
+	// first span to the end of their last one.
+	latency time.Duration
+}
+
+// reason tells why a trace is forwarded, empty when it is dropped.
+func (p policy) reason(t *bufferedTrace) string {
+	switch {
+	case t.hasError:
+		return "error"
+	case p.latency > 0 && time.Duration(t.end-t.start) > p.latency:
+		return "latency"
+	}
+	return ""
+}
+
+// bufferedTrace holds the spans received for a trace so far, grouped as they
+// arrived under their resource and scope.
+type bufferedTrace struct {
+	fragments  []*tracepb.ResourceSpans
+	spans      int
+	start, end uint64 // unix nano
+	hasError   bool
+	lastSeen   time.Time
+}
+
+func (t *bufferedTrace) add(rs *tracepb.ResourceSpans, now time.Time) {
+	t.fragments = append(t.fragments, rs)
+	for _, ss := range rs.ScopeSpans {
+		for _, s := range ss.Spans {
+			t.spans++
+			if t.start == 0 || s.StartTimeUnixNano < t.start {
+				t.start = s.StartTimeUnixNano
+			}
+			t.end = max(t.end, s.EndTimeUnixNano)
+			if s.GetStatus().GetCode() == tracepb.Status_STATUS_CODE_ERROR {
+				t.hasError = true
+			}
+		}
+	}
+	t.lastSeen = now
+}
+
+// buffer collects the spans by trace until no span of a trace has arrived
+// for wait, when the trace is taken as complete and decided on. Past
+// maxTraces, the trace idle the longest is decided on early.
+type buffer struct {
+	wait      time.Duration
+	maxTraces int
+
+	mu     sync.Mutex
+	traces map[[16]byte]*bufferedTrace
+}
+
+func newBuffer(wait time.Duration, maxTraces int) *buffer {
+	return &buffer{wait: wait, maxTraces: maxTraces, traces: make(map[[16]byte]*bufferedTrace)}
+}
+
+// add splits the resource spans of a request by trace and buffers them. It
+// returns the traces evicted to stay within maxTraces.
+func (b *buffer) add(resourceSpans []*tracepb.ResourceSpans) []*bufferedTrace {
+	now := time.Now()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, rs := range resourceSpans {
+		for id, fragment := range splitByTrace(rs) {
+			t := b.traces[id]
+			if t == nil {
+				t = &bufferedTrace{}
+				b.traces[id] = t
+			}
+			t.add(fragment, now)
+		}
+	}
+
+	var evicted []*bufferedTrace
+	for len(b.traces) > b.maxTraces {
+		var oldest [16]byte
+		var oldestSeen time.Time
+		for id, t := range b.traces {
+			if oldestSeen.IsZero() || t.lastSeen.Before(oldestSeen) {
+				oldest, oldestSeen = id, t.lastSeen
+			}
+		}
+		evicted = append(evicted, b.traces[oldest])
+		delete(b.traces, oldest)
+	}
+	return evicted
+}
+
+// complete removes and returns the traces idle for wait at now, or every
+// trace when all is set.
+func (b *buffer) complete(now time.Time, all bool) []*bufferedTrace {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var done []*bufferedTrace
+	for id, t := range b.traces {
+		if all || now.Sub(t.lastSeen) >= b.wait {
+			done = append(done, t)
+			delete(b.traces, id)
+		}
+	}
+	return done
+}
+
+// splitByTrace returns the spans of rs by trace, under the same resource
+// and scopes.
+func splitByTrace(rs *tracepb.ResourceSpans) map[[16]byte]*tracepb.ResourceSpans {
+	byTrace := make(map[[16]byte]*tracepb.ResourceSpans)
+	for _, ss := range rs.ScopeSpans {
+		scopes := make(map[[16]byte]*tracepb.ScopeSpans)
+		for _, s := range ss.Spans {
+			var id [16]byte
+			copy(id[:], s.TraceId)
+			scope := scopes[id]
+			if scope == nil {
+				scope = &tracepb.ScopeSpans{Scope: ss.Scope, SchemaUrl: ss.SchemaUrl}
+				scopes[id] = scope
+				fragment := byTrace[id]
+				if fragment == nil {
+					fragment = &tracepb.ResourceSpans{Resource: rs.Resource, SchemaUrl: rs.SchemaUrl}
+					byTrace[id] = fragment
+				}
+				fragment.ScopeSpans = append(fragment.ScopeSpans, scope)
+			}
+			scope.Spans = append(scope.Spans, s)
+		}
+	}
+	return byTrace
+}
@@ -0,0 +1,153 @@
+//go:build !notelemetry && !wasm
+
+// Command trace-gateway is a tail sampling demo without a collector: the
+// services export their spans to it over OTLP gRPC, it holds each trace until
+// no span of it has arrived for -wait, then forwards to the backend only the
+// traces with an error span or lasting longer than -latency. Every other
+// trace is dropped, whatever the head sampling decided.
+//
+//	go run ./trace-gateway -listen :4327 -backend http://localhost:4317 -latency 500ms
+//	go run ./golang2 -otlp-endpoint http://localhost:4327
+//
+// Metrics and logs are not received, point their exporters at the backend
+// directly. Spans a trace receives after its decision start a new one.
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/grpc"
+
+	"test-jaeger/internal/telemetry"
+)
+
+// reportInterval is how often the decisions are summed up in the log.
+const reportInterval = time.Minute
+
+func main() {
+	listen := flag.String("listen", ":4327", "address of the OTLP gRPC receiver")
+	backend := flag.String("backend", telemetry.DefaultEndpoint, "OTLP gRPC endpoint URL the kept traces are forwarded to")
+	wait := flag.Duration("wait", 10*time.Second, "how long a trace waits for more spans before it is decided on")
+	latency := flag.Duration("latency", 500*time.Millisecond, "forward the traces lasting longer, 0 keeps only the traces with errors")
+	maxTraces := flag.Int("max-traces", 10000, "traces buffered at most, the idlest are decided on early past it")
+	flag.Parse()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	client := otlptracegrpc.NewClient(otlptracegrpc.WithEndpointURL(*backend))
+	if err := client.Start(ctx); err != nil {
+		log.Fatalf("failed to connect to %s: %v", *backend, err)
+	}
+	g := &gateway{
+		buffer: newBuffer(*wait, *maxTraces),
+		policy: policy{latency: *latency},
+		client: client,
+		counts: make(map[string]int),
+	}
+
+	l, err := net.Listen("tcp", *listen)
+	if err != nil {
+		log.Fatal(err)
+	}
+	srv := grpc.NewServer()
+	coltracepb.RegisterTraceServiceServer(srv, g)
+	go func() {
+		<-ctx.Done()
+		srv.GracefulStop()
+	}()
+	go g.run(ctx)
+	log.Printf("receiving OTLP on %s, forwarding traces with errors or longer than %s to %s", *listen, *latency, *backend)
+	if err := srv.Serve(l); err != nil {
+		log.Printf("receiver stopped: %v", err)
+	}
+
+	// Decide on what is left, the services flushed their spans on shutdown
+	g.decide(context.Background(), g.buffer.complete(time.Now(), true))
+	g.report()
+	if err := client.Stop(context.Background()); err != nil {
+		log.Printf("failed to stop the backend client: %v", err)
+	}
+}
+
+type gateway struct {
+	coltracepb.UnimplementedTraceServiceServer
+
+	buffer *buffer
+	policy policy
+	client otlptrace.Client
+
+	mu     sync.Mutex
+	counts map[string]int // traces by reason, "" for the dropped ones
+}
+
+func (g *gateway) Export(ctx context.Context, req *coltracepb.ExportTraceServiceRequest) (*coltracepb.ExportTraceServiceResponse, error) {
+	if evicted := g.buffer.add(req.ResourceSpans); len(evicted) > 0 {
+		g.decide(ctx, evicted)
+	}
+	return &coltracepb.ExportTraceServiceResponse{}, nil
+}
+
+// run decides on the complete traces every second until ctx is done.
+func (g *gateway) run(ctx context.Context) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	reported := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			g.decide(ctx, g.buffer.complete(now, false))
+			if now.Sub(reported) >= reportInterval {
+				g.report()
+				reported = now
+			}
+		}
+	}
+}
+
+// decide forwards the traces the policy keeps and drops the others.
+func (g *gateway) decide(ctx context.Context, traces []*bufferedTrace) {
+	var kept []*tracepb.ResourceSpans
+	g.mu.Lock()
+	for _, t := range traces {
+		reason := g.policy.reason(t)
+		g.counts[reason]++
+		if reason != "" {
+			kept = append(kept, t.fragments...)
+		}
+	}
+	g.mu.Unlock()
+	if len(kept) == 0 {
+		return
+	}
+	if err := g.client.UploadTraces(ctx, kept); err != nil {
+		log.Printf("failed to forward %d resource spans: %v", len(kept), err)
+	}
+}
+
+// report logs the decisions since the last report.
+func (g *gateway) report() {
+	g.mu.Lock()
+	counts := g.counts
+	g.counts = make(map[string]int)
+	g.mu.Unlock()
+	if len(counts) == 0 {
+		return
+	}
+	log.Printf("forwarded %d traces with errors and %d slow ones, dropped %d",
+		counts["error"], counts["latency"], counts[""])
+}